@@ -0,0 +1,182 @@
+package portabletext
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// vagueLinkTexts are link texts that say nothing about the destination
+// (WCAG 2.4.4 Link Purpose). Compared case-insensitively after trimming
+// whitespace and trailing punctuation.
+var vagueLinkTexts = map[string]bool{
+	"click here": true, "click": true, "here": true, "read more": true,
+	"more": true, "learn more": true, "link": true, "this link": true,
+	"continue reading": true, "details": true,
+}
+
+// manualBullets are characters commonly typed to fake a list item.
+const manualBullets = "•◦▪▫‣⁃·"
+
+// CheckAccessibility runs AccessibilityRules over doc and returns one
+// *ValidationError per problem, in document order.
+func CheckAccessibility(doc Document) []error {
+	l := NewEmptyLinter()
+	for _, r := range AccessibilityRules() {
+		if err := l.Register(r); err != nil {
+			panic(err)
+		}
+	}
+	var errs []error
+	for _, f := range l.Lint(doc) {
+		ve := f.ValidationError
+		errs = append(errs, &ve)
+	}
+	return errs
+}
+
+// AccessibilityRules returns lint rules for common WCAG problems. They can
+// be registered into a Linter alongside BuiltinRules:
+//
+//   - a11y-heading-skip: a heading more than one level below the previous heading
+//   - a11y-multiple-h1: more than one h1 in the document
+//   - a11y-image-alt: an image node or inline image without alt text in Raw["alt"]
+//   - a11y-link-text: link text such as "click here", "read more" or a bare URL
+//   - a11y-manual-list: a non-list block starting with a typed bullet such as "•"
+func AccessibilityRules() []Rule {
+	return []Rule{
+		{
+			Name:        "a11y-heading-skip",
+			Description: "heading levels should only increase by one",
+			Severity:    SeverityError,
+			Check: func(t *LintTarget) []string {
+				if !t.IsNode() {
+					return nil
+				}
+				lvl := headingLevel(t.Node)
+				if lvl == 0 {
+					return nil
+				}
+				if prev := t.run.previousHeadingLevel(t.Doc, t.Index); prev > 0 && lvl > prev+1 {
+					return []string{fmt.Sprintf("heading level skipped: h%d follows h%d", lvl, prev)}
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "a11y-multiple-h1",
+			Description: "a document should have a single h1",
+			Severity:    SeverityWarning,
+			Check: func(t *LintTarget) []string {
+				if !t.IsNode() || headingLevel(t.Node) != 1 {
+					return nil
+				}
+				if first := t.run.firstH1(t.Doc); first >= 0 && first < t.Index {
+					return []string{fmt.Sprintf("multiple h1 headings (first at %s)", nodePath(first))}
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "a11y-image-alt",
+			Description: "images must have alternative text",
+			Severity:    SeverityError,
+			Check: func(t *LintTarget) []string {
+				var raw map[string]any
+				switch {
+				case t.IsNode() && t.Node.Type == "image":
+					raw = t.Node.Raw
+				case t.IsSpan() && t.Span.Type == "image":
+					raw = t.Span.Raw
+				default:
+					return nil
+				}
+				if alt, _ := raw["alt"].(string); strings.TrimSpace(alt) == "" {
+					return []string{"image missing alt text"}
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "a11y-link-text",
+			Description: "link text should describe the destination",
+			Severity:    SeverityWarning,
+			Check: func(t *LintTarget) []string {
+				if !t.IsMarkDef() || t.MarkDef.Type != "link" || t.MarkDef.Key == "" {
+					return nil
+				}
+				text := strings.TrimSpace(markedText(t.Node, t.MarkDef.Key))
+				norm := strings.ToLower(strings.TrimRight(text, ".…!:>» "))
+				switch {
+				case text == "":
+					return nil
+				case vagueLinkTexts[norm]:
+					return []string{fmt.Sprintf("link text %q does not describe its destination", text)}
+				case looksLikeURL(text):
+					return []string{fmt.Sprintf("link text is a bare URL: %q", text)}
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "a11y-manual-list",
+			Description: "lists should use listItem rather than typed bullets",
+			Severity:    SeverityWarning,
+			Check: func(t *LintTarget) []string {
+				if !t.IsNode() || !t.Node.IsBlock() || t.Node.ListItem != nil {
+					return nil
+				}
+				text := strings.TrimLeft(t.Node.GetText(), " \t")
+				if r, _ := utf8.DecodeRuneInString(text); r != utf8.RuneError && strings.ContainsRune(manualBullets, r) {
+					return []string{fmt.Sprintf("block starts with a typed bullet %q; use listItem", r)}
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// markedText concatenates the text of n's spans that carry mark.
+func markedText(n *Node, mark string) string {
+	var b strings.Builder
+	for _, c := range n.Children {
+		if c.Text != nil && c.HasMark(mark) {
+			b.WriteString(*c.Text)
+		}
+	}
+	return b.String()
+}
+
+func looksLikeURL(s string) bool {
+	l := strings.ToLower(s)
+	return !strings.ContainsAny(s, " \t\n") &&
+		(strings.HasPrefix(l, "http://") || strings.HasPrefix(l, "https://") || strings.HasPrefix(l, "www."))
+}
+
+func (r *lintRun) computeHeadings(doc Document) {
+	if r.prevHeading != nil {
+		return
+	}
+	r.prevHeading = make([]int, len(doc))
+	r.h1 = -1
+	prev := 0
+	for i := range doc {
+		r.prevHeading[i] = prev
+		if lvl := headingLevel(&doc[i]); lvl > 0 {
+			prev = lvl
+			if lvl == 1 && r.h1 < 0 {
+				r.h1 = i
+			}
+		}
+	}
+}
+
+func (r *lintRun) previousHeadingLevel(doc Document, i int) int {
+	r.computeHeadings(doc)
+	return r.prevHeading[i]
+}
+
+func (r *lintRun) firstH1(doc Document) int {
+	r.computeHeadings(doc)
+	return r.h1
+}
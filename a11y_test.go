@@ -0,0 +1,68 @@
+package portabletext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckAccessibility(t *testing.T) {
+	input := `[
+		{"_type":"block","style":"h1","children":[{"_type":"span","text":"Title"}]},
+		{"_type":"block","style":"h3","children":[{"_type":"span","text":"Skipped"}]},
+		{"_type":"block","style":"h1","children":[{"_type":"span","text":"Second title"}]},
+		{"_type":"image","asset":{"_ref":"image-a"}},
+		{"_type":"image","alt":"Team photo"},
+		{"_type":"block","children":[
+			{"_type":"span","text":"Click here","marks":["l1"]},
+			{"_type":"span","text":" or "},
+			{"_type":"span","text":"https://example.com","marks":["l2"]},
+			{"_type":"span","text":" or "},
+			{"_type":"span","text":"the pricing page","marks":["l3"]},
+			{"_type":"image"}
+		],"markDefs":[
+			{"_type":"link","_key":"l1","href":"/a"},
+			{"_type":"link","_key":"l2","href":"https://example.com"},
+			{"_type":"link","_key":"l3","href":"/pricing"}
+		]},
+		{"_type":"block","children":[{"_type":"span","text":"• fake item"}]},
+		{"_type":"block","listItem":"bullet","children":[{"_type":"span","text":"• tolerated in real lists"}]}
+	]`
+	doc, err := DecodeString(input)
+	if err != nil {
+		t.Fatalf("DecodeString() error = %v", err)
+	}
+
+	errs := CheckAccessibility(doc)
+
+	var got []string
+	for _, err := range errs {
+		ve, ok := err.(*ValidationError)
+		if !ok {
+			t.Fatalf("CheckAccessibility() returned %T, want *ValidationError", err)
+		}
+		if ve.Node == nil {
+			t.Errorf("%s: missing node reference", ve.Path)
+		}
+		got = append(got, ve.Path)
+	}
+	want := []string{"[1]", "[2]", "[3]", "[5].children[5]", "[5].markDefs[0]", "[5].markDefs[1]", "[6]"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("CheckAccessibility() paths = %v\nwant %v", got, want)
+		for _, err := range errs {
+			t.Log(err)
+		}
+	}
+}
+
+func TestAccessibilityRulesInLinter(t *testing.T) {
+	l := NewLinter()
+	for _, r := range AccessibilityRules() {
+		if err := l.Register(r); err != nil {
+			t.Fatalf("Register(%s) error = %v", r.Name, err)
+		}
+	}
+	findings := l.Lint(Document{*NewBlock("h2").AddSpan("A"), *NewBlock("h4").AddSpan("B")})
+	if len(findings) != 1 || findings[0].Rule != "a11y-heading-skip" || findings[0].Message != "heading level skipped: h4 follows h2" {
+		t.Errorf("Lint() = %v", findings)
+	}
+}
@@ -0,0 +1,247 @@
+package portabletext
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	autoURLPattern   = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+`)
+	autoEmailPattern = regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b`)
+)
+
+// AutoLinkPattern turns matches of Pattern into annotations. Handler
+// receives the submatches (index 0 is the whole match) and returns the
+// markDef to apply, without a _key, or nil to leave the match alone. It
+// is not called for matches that lose to an overlapping one.
+type AutoLinkPattern struct {
+	Pattern *regexp.Regexp
+	Handler func(submatches []string) *MarkDef
+
+	// Group selects the submatch whose text is annotated; 0 (the default)
+	// annotates the whole match. Use it when the pattern must consume
+	// surrounding context, such as the character before an @mention.
+	Group int
+}
+
+// AutoLinkOptions controls AutoLink.
+type AutoLinkOptions struct {
+	URLs   bool // Link http(s):// and www. URLs
+	Emails bool // Link email addresses with mailto: hrefs
+
+	// Patterns are custom matchers, e.g. @mentions or issue keys. When
+	// matches overlap, the leftmost wins, then the longest, then the
+	// earliest in the order URLs, Emails, Patterns.
+	Patterns []AutoLinkPattern
+
+	// KeyFunc generates _key values for new markDefs and split spans.
+	// Defaults to NewKey.
+	KeyFunc func() string
+}
+
+// MentionPattern returns an AutoLinkPattern that annotates "@name" with a
+// markDef of the given type whose "reference" field is a Sanity reference
+// to resolve(name). Names that resolve to "" are left alone.
+func MentionPattern(markType string, resolve func(name string) string) AutoLinkPattern {
+	return AutoLinkPattern{
+		Pattern: regexp.MustCompile(`(?:^|[^\w@])(@(\w[\w.-]*\w|\w))`),
+		Group:   1,
+		Handler: func(m []string) *MarkDef {
+			id := resolve(m[2])
+			if id == "" {
+				return nil
+			}
+			return &MarkDef{Type: markType, Raw: map[string]any{
+				"reference": map[string]any{"_type": "reference", "_ref": id},
+			}}
+		},
+	}
+}
+
+// AutoLink returns a copy of doc in which bare URLs, email addresses and
+// custom patterns in span text are wrapped in annotations. Matching spans
+// are split; the pieces keep the original marks and the match gains the
+// new markDef's key. Spans that already carry an annotation (a mark with a
+// markDef in the same block) are left untouched so links are never nested.
+func AutoLink(doc Document, opts AutoLinkOptions) Document {
+	if opts.KeyFunc == nil {
+		opts.KeyFunc = NewKey
+	}
+
+	var patterns []AutoLinkPattern
+	if opts.URLs {
+		patterns = append(patterns, AutoLinkPattern{Pattern: autoURLPattern, Handler: urlHandler})
+	}
+	if opts.Emails {
+		patterns = append(patterns, AutoLinkPattern{Pattern: autoEmailPattern, Handler: emailHandler})
+	}
+	patterns = append(patterns, opts.Patterns...)
+
+	out := make(Document, len(doc))
+	for i := range doc {
+		n := doc[i].Clone()
+		if n.IsBlock() && len(patterns) > 0 {
+			autoLinkNode(n, patterns, opts.KeyFunc)
+		}
+		out[i] = *n
+	}
+	return out
+}
+
+type autoMatch struct {
+	start, end int
+	md         *MarkDef
+	priority   int
+	subs       []string
+}
+
+func autoLinkNode(n *Node, patterns []AutoLinkPattern, newKey func() string) {
+	annotations := map[string]bool{}
+	for _, md := range n.MarkDefs {
+		annotations[md.Key] = true
+	}
+
+	children := make([]Span, 0, len(n.Children))
+	for _, s := range n.Children {
+		if s.Type != "span" || s.Text == nil || hasAnnotation(&s, annotations) {
+			children = append(children, s)
+			continue
+		}
+
+		matches := findAutoMatches(*s.Text, patterns)
+		if len(matches) == 0 {
+			children = append(children, s)
+			continue
+		}
+
+		text := *s.Text
+		pos := 0
+		first := true
+		piece := func(t string, extra string) {
+			p := s
+			p.Text = &t
+			p.Raw = deepCopyMap(s.Raw)
+			p.Marks = append([]string(nil), s.Marks...)
+			if extra != "" {
+				p.Marks = append(p.Marks, extra)
+			} else if p.Marks == nil && s.Marks != nil {
+				p.Marks = []string{}
+			}
+			if !first {
+				if _, ok := p.Raw["_key"]; ok {
+					p.Raw["_key"] = newKey()
+				}
+			}
+			first = false
+			children = append(children, p)
+		}
+
+		for _, m := range matches {
+			if m.start > pos {
+				piece(text[pos:m.start], "")
+			}
+			m.md.Key = newKey()
+			if m.md.Raw == nil {
+				m.md.Raw = map[string]any{}
+			}
+			n.MarkDefs = append(n.MarkDefs, *m.md)
+			piece(text[m.start:m.end], m.md.Key)
+			pos = m.end
+		}
+		if pos < len(text) {
+			piece(text[pos:], "")
+		}
+	}
+	n.Children = children
+}
+
+func hasAnnotation(s *Span, annotations map[string]bool) bool {
+	for _, m := range s.Marks {
+		if annotations[m] {
+			return true
+		}
+	}
+	return false
+}
+
+// findAutoMatches returns non-overlapping matches in text order.
+func findAutoMatches(text string, patterns []AutoLinkPattern) []autoMatch {
+	var all []autoMatch
+	for pi, p := range patterns {
+		for _, loc := range p.Pattern.FindAllStringSubmatchIndex(text, -1) {
+			subs := make([]string, len(loc)/2)
+			for k := range subs {
+				if loc[2*k] >= 0 {
+					subs[k] = text[loc[2*k]:loc[2*k+1]]
+				}
+			}
+			start, end := loc[0], loc[1]
+			if g := p.Group; g > 0 && 2*g+1 < len(loc) && loc[2*g] >= 0 {
+				start, end = loc[2*g], loc[2*g+1]
+			}
+			if p.Pattern == autoURLPattern {
+				end = start + len(trimURL(text[start:end]))
+			}
+			all = append(all, autoMatch{start: start, end: end, priority: pi, subs: subs})
+		}
+	}
+
+	sort.SliceStable(all, func(a, b int) bool {
+		x, y := all[a], all[b]
+		if x.start != y.start {
+			return x.start < y.start
+		}
+		if x.end-x.start != y.end-y.start {
+			return x.end-x.start > y.end-y.start
+		}
+		return x.priority < y.priority
+	})
+
+	// Handlers run only for matches that survive the overlap check, so a
+	// match shadowed by a better one never reaches its handler. A match
+	// whose handler returns nil leaves room for the next candidate.
+	var out []autoMatch
+	pos := 0
+	for _, m := range all {
+		if m.start < pos || m.end <= m.start {
+			continue
+		}
+		if m.md = patterns[m.priority].Handler(m.subs); m.md != nil {
+			out = append(out, m)
+			pos = m.end
+		}
+	}
+	return out
+}
+
+func urlHandler(m []string) *MarkDef {
+	href := trimURL(m[0])
+	if strings.HasPrefix(strings.ToLower(href), "www.") {
+		href = "https://" + href
+	}
+	return &MarkDef{Type: "link", Raw: map[string]any{"href": href}}
+}
+
+func emailHandler(m []string) *MarkDef {
+	return &MarkDef{Type: "link", Raw: map[string]any{"href": "mailto:" + m[0]}}
+}
+
+// trimURL drops trailing punctuation that is almost never part of a URL in
+// prose, keeping a closing parenthesis when it balances an opening one.
+func trimURL(u string) string {
+	for len(u) > 0 {
+		last := u[len(u)-1]
+		switch {
+		case strings.IndexByte(".,;:!?'\"", last) >= 0:
+			u = u[:len(u)-1]
+		case last == ')' && strings.Count(u, "(") < strings.Count(u, ")"):
+			u = u[:len(u)-1]
+		case last == ']' && strings.Count(u, "[") < strings.Count(u, "]"):
+			u = u[:len(u)-1]
+		default:
+			return u
+		}
+	}
+	return u
+}
@@ -0,0 +1,131 @@
+package portabletext
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func sequentialKeys() func() string {
+	n := 0
+	return func() string {
+		n++
+		return fmt.Sprintf("k%d", n)
+	}
+}
+
+func describeSpans(n Node) string {
+	var parts []string
+	for _, s := range n.Children {
+		parts = append(parts, fmt.Sprintf("%q%v", *s.Text, s.Marks))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestAutoLinkURLsAndEmails(t *testing.T) {
+	doc := Document{*NewBlock("normal").AddSpan("See https://example.com/a_(b). or mail ops@example.com, or www.go.dev!", "em")}
+
+	out := AutoLink(doc, AutoLinkOptions{URLs: true, Emails: true, KeyFunc: sequentialKeys()})
+
+	want := `"See "[em] "https://example.com/a_(b)"[em k1] ". or mail "[em] "ops@example.com"[em k2] ", or "[em] "www.go.dev"[em k3] "!"[em]`
+	if got := describeSpans(out[0]); got != want {
+		t.Errorf("spans = %s\nwant %s", got, want)
+	}
+
+	hrefs := []string{"https://example.com/a_(b)", "mailto:ops@example.com", "https://www.go.dev"}
+	if len(out[0].MarkDefs) != len(hrefs) {
+		t.Fatalf("got %d markDefs, want %d", len(out[0].MarkDefs), len(hrefs))
+	}
+	for i, md := range out[0].MarkDefs {
+		if md.Type != "link" || md.Raw["href"] != hrefs[i] {
+			t.Errorf("markDef[%d] = %s %v, want link %s", i, md.Type, md.Raw["href"], hrefs[i])
+		}
+	}
+	if out[0].GetText() != doc[0].GetText() {
+		t.Error("AutoLink() changed the text")
+	}
+	if len(doc[0].Children) != 1 {
+		t.Error("AutoLink() mutated the input document")
+	}
+}
+
+func TestAutoLinkSkipsExistingLinks(t *testing.T) {
+	n := NewBlock("normal").
+		AddSpan("https://linked.example", "l1").
+		AddSpan(" https://bare.example", "strong").
+		AddMarkDef("l1", "link", map[string]any{"href": "https://linked.example"})
+
+	out := AutoLink(Document{*n}, AutoLinkOptions{URLs: true, KeyFunc: sequentialKeys()})
+
+	want := `"https://linked.example"[l1] " "[strong] "https://bare.example"[strong k1]`
+	if got := describeSpans(out[0]); got != want {
+		t.Errorf("spans = %s\nwant %s", got, want)
+	}
+}
+
+func TestAutoLinkCustomPatterns(t *testing.T) {
+	users := map[string]string{"ada": "user-ada"}
+	jira := AutoLinkPattern{
+		Pattern: regexp.MustCompile(`\b([A-Z][A-Z0-9]+-\d+)\b`),
+		Handler: func(m []string) *MarkDef {
+			return &MarkDef{Type: "link", Raw: map[string]any{"href": "https://jira.example.com/browse/" + m[1]}}
+		},
+	}
+
+	span := NewBlock("normal").AddSpan("Ping @ada and @bob about PROJ-123 (mail ada@example.com)")
+	span.Children[0].Raw["_key"] = "s0"
+
+	out := AutoLink(Document{*span}, AutoLinkOptions{
+		Emails:   true,
+		Patterns: []AutoLinkPattern{MentionPattern("mention", func(name string) string { return users[name] }), jira},
+		KeyFunc:  sequentialKeys(),
+	})
+
+	want := `"Ping "[] "@ada"[k1] " and @bob about "[] "PROJ-123"[k4] " (mail "[] "ada@example.com"[k7] ")"[]`
+	if got := describeSpans(out[0]); got != want {
+		t.Errorf("spans = %s\nwant %s", got, want)
+	}
+
+	md := out[0].MarkDefs[0]
+	if md.Type != "mention" || md.Raw["reference"].(map[string]any)["_ref"] != "user-ada" {
+		t.Errorf("mention markDef = %+v", md)
+	}
+
+	keys := map[any]bool{}
+	for _, c := range out[0].Children {
+		if keys[c.Raw["_key"]] {
+			t.Errorf("duplicate span key %v", c.Raw["_key"])
+		}
+		keys[c.Raw["_key"]] = true
+	}
+	if out[0].Children[0].Raw["_key"] != "s0" {
+		t.Error("first piece should keep the original span key")
+	}
+}
+
+func TestAutoLinkHandlerOnlyForKeptMatches(t *testing.T) {
+	var seen []string
+	issue := AutoLinkPattern{
+		Pattern: regexp.MustCompile(`\b[A-Z]+-\d+\b`),
+		Handler: func(m []string) *MarkDef {
+			seen = append(seen, m[0])
+			return &MarkDef{Type: "issue"}
+		},
+	}
+	skip := AutoLinkPattern{
+		Pattern: regexp.MustCompile(`see [A-Z]+`),
+		Handler: func(m []string) *MarkDef { return nil },
+	}
+
+	span := NewBlock("normal").AddSpan("see https://example.com/PROJ-1 and see PROJ-2")
+	out := AutoLink(Document{*span}, AutoLinkOptions{URLs: true, Patterns: []AutoLinkPattern{skip, issue}, KeyFunc: sequentialKeys()})
+
+	if len(seen) != 1 || seen[0] != "PROJ-2" {
+		t.Errorf("handler saw %q, want only PROJ-2", seen)
+	}
+	want := `"see "[] "https://example.com/PROJ-1"[k1] " and see "[] "PROJ-2"[k2]`
+	if got := describeSpans(out[0]); got != want {
+		t.Errorf("spans = %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,234 @@
+package portabletext
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ChunkOptions controls how Chunks splits a document.
+type ChunkOptions struct {
+	MaxChars  int // Maximum characters (runes) per chunk; 0 means no limit
+	MaxTokens int // Maximum estimated tokens per chunk; 0 means no limit
+
+	// MaxHeadingLevel is the deepest heading that starts a new chunk;
+	// e.g. 2 splits on h1 and h2 only. Zero means all headings (h1–h6).
+	MaxHeadingLevel int
+
+	// EstimateTokens approximates the token count of a string. It must be
+	// monotonic in the length of its input. Defaults to runes/4, rounded up.
+	EstimateTokens func(string) int
+
+	// OffsetUnit is the unit of ChunkSource.Start and End; defaults to
+	// UnitRune. Use UnitUTF16 for offsets that JavaScript clients consume.
+	OffsetUnit OffsetUnit
+}
+
+// Chunk is a contiguous, semantically coherent slice of a document.
+type Chunk struct {
+	Text       string        // Block texts joined by blank lines
+	Breadcrumb []string      // Enclosing heading texts, outermost first
+	Sources    []ChunkSource // The nodes (or parts of nodes) covered, in order
+}
+
+// ChunkSource maps part of a chunk back to its source node.
+// Start and End are offsets into the node's span text, as indexed by
+// NewTextIndex, in runes unless ChunkOptions.OffsetUnit says otherwise.
+type ChunkSource struct {
+	Index int    // Index of the node in the document
+	Key   string // _key of the node, if any
+	Start int
+	End   int
+}
+
+// Keys returns the _key of every node the chunk covers, without duplicates.
+func (c Chunk) Keys() []string {
+	var keys []string
+	for _, s := range c.Sources {
+		if s.Key != "" && (len(keys) == 0 || keys[len(keys)-1] != s.Key) {
+			keys = append(keys, s.Key)
+		}
+	}
+	return keys
+}
+
+// Chunks splits doc into chunks for search indexing or embedding.
+//
+// Every heading (up to MaxHeadingLevel) starts a new chunk, and each chunk
+// carries the breadcrumb of headings it sits under. Within a section,
+// blocks are packed into a chunk until the next would exceed the budget.
+// A block is only cut when it alone exceeds the budget, in which case it is
+// split at sentence or word boundaries. Nodes without text are skipped.
+// Only spans contribute text: inline objects are left out even when they
+// have a text field, so that offsets agree with TextIndex.
+func Chunks(doc Document, opts ChunkOptions) []Chunk {
+	if opts.EstimateTokens == nil {
+		opts.EstimateTokens = estimateTokens
+	}
+	if opts.MaxHeadingLevel <= 0 {
+		opts.MaxHeadingLevel = 6
+	}
+
+	c := chunker{opts: opts}
+	indexes := make([]*TextIndex, len(doc))
+	for i := range doc {
+		n := &doc[i]
+		indexes[i] = NewTextIndex(n)
+		text := indexes[i].Text()
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		if lvl := headingLevel(n); lvl > 0 && lvl <= opts.MaxHeadingLevel {
+			c.flush()
+			for len(c.levels) > 0 && c.levels[len(c.levels)-1] >= lvl {
+				c.levels = c.levels[:len(c.levels)-1]
+				c.crumbs = c.crumbs[:len(c.crumbs)-1]
+			}
+			c.levels = append(c.levels, lvl)
+			c.crumbs = append(c.crumbs, text)
+		}
+
+		c.add(i, n.Key, text)
+	}
+	c.flush()
+
+	if opts.OffsetUnit != UnitRune {
+		for _, ch := range c.out {
+			for k := range ch.Sources {
+				src := &ch.Sources[k]
+				src.Start = convertFloor(indexes[src.Index], src.Start, opts.OffsetUnit)
+				src.End = convertFloor(indexes[src.Index], src.End, opts.OffsetUnit)
+			}
+		}
+	}
+	return c.out
+}
+
+// convertFloor converts a rune offset to unit u, moving back to the start
+// of a grapheme cluster that a long block was split inside.
+func convertFloor(x *TextIndex, r int, u OffsetUnit) int {
+	for ; r > 0; r-- {
+		if off, err := x.Convert(r, UnitRune, u); err == nil {
+			return off
+		}
+	}
+	return 0
+}
+
+type chunker struct {
+	opts   ChunkOptions
+	out    []Chunk
+	levels []int
+	crumbs []string
+
+	parts   []string
+	sources []ChunkSource
+}
+
+func (c *chunker) add(index int, key, text string) {
+	if c.fits(c.joined(text)) {
+		c.append(ChunkSource{Index: index, Key: key, End: utf8.RuneCountInString(text)}, text)
+		return
+	}
+	c.flush()
+	if c.fits(text) {
+		c.append(ChunkSource{Index: index, Key: key, End: utf8.RuneCountInString(text)}, text)
+		return
+	}
+
+	// The block alone exceeds the budget: split it.
+	runes := []rune(text)
+	start := 0
+	for start < len(runes) {
+		end := c.splitPoint(runes, start)
+		c.append(ChunkSource{Index: index, Key: key, Start: start, End: end}, strings.TrimSpace(string(runes[start:end])))
+		c.flush()
+		start = end
+		for start < len(runes) && unicode.IsSpace(runes[start]) {
+			start++
+		}
+	}
+}
+
+func (c *chunker) append(src ChunkSource, text string) {
+	c.parts = append(c.parts, text)
+	c.sources = append(c.sources, src)
+}
+
+func (c *chunker) joined(text string) string {
+	if len(c.parts) == 0 {
+		return text
+	}
+	return strings.Join(c.parts, "\n\n") + "\n\n" + text
+}
+
+func (c *chunker) flush() {
+	if len(c.parts) == 0 {
+		return
+	}
+	c.out = append(c.out, Chunk{
+		Text:       strings.Join(c.parts, "\n\n"),
+		Breadcrumb: append([]string(nil), c.crumbs...),
+		Sources:    c.sources,
+	})
+	c.parts = nil
+	c.sources = nil
+}
+
+func (c *chunker) fits(s string) bool {
+	if c.opts.MaxChars > 0 && utf8.RuneCountInString(s) > c.opts.MaxChars {
+		return false
+	}
+	if c.opts.MaxTokens > 0 && c.opts.EstimateTokens(s) > c.opts.MaxTokens {
+		return false
+	}
+	return true
+}
+
+// splitPoint returns the end of the longest piece starting at start that
+// fits the budget, preferring sentence and then word boundaries.
+func (c *chunker) splitPoint(runes []rune, start int) int {
+	// Binary search the longest prefix that fits; always take at least one rune.
+	lo, hi := start+1, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if c.fits(string(runes[start:mid])) {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	limit := lo
+	if limit == len(runes) {
+		return limit
+	}
+
+	for i := limit; i > start+1; i-- {
+		if unicode.IsSpace(runes[i]) && strings.ContainsRune(".!?", runes[i-1]) {
+			return i
+		}
+	}
+	for i := limit; i > start+1; i-- {
+		if unicode.IsSpace(runes[i]) {
+			return i
+		}
+	}
+	return limit
+}
+
+func estimateTokens(s string) int {
+	return (utf8.RuneCountInString(s) + 3) / 4
+}
+
+// headingLevel returns 1–6 for blocks styled h1–h6, and 0 otherwise.
+func headingLevel(n *Node) int {
+	if !n.IsBlock() {
+		return 0
+	}
+	s := n.GetStyle()
+	if len(s) == 2 && s[0] == 'h' && s[1] >= '1' && s[1] <= '6' {
+		return int(s[1] - '0')
+	}
+	return 0
+}
@@ -0,0 +1,146 @@
+package portabletext
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func chunkTestDoc() Document {
+	block := func(key, style, text string) Node {
+		n := NewBlock(style).AddSpan(text)
+		n.Key = key
+		return *n
+	}
+	item := block("li1", "normal", "First item")
+	bullet := "bullet"
+	item.ListItem = &bullet
+
+	return Document{
+		block("h1", "h1", "Guide"),
+		block("p1", "normal", "Intro paragraph."),
+		block("h2a", "h2", "Install"),
+		block("p2", "normal", "Run the installer."),
+		item,
+		*NewNode("image"),
+		block("h2b", "h2", "Usage"),
+		block("p3", "normal", "Call the API."),
+	}
+}
+
+func TestChunkByHeadings(t *testing.T) {
+	chunks := Chunks(chunkTestDoc(), ChunkOptions{})
+
+	if len(chunks) != 3 {
+		t.Fatalf("Chunks() returned %d chunks, want 3", len(chunks))
+	}
+
+	want := []struct {
+		crumbs string
+		keys   string
+	}{
+		{"Guide", "h1,p1"},
+		{"Guide > Install", "h2a,p2,li1"},
+		{"Guide > Usage", "h2b,p3"},
+	}
+	for i, w := range want {
+		if got := strings.Join(chunks[i].Breadcrumb, " > "); got != w.crumbs {
+			t.Errorf("chunk[%d].Breadcrumb = %s, want %s", i, got, w.crumbs)
+		}
+		if got := strings.Join(chunks[i].Keys(), ","); got != w.keys {
+			t.Errorf("chunk[%d].Keys() = %s, want %s", i, got, w.keys)
+		}
+	}
+	if chunks[0].Text != "Guide\n\nIntro paragraph." {
+		t.Errorf("chunk[0].Text = %q", chunks[0].Text)
+	}
+}
+
+func TestChunkBudgetKeepsBlocksWhole(t *testing.T) {
+	doc := chunkTestDoc()
+	chunks := Chunks(doc, ChunkOptions{MaxChars: 30})
+
+	for _, c := range chunks {
+		if utf8.RuneCountInString(c.Text) > 30 {
+			t.Errorf("chunk exceeds budget: %q", c.Text)
+		}
+		for _, s := range c.Sources {
+			if s.Start != 0 || s.End != utf8.RuneCountInString(doc[s.Index].GetText()) {
+				t.Errorf("block %s was cut: %+v", s.Key, s)
+			}
+		}
+	}
+	// "Install" + "Run the installer." fits; the list item does not.
+	if got := strings.Join(chunks[1].Keys(), ","); got != "h2a,p2" {
+		t.Errorf("chunk[1].Keys() = %s, want h2a,p2", got)
+	}
+}
+
+func TestChunkSplitsOversizedBlock(t *testing.T) {
+	text := "One sentence here. Another sentence follows. And a final one."
+	n := NewBlock("normal").AddSpan(text)
+	n.Key = "long"
+
+	chunks := Chunks(Document{*n}, ChunkOptions{MaxTokens: 8})
+	if len(chunks) < 2 {
+		t.Fatalf("Chunks() returned %d chunks, want a split", len(chunks))
+	}
+
+	runes := []rune(text)
+	for _, c := range chunks {
+		if estimateTokens(c.Text) > 8 {
+			t.Errorf("chunk exceeds token budget: %q", c.Text)
+		}
+		src := c.Sources[0]
+		if got := strings.TrimSpace(string(runes[src.Start:src.End])); got != c.Text {
+			t.Errorf("offsets %d:%d map to %q, want %q", src.Start, src.End, got, c.Text)
+		}
+	}
+	if chunks[0].Text != "One sentence here." {
+		t.Errorf("first piece = %q, want sentence boundary", chunks[0].Text)
+	}
+}
+
+func TestChunkMaxHeadingLevel(t *testing.T) {
+	chunks := Chunks(chunkTestDoc(), ChunkOptions{MaxHeadingLevel: 1})
+	if len(chunks) != 1 {
+		t.Errorf("Chunks() returned %d chunks, want 1", len(chunks))
+	}
+}
+
+func TestChunksOffsetUnit(t *testing.T) {
+	doc := Document{*NewBlock("normal").AddSpan("😀 one two three four five")}
+	chunks := Chunks(doc, ChunkOptions{MaxChars: 10, OffsetUnit: UnitUTF16})
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want at least 2", len(chunks))
+	}
+	// "😀 one two" is 9 runes but 10 UTF-16 code units.
+	if s := chunks[0].Sources[0]; s.Start != 0 || s.End != 10 {
+		t.Errorf("first source = %+v, want End 10", s)
+	}
+	last := chunks[len(chunks)-1].Sources[0]
+	if last.End != 26 {
+		t.Errorf("last source = %+v, want End 26", last)
+	}
+}
+
+func TestChunksInlineObjectText(t *testing.T) {
+	// GetText includes the inline object's text field; TextIndex does not,
+	// and chunk offsets must agree with TextIndex.
+	n := NewBlock("normal").AddSpan("Hi ")
+	label := "@ada"
+	n.Children = append(n.Children, Span{Type: "mention", Text: &label, Raw: map[string]any{}})
+	n.AddSpan("😀 there")
+	doc := Document{*n}
+
+	for _, u := range []OffsetUnit{UnitRune, UnitUTF16} {
+		chunks := Chunks(doc, ChunkOptions{OffsetUnit: u})
+		if len(chunks) != 1 || chunks[0].Text != "Hi 😀 there" {
+			t.Fatalf("Chunks(%v) = %+v", u, chunks)
+		}
+		x := NewTextIndex(&doc[0])
+		if s := chunks[0].Sources[0]; s.Start != 0 || s.End != x.Len(u) {
+			t.Errorf("Chunks(%v) source = %+v, want End %d", u, s, x.Len(u))
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/derickschaefer/portabletext"
+)
+
+var formatExts = map[string]string{
+	".json": "json",
+	".html": "html", ".htm": "html",
+	".md": "markdown", ".markdown": "markdown",
+	".txt": "text",
+}
+
+var formatAliases = map[string]string{
+	"json": "json", "html": "html", "markdown": "markdown", "md": "markdown", "text": "text", "txt": "text",
+}
+
+var outputExts = map[string]string{"json": ".json", "html": ".html", "markdown": ".md", "text": ".txt"}
+
+func runConvert(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("convert", "-to format [file|glob ...]", stderr)
+	from := fs.String("from", "", "input format: json, html, markdown or text (default: from file extension, else json)")
+	to := fs.String("to", "", "output format: json, html, markdown or text")
+	dir := fs.String("d", "", "write each result to this directory instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	outFormat, ok := formatAliases[*to]
+	if !ok {
+		fmt.Fprintf(stderr, "ptx convert: -to must be json, html, markdown or text\n")
+		return 2
+	}
+	if *from != "" && formatAliases[*from] == "" {
+		fmt.Fprintf(stderr, "ptx convert: unknown -from format %q\n", *from)
+		return 2
+	}
+
+	inputs, err := readInputs(fs.Args(), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "ptx convert: %v\n", err)
+		return 2
+	}
+
+	status := 0
+	for _, in := range inputs {
+		inFormat := formatAliases[*from]
+		if inFormat == "" {
+			inFormat = formatExts[strings.ToLower(filepath.Ext(in.name))]
+		}
+		if inFormat == "" {
+			inFormat = "json"
+		}
+
+		doc, err := parseAs(inFormat, in)
+		if err != nil {
+			reportError(stderr, in.name, err)
+			status = 2
+			continue
+		}
+		out, skipped, err := renderAs(outFormat, doc)
+		if err != nil {
+			reportError(stderr, in.name, err)
+			status = 2
+			continue
+		}
+		for _, t := range sortedCounts(skipped) {
+			fmt.Fprintf(stderr, "%s: skipped %d %s node(s)\n", in.name, skipped[t], t)
+		}
+
+		if *dir == "" {
+			io.WriteString(stdout, out)
+			continue
+		}
+		name := "stdin"
+		if !in.isStdin() {
+			name = strings.TrimSuffix(filepath.Base(in.name), filepath.Ext(in.name))
+		}
+		dst := filepath.Join(*dir, name+outputExts[outFormat])
+		if err := os.WriteFile(dst, []byte(out), 0o644); err != nil {
+			fmt.Fprintf(stderr, "ptx convert: %v\n", err)
+			status = 2
+		}
+	}
+	return status
+}
+
+func parseAs(format string, in input) (portabletext.Document, error) {
+	switch format {
+	case "html":
+		return parseHTML(string(in.data)), nil
+	case "markdown":
+		return parseMarkdown(string(in.data)), nil
+	case "text":
+		return parseText(string(in.data)), nil
+	}
+	return decodeInput(in)
+}
+
+func renderAs(format string, doc portabletext.Document) (string, map[string]int, error) {
+	switch format {
+	case "html":
+		out, skipped := renderHTML(doc)
+		return out, skipped, nil
+	case "markdown":
+		out, skipped := renderMarkdown(doc)
+		return out, skipped, nil
+	case "text":
+		out, skipped := renderText(doc)
+		return out, skipped, nil
+	}
+	b, err := formatDocument(doc)
+	return string(b), nil, err
+}
+
+func sortedCounts(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
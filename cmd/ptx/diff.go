@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/derickschaefer/portabletext"
+)
+
+// change is one difference between two documents.
+type change struct {
+	Op     string `json:"op"` // "added", "removed", "changed", "moved"
+	Key    string `json:"key"`
+	Path   string `json:"path"`             // in the new document, or the old one for removals
+	From   string `json:"from,omitempty"`   // old path, for moves
+	Detail string `json:"detail,omitempty"` // what changed
+}
+
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("diff", "old.json new.json", stderr)
+	asJSON := fs.Bool("json", false, "print changes as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+	inputs, err := readInputs(fs.Args(), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "ptx diff: %v\n", err)
+		return 2
+	}
+	var docs [2]portabletext.Document
+	for i, in := range inputs {
+		if docs[i], err = decodeInput(in); err != nil {
+			reportError(stderr, in.name, err)
+			return 2
+		}
+	}
+
+	changes := diffDocuments(docs[0], docs[1])
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if changes == nil {
+			changes = []change{}
+		}
+		enc.Encode(changes)
+	} else {
+		sym := map[string]string{"added": "+", "removed": "-", "changed": "~", "moved": ">"}
+		for _, c := range changes {
+			fmt.Fprintf(stdout, "%s %s %s", sym[c.Op], c.Path, c.Key)
+			if c.From != "" {
+				fmt.Fprintf(stdout, " (was %s)", c.From)
+			}
+			if c.Detail != "" {
+				fmt.Fprintf(stdout, ": %s", c.Detail)
+			}
+			fmt.Fprintln(stdout)
+		}
+	}
+	if len(changes) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// diffDocuments compares two documents block by block. Nodes are matched
+// by _key, falling back to their index for nodes without one. Nodes that
+// keep their key but fall out of the longest common order are reported as
+// moved.
+func diffDocuments(a, b portabletext.Document) []change {
+	id := func(doc portabletext.Document, i int) string {
+		if doc[i].Key != "" {
+			return doc[i].Key
+		}
+		return fmt.Sprintf("#%d", i)
+	}
+	oldIdx := map[string]int{}
+	for i := range a {
+		oldIdx[id(a, i)] = i
+	}
+	newIdx := map[string]int{}
+	for i := range b {
+		newIdx[id(b, i)] = i
+	}
+
+	// Common keys in new order, and the old positions of each.
+	var common []string
+	for i := range b {
+		if _, ok := oldIdx[id(b, i)]; ok {
+			common = append(common, id(b, i))
+		}
+	}
+	stay := map[string]bool{}
+	for _, k := range longestIncreasing(common, oldIdx) {
+		stay[k] = true
+	}
+
+	var out []change
+	for i := range a {
+		if _, ok := newIdx[id(a, i)]; !ok {
+			out = append(out, change{Op: "removed", Key: id(a, i), Path: fmt.Sprintf("[%d]", i), Detail: describeNode(&a[i])})
+		}
+	}
+	for i := range b {
+		k := id(b, i)
+		path := fmt.Sprintf("[%d]", i)
+		j, ok := oldIdx[k]
+		if !ok {
+			out = append(out, change{Op: "added", Key: k, Path: path, Detail: describeNode(&b[i])})
+			continue
+		}
+		if !stay[k] {
+			out = append(out, change{Op: "moved", Key: k, Path: path, From: fmt.Sprintf("[%d]", j)})
+		}
+		for _, d := range nodeChanges(&a[j], &b[i]) {
+			out = append(out, change{Op: "changed", Key: k, Path: path, Detail: d})
+		}
+	}
+	return out
+}
+
+// longestIncreasing returns the keys forming the longest subsequence of
+// keys whose old positions increase: the nodes that did not move.
+func longestIncreasing(keys []string, pos map[string]int) []string {
+	n := len(keys)
+	length := make([]int, n)
+	prev := make([]int, n)
+	best := -1
+	for i := range keys {
+		length[i], prev[i] = 1, -1
+		for j := 0; j < i; j++ {
+			if pos[keys[j]] < pos[keys[i]] && length[j]+1 > length[i] {
+				length[i], prev[i] = length[j]+1, j
+			}
+		}
+		if best < 0 || length[i] > length[best] {
+			best = i
+		}
+	}
+	var out []string
+	for i := best; i >= 0; i = prev[i] {
+		out = append(out, keys[i])
+	}
+	return out
+}
+
+func nodeChanges(a, b *portabletext.Node) []string {
+	var out []string
+	field := func(name, x, y string) {
+		if x != y {
+			out = append(out, fmt.Sprintf("%s: %q -> %q", name, truncateText(x, 60), truncateText(y, 60)))
+		}
+	}
+	field("type", a.Type, b.Type)
+	if a.IsBlock() || b.IsBlock() {
+		field("style", a.GetStyle(), b.GetStyle())
+		field("listItem", strOrEmpty(a.ListItem), strOrEmpty(b.ListItem))
+		if a.ListItem != nil || b.ListItem != nil {
+			field("level", fmt.Sprint(a.GetListLevel()), fmt.Sprint(b.GetListLevel()))
+		}
+		field("text", a.GetText(), b.GetText())
+	}
+	if len(out) == 0 {
+		x, _ := json.Marshal(a)
+		y, _ := json.Marshal(b)
+		if !bytes.Equal(x, y) {
+			out = append(out, "marks, annotations or fields changed")
+		}
+	}
+	return out
+}
+
+func describeNode(n *portabletext.Node) string {
+	if n.IsBlock() {
+		return fmt.Sprintf("%s %q", n.GetStyle(), truncateText(n.GetText(), 60))
+	}
+	return n.Type
+}
+
+func strOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
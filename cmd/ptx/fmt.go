@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/derickschaefer/portabletext"
+)
+
+func runFmt(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("fmt", "[file|glob ...]", stderr)
+	write := fs.Bool("w", false, "write result to the source file instead of stdout")
+	list := fs.Bool("l", false, "list files whose formatting differs")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	inputs, err := readInputs(fs.Args(), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "ptx fmt: %v\n", err)
+		return 2
+	}
+
+	status := 0
+	for _, in := range inputs {
+		doc, err := decodeInput(in)
+		if err != nil {
+			reportError(stderr, in.name, err)
+			status = 2
+			continue
+		}
+		out, err := formatDocument(doc)
+		if err != nil {
+			reportError(stderr, in.name, err)
+			status = 2
+			continue
+		}
+		changed := !bytes.Equal(in.data, out)
+
+		if *list && changed {
+			fmt.Fprintln(stdout, in.name)
+			status = max(status, 1)
+		}
+		switch {
+		case *write && in.isStdin():
+			fmt.Fprintln(stderr, "ptx fmt: cannot use -w with stdin")
+			return 2
+		case *write:
+			if changed {
+				if err := os.WriteFile(in.name, out, 0o644); err != nil {
+					fmt.Fprintf(stderr, "ptx fmt: %v\n", err)
+					status = 2
+				}
+			}
+		case !*list:
+			stdout.Write(out)
+		}
+	}
+	return status
+}
+
+// formatDocument returns the normalized encoding of doc: two-space
+// indentation, object keys sorted, and a trailing newline, so that
+// formatting is stable and diffs stay small.
+func formatDocument(doc portabletext.Document) ([]byte, error) {
+	if doc == nil {
+		doc = portabletext.Document{}
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
@@ -0,0 +1,146 @@
+// Command ptx validates, formats, converts and inspects Portable Text JSON.
+//
+// Usage:
+//
+//	ptx <command> [flags] [file|glob ...]
+//
+// Files may be given as glob patterns; with no files, or "-", input is read
+// from stdin. Errors are reported as file:path: message, where path is the
+// location inside the document, e.g. [3].children[1].
+//
+// Exit status is 0 on success, 1 when a check finds problems (validation
+// errors, unformatted files, differences) and 2 on usage or I/O errors.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/derickschaefer/portabletext"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands = []command{
+	{"validate", "check documents for structural errors", runValidate},
+	{"fmt", "rewrite documents in normalized form", runFmt},
+	{"convert", "convert between JSON, HTML, Markdown and text", runConvert},
+	{"stats", "print document statistics", runStats},
+	{"query", "select nodes, spans and markDefs", runQuery},
+	{"diff", "compare two documents block by block", runDiff},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(stderr)
+		return 2
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:], stdout, stderr)
+		}
+	}
+	fmt.Fprintf(stderr, "ptx: unknown command %q\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: ptx <command> [flags] [file|glob ...]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'ptx <command> -h' for command flags.")
+}
+
+func newFlagSet(name, args string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("ptx "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: ptx %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// input is one file (or stdin) named on the command line.
+type input struct {
+	name string // "-" for stdin
+	data []byte
+}
+
+func (in input) isStdin() bool { return in.name == "-" }
+
+// expandArgs resolves glob patterns. With no arguments it returns "-".
+func expandArgs(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return []string{"-"}, nil
+	}
+	var names []string
+	for _, a := range args {
+		if a == "-" || !strings.ContainsAny(a, "*?[") {
+			names = append(names, a)
+			continue
+		}
+		matches, err := filepath.Glob(a)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern %q: %v", a, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", a)
+		}
+		sort.Strings(matches)
+		names = append(names, matches...)
+	}
+	return names, nil
+}
+
+func readInputs(args []string, stdin io.Reader) ([]input, error) {
+	names, err := expandArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	inputs := make([]input, 0, len(names))
+	for _, name := range names {
+		var data []byte
+		if name == "-" {
+			data, err = io.ReadAll(stdin)
+		} else {
+			data, err = os.ReadFile(name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, input{name: name, data: data})
+	}
+	return inputs, nil
+}
+
+// stdin is swapped out by tests.
+var stdin io.Reader = os.Stdin
+
+func decodeInput(in input) (portabletext.Document, error) {
+	return portabletext.DecodeString(string(in.data))
+}
+
+// reportError prints err as file:path: message, using the location carried
+// by *portabletext.Error or *portabletext.ValidationError.
+func reportError(w io.Writer, name string, err error) {
+	printProblem(w, toProblem(name, err))
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sampleDoc = `[
+{"_type":"block","_key":"a","style":"h1","children":[{"_type":"span","text":"Title"}],"markDefs":[]},
+{"_type":"block","_key":"b","style":"normal","children":[
+  {"_type":"span","text":"See "},
+  {"_type":"span","text":"the docs","marks":["strong","l1"]},
+  {"_type":"span","text":" now","marks":["strong"]}
+],"markDefs":[{"_type":"link","_key":"l1","href":"https://example.com"}]},
+{"_type":"block","_key":"c","style":"normal","listItem":"bullet","level":1,"children":[{"_type":"span","text":"One"}]},
+{"_type":"block","_key":"d","style":"normal","listItem":"number","level":2,"children":[{"_type":"span","text":"Two"}]}
+]`
+
+func sequentialNewKey(t *testing.T) {
+	n := 0
+	old := newKey
+	newKey = func() string {
+		n++
+		return fmt.Sprintf("k%d", n)
+	}
+	t.Cleanup(func() { newKey = old })
+}
+
+func writeFile(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func runPtx(t *testing.T, in string, args ...string) (string, string, int) {
+	t.Helper()
+	old := stdin
+	stdin = strings.NewReader(in)
+	defer func() { stdin = old }()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "good.json", sampleDoc)
+	writeFile(t, dir, "empty.json", `[{"_type":"block","children":[{"_type":"span","text":""}]}]`)
+	writeFile(t, dir, "broken.json", `[{"_type":"block"},{"children":[]}]`)
+
+	out, _, code := runPtx(t, "", "validate", filepath.Join(dir, "*.json"))
+	if code != 1 {
+		t.Errorf("validate exit = %d, want 1", code)
+	}
+	for _, want := range []string{"broken.json:[1]: missing _type", "empty.json:[0].children[0]: span has empty text"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("validate output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "good.json") {
+		t.Errorf("good.json reported:\n%s", out)
+	}
+
+	out, _, code = runPtx(t, sampleDoc, "validate", "-check-marks", "-format", "json")
+	if code != 1 || !strings.Contains(out, `"path": "[1].children[1]"`) || !strings.Contains(out, `"file": "-"`) {
+		t.Errorf("validate -check-marks -format json = %d\n%s", code, out)
+	}
+
+	if _, _, code := runPtx(t, sampleDoc, "validate"); code != 0 {
+		t.Errorf("validate(valid) exit = %d, want 0", code)
+	}
+}
+
+func TestFmt(t *testing.T) {
+	dir := t.TempDir()
+	p := writeFile(t, dir, "doc.json", `[{"_type":"block","children":[{"text":"Hi","_type":"span"}]}]`)
+
+	out, _, code := runPtx(t, "", "fmt", "-l", p)
+	if code != 1 || strings.TrimSpace(out) != p {
+		t.Errorf("fmt -l = %d %q, want 1 %q", code, out, p)
+	}
+	if _, _, code := runPtx(t, "", "fmt", "-w", p); code != 0 {
+		t.Fatalf("fmt -w exit = %d", code)
+	}
+	got, _ := os.ReadFile(p)
+	want := "[\n  {\n    \"_type\": \"block\",\n    \"children\": [\n      {\n        \"_type\": \"span\",\n        \"text\": \"Hi\"\n      }\n    ]\n  }\n]\n"
+	if string(got) != want {
+		t.Errorf("fmt -w wrote\n%s\nwant\n%s", got, want)
+	}
+	if out, _, code := runPtx(t, "", "fmt", "-l", p); code != 0 || out != "" {
+		t.Errorf("fmt -l after -w = %d %q, want clean", code, out)
+	}
+
+	_, stderr, code := runPtx(t, `[{"_type":"block","children":[{"text":"x"}]}]`, "fmt")
+	if code != 2 || !strings.Contains(stderr, "-:[0].children[0]:") {
+		t.Errorf("fmt(bad) = %d %q, want path-aware error", code, stderr)
+	}
+}
+
+func TestConvertRender(t *testing.T) {
+	tests := []struct {
+		to   string
+		want string
+	}{
+		{"html", `<h1 id="title">Title</h1>
+<p>See <strong><a href="https://example.com">the docs</a> now</strong></p>
+<ul>
+<li>One<ol>
+<li>Two</li>
+</ol>
+</li>
+</ul>
+`},
+		{"markdown", `# Title
+
+See **[the docs](https://example.com) now**
+
+- One
+    1. Two
+`},
+		{"text", `Title
+
+See the docs now
+
+- One
+  1. Two
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.to, func(t *testing.T) {
+			out, stderr, code := runPtx(t, sampleDoc, "convert", "-to", tt.to)
+			if code != 0 || out != tt.want {
+				t.Errorf("convert -to %s = %d %s\n%s\nwant\n%s", tt.to, code, stderr, out, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertHeadingIDs(t *testing.T) {
+	in := `[{"_type":"block","style":"h2","children":[{"_type":"span","text":"Intro"}]},
+		{"_type":"block","style":"h2","children":[{"_type":"span","text":"Intro"}]},
+		{"_type":"block","style":"h2","anchor":"intro","children":[{"_type":"span","text":"Renamed"}]}]`
+	out, _, code := runPtx(t, in, "convert", "-to", "html")
+	want := "<h2 id=\"intro-1\">Intro</h2>\n<h2 id=\"intro-2\">Intro</h2>\n<h2 id=\"intro\">Renamed</h2>\n"
+	if code != 0 || out != want {
+		t.Errorf("convert -to html = %q, want %q", out, want)
+	}
+}
+
+func TestConvertListLevelZero(t *testing.T) {
+	in := `[{"_type":"block","listItem":"bullet","level":0,"children":[{"_type":"span","text":"A"}]},
+		{"_type":"block","listItem":"bullet","level":-2,"children":[{"_type":"span","text":"B"}]}]`
+	for _, to := range []string{"html", "markdown", "text"} {
+		out, stderr, code := runPtx(t, in, "convert", "-to", to)
+		if code != 0 || !strings.Contains(out, "A") || !strings.Contains(out, "B") {
+			t.Errorf("convert -to %s = %d %s\n%s", to, code, stderr, out)
+		}
+	}
+	if out, _, _ := runPtx(t, in, "convert", "-to", "html"); out != "<ul>\n<li>A</li>\n<li>B</li>\n</ul>\n" {
+		t.Errorf("convert -to html = %q", out)
+	}
+}
+
+func TestConvertUnsafeHref(t *testing.T) {
+	in := `[{"_type":"block","markDefs":[{"_type":"link","_key":"l","href":"javascript:alert(1)"}],
+		"children":[{"_type":"span","text":"click","marks":["l"]}]}]`
+	for _, to := range []string{"html", "markdown"} {
+		out, stderr, code := runPtx(t, in, "convert", "-to", to)
+		if code != 0 || strings.Contains(out, "javascript") || !strings.Contains(out, "click") {
+			t.Errorf("convert -to %s = %d %s\n%s", to, code, stderr, out)
+		}
+	}
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	sequentialNewKey(t)
+	for _, from := range []string{"html", "markdown"} {
+		t.Run(from, func(t *testing.T) {
+			rendered, _, _ := runPtx(t, sampleDoc, "convert", "-to", from)
+			back, _, code := runPtx(t, rendered, "convert", "-from", from, "-to", "markdown")
+			want, _, _ := runPtx(t, sampleDoc, "convert", "-to", "markdown")
+			if code != 0 || back != want {
+				t.Errorf("%s round trip:\n%s\nwant\n%s", from, back, want)
+			}
+		})
+	}
+}
+
+func TestParseHTML(t *testing.T) {
+	sequentialNewKey(t)
+	doc := parseHTML(`<p>A <b>bold</b> &amp; <a href="/x?a=1&amp;b=2">link</a><br>
+		line</p><blockquote>Q</blockquote><pre><code class="language-go">x &lt; y</code></pre>
+		<ul><li><p>item</p></li></ul><script>ignored()</script>`)
+
+	if len(doc) != 4 {
+		t.Fatalf("got %d nodes, want 4: %+v", len(doc), doc)
+	}
+	if got := doc[0].GetText(); got != "A bold & link\nline" {
+		t.Errorf("paragraph text = %q", got)
+	}
+	if href := doc[0].MarkDefs[0].Raw["href"]; href != "/x?a=1&b=2" {
+		t.Errorf("href = %v", href)
+	}
+	if doc[1].GetStyle() != "blockquote" || doc[2].Type != "code" || doc[2].Raw["language"] != "go" || doc[2].Raw["code"] != "x < y" {
+		t.Errorf("blockquote/code = %+v %+v", doc[1], doc[2])
+	}
+	if doc[3].ListItem == nil || doc[3].GetText() != "item" {
+		t.Errorf("list item = %+v", doc[3])
+	}
+}
+
+func TestParseHTMLRawTextNonASCII(t *testing.T) {
+	// Lowercasing changes the byte length of these runes, so the closing
+	// tag must be found in the original text.
+	sequentialNewKey(t)
+	doc := parseHTML(`<pre>İİİİ</pre><p>after</p>`)
+	if len(doc) != 2 || doc[0].Raw["code"] != "İİİİ" || doc[1].GetText() != "after" {
+		t.Errorf("parseHTML(İ) = %+v", doc)
+	}
+
+	doc = parseHTML(`<p>x</p><pre>` + strings.Repeat("Ⱥ", 20) + `</pre>`)
+	if len(doc) != 2 || doc[0].GetText() != "x" || doc[1].Raw["code"] != strings.Repeat("Ⱥ", 20) {
+		t.Errorf("parseHTML(Ⱥ) = %+v", doc)
+	}
+
+	doc = parseHTML(`<PRE>a</Pre><p>b</p>`)
+	if len(doc) != 2 || doc[0].Raw["code"] != "a" {
+		t.Errorf("parseHTML(mixed case) = %+v", doc)
+	}
+}
+
+func TestParseMarkdownInline(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain 2 * 3 and snake_case", `"plain 2 * 3 and snake_case"[]`},
+		{"*a* **b** ~~c~~ `d*e`", `"a"[em] " "[] "b"[strong] " "[] "c"[strike-through] " "[] "d*e"[code]`},
+		{`\*lit\* [x](http://a "t")`, `"*lit* "[] "x"[k1]`},
+		{"**a *b* c**", `"a "[strong] "b"[strong em] " c"[strong]`},
+	}
+	for _, tt := range tests {
+		sequentialNewKey(t)
+		doc := parseMarkdown(tt.in)
+		var parts []string
+		for _, s := range doc[0].Children {
+			parts = append(parts, fmt.Sprintf("%q%v", *s.Text, s.Marks))
+		}
+		if got := strings.Join(parts, " "); got != tt.want {
+			t.Errorf("parseMarkdown(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	out, _, code := runPtx(t, sampleDoc, "query", "span[marks~=strong]")
+	want := "-:[1].children[1]\tspan\tthe docs\n-:[1].children[2]\tspan\t now\n"
+	if code != 0 || out != want {
+		t.Errorf("query = %d\n%s\nwant\n%s", code, out, want)
+	}
+
+	out, _, _ = runPtx(t, sampleDoc, "query", "-fields", "_key,style", "block[listItem]")
+	if out != "-:[2]\tc\tnormal\n-:[3]\td\tnormal\n" {
+		t.Errorf("query -fields = %q", out)
+	}
+
+	if _, stderr, code := runPtx(t, sampleDoc, "query", "block[style"); code != 2 || stderr == "" {
+		t.Errorf("query(bad selector) = %d %q", code, stderr)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	a := writeFile(t, dir, "a.json", `[
+		{"_type":"block","_key":"x","children":[{"_type":"span","text":"Keep"}]},
+		{"_type":"block","_key":"y","children":[{"_type":"span","text":"Old"}]},
+		{"_type":"block","_key":"z","children":[{"_type":"span","text":"Gone"}]},
+		{"_type":"image","_key":"i"}]`)
+	b := writeFile(t, dir, "b.json", `[
+		{"_type":"image","_key":"i"},
+		{"_type":"block","_key":"x","children":[{"_type":"span","text":"Keep"}]},
+		{"_type":"block","_key":"y","style":"h2","children":[{"_type":"span","text":"New"}]},
+		{"_type":"block","_key":"n","children":[{"_type":"span","text":"Added"}]}]`)
+
+	out, _, code := runPtx(t, "", "diff", a, b)
+	want := `- [2] z: normal "Gone"
+> [0] i (was [3])
+~ [2] y: style: "normal" -> "h2"
+~ [2] y: text: "Old" -> "New"
++ [3] n: normal "Added"
+`
+	if code != 1 || out != want {
+		t.Errorf("diff = %d\n%s\nwant\n%s", code, out, want)
+	}
+	if out, _, code := runPtx(t, "", "diff", a, a); code != 0 || out != "" {
+		t.Errorf("diff(same) = %d %q", code, out)
+	}
+}
+
+func TestStatsAndUsage(t *testing.T) {
+	out, _, code := runPtx(t, sampleDoc, "stats")
+	for _, want := range []string{"blocks      4", "words       7", "links       1"} {
+		if code != 0 || !strings.Contains(out, want) {
+			t.Errorf("stats missing %q:\n%s", want, out)
+		}
+	}
+	if _, stderr, code := runPtx(t, "", "nope"); code != 2 || !strings.Contains(stderr, "unknown command") {
+		t.Errorf("unknown command = %d %q", code, stderr)
+	}
+	if _, stderr, code := runPtx(t, "", "validate", "/no/such/*.json"); code != 2 || !strings.Contains(stderr, "no files match") {
+		t.Errorf("validate(no match) = %d %q", code, stderr)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/derickschaefer/portabletext"
+)
+
+// newKey generates _key values for converted documents; tests replace it.
+var newKey = portabletext.NewKey
+
+// docBuilder accumulates blocks for the HTML, Markdown and text parsers.
+type docBuilder struct {
+	doc   portabletext.Document
+	block *portabletext.Node
+}
+
+// start begins a new block; listItem may be empty.
+func (b *docBuilder) start(style, listItem string, level int) {
+	b.flush()
+	b.block = portabletext.NewBlock(style)
+	if listItem != "" {
+		b.block.ListItem = &listItem
+		b.block.Level = &level
+	}
+}
+
+// text appends a span, merging it into the previous span when the marks
+// match. Text outside any block starts a normal paragraph.
+func (b *docBuilder) text(s string, marks []string) {
+	if s == "" {
+		return
+	}
+	if b.block == nil {
+		b.start("normal", "", 0)
+	}
+	if c := b.block.Children; len(c) > 0 && slices.Equal(c[len(c)-1].Marks, marks) {
+		t := *c[len(c)-1].Text + s
+		c[len(c)-1].Text = &t
+		return
+	}
+	b.block.AddSpan(s, slices.Clone(marks)...)
+	if marks == nil {
+		b.block.Children[len(b.block.Children)-1].Marks = []string{}
+	}
+}
+
+// lastText returns the text written so far in the current block.
+func (b *docBuilder) lastText() string {
+	if b.block == nil || len(b.block.Children) == 0 {
+		return ""
+	}
+	return *b.block.Children[len(b.block.Children)-1].Text
+}
+
+// link adds a link markDef to the current block and returns its key.
+func (b *docBuilder) link(href string) string {
+	if b.block == nil {
+		b.start("normal", "", 0)
+	}
+	key := newKey()
+	b.block.AddMarkDef(key, "link", map[string]any{"href": href})
+	return key
+}
+
+// add appends a custom node.
+func (b *docBuilder) add(n *portabletext.Node) {
+	b.flush()
+	n.Key = newKey()
+	b.doc = append(b.doc, *n)
+}
+
+// flush finishes the current block: surrounding whitespace is trimmed,
+// empty spans and unused markDefs are dropped, and keys are assigned.
+// Blocks without text are discarded.
+func (b *docBuilder) flush() {
+	n := b.block
+	b.block = nil
+	if n == nil || len(n.Children) == 0 {
+		return
+	}
+	first := strings.TrimLeftFunc(*n.Children[0].Text, unicode.IsSpace)
+	n.Children[0].Text = &first
+	last := strings.TrimRightFunc(*n.Children[len(n.Children)-1].Text, unicode.IsSpace)
+	n.Children[len(n.Children)-1].Text = &last
+
+	used := map[string]bool{}
+	children := n.Children[:0]
+	for _, c := range n.Children {
+		if *c.Text == "" {
+			continue
+		}
+		c.Raw["_key"] = newKey()
+		for _, m := range c.Marks {
+			used[m] = true
+		}
+		children = append(children, c)
+	}
+	if len(children) == 0 {
+		return
+	}
+	n.Children = children
+	n.MarkDefs = slices.DeleteFunc(n.MarkDefs, func(md portabletext.MarkDef) bool { return !used[md.Key] })
+	n.Key = newKey()
+	b.doc = append(b.doc, *n)
+}
+
+func (b *docBuilder) document() portabletext.Document {
+	b.flush()
+	if b.doc == nil {
+		return portabletext.Document{}
+	}
+	return b.doc
+}
+
+// parseText turns plain text into normal blocks, one per paragraph.
+// Line breaks inside a paragraph are kept.
+func parseText(s string) portabletext.Document {
+	var b docBuilder
+	for _, para := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n\n") {
+		if strings.TrimSpace(para) == "" {
+			continue
+		}
+		b.start("normal", "", 0)
+		b.text(strings.Trim(para, "\n"), nil)
+	}
+	return b.document()
+}
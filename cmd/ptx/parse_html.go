@@ -0,0 +1,378 @@
+package main
+
+import (
+	"html"
+	"strings"
+
+	"github.com/derickschaefer/portabletext"
+)
+
+// htmlToken is one tag or text run from tokenizeHTML.
+type htmlToken struct {
+	text  string            // text content, unescaped; empty for tags
+	tag   string            // lower-case tag name; empty for text
+	end   bool              // </tag>
+	attrs map[string]string // unescaped attribute values
+}
+
+// rawTextTags have contents that are not markup.
+var rawTextTags = map[string]bool{"script": true, "style": true, "pre": true, "textarea": true}
+
+// tokenizeHTML is a small, forgiving HTML tokenizer: it understands tags,
+// attributes, comments, doctypes and character references, which is
+// enough for the fragments CMS editors paste. The contents of script,
+// style, textarea and pre elements are returned as a single text token.
+func tokenizeHTML(s string) []htmlToken {
+	var toks []htmlToken
+	for len(s) > 0 {
+		lt := strings.IndexByte(s, '<')
+		if lt < 0 {
+			toks = append(toks, htmlToken{text: html.UnescapeString(s)})
+			break
+		}
+		if lt > 0 {
+			toks = append(toks, htmlToken{text: html.UnescapeString(s[:lt])})
+			s = s[lt:]
+		}
+
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			end := strings.Index(s[4:], "-->")
+			if end < 0 {
+				return toks
+			}
+			s = s[4+end+3:]
+			continue
+		case strings.HasPrefix(s, "<!") || strings.HasPrefix(s, "<?"):
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				return toks
+			}
+			s = s[end+1:]
+			continue
+		}
+
+		tok, rest, ok := parseTag(s)
+		if !ok {
+			// A lone '<' is text.
+			toks = append(toks, htmlToken{text: "<"})
+			s = s[1:]
+			continue
+		}
+		toks = append(toks, tok)
+		s = rest
+
+		if !tok.end && rawTextTags[tok.tag] {
+			closing := "</" + tok.tag
+			end := indexFold(s, closing)
+			if end < 0 {
+				end = len(s)
+			}
+			body := s[:end]
+			if tok.tag == "pre" {
+				// Keep an inner <code class="language-x"> for the language.
+				toks = append(toks, tokenizePre(body)...)
+			} else if tok.tag != "script" && tok.tag != "style" {
+				toks = append(toks, htmlToken{text: html.UnescapeString(body)})
+			}
+			s = s[end:]
+		}
+	}
+	return toks
+}
+
+// indexFold returns the byte index in s of the first instance of the
+// lowercase ASCII string sub, ignoring ASCII case, or -1. Unlike searching
+// strings.ToLower(s), the index is valid in s even when lowercasing would
+// change the length of non-ASCII text.
+func indexFold(s, sub string) int {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		j := 0
+		for j < len(sub) && lowerASCII(s[i+j]) == sub[j] {
+			j++
+		}
+		if j == len(sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+func lowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// tokenizePre splits the body of a <pre> into an optional <code> tag and
+// its literal text, dropping any other markup.
+func tokenizePre(body string) []htmlToken {
+	var toks []htmlToken
+	var text strings.Builder
+	for len(body) > 0 {
+		lt := strings.IndexByte(body, '<')
+		if lt < 0 {
+			text.WriteString(body)
+			break
+		}
+		text.WriteString(body[:lt])
+		tok, rest, ok := parseTag(body[lt:])
+		if !ok {
+			text.WriteByte('<')
+			body = body[lt+1:]
+			continue
+		}
+		if tok.tag == "code" && !tok.end && len(toks) == 0 {
+			toks = append(toks, tok)
+		}
+		body = rest
+	}
+	return append(toks, htmlToken{text: html.UnescapeString(text.String())})
+}
+
+// parseTag parses a tag at the start of s ("<a href=x>", "</p>", "<br/>").
+func parseTag(s string) (htmlToken, string, bool) {
+	i := 1
+	tok := htmlToken{}
+	if i < len(s) && s[i] == '/' {
+		tok.end = true
+		i++
+	}
+	start := i
+	for i < len(s) && (isASCIILetter(s[i]) || (i > start && s[i] >= '0' && s[i] <= '9')) {
+		i++
+	}
+	if i == start {
+		return tok, s, false
+	}
+	tok.tag = strings.ToLower(s[start:i])
+
+	for i < len(s) {
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			return tok, s, false
+		}
+		if s[i] == '>' {
+			return tok, s[i+1:], true
+		}
+		if s[i] == '/' {
+			i++
+			continue
+		}
+		ns := i
+		for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		name := strings.ToLower(s[ns:i])
+		val := ""
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isHTMLSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				q := s[i]
+				end := strings.IndexByte(s[i+1:], q)
+				if end < 0 {
+					return tok, s, false
+				}
+				val = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				vs := i
+				for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				val = s[vs:i]
+			}
+		}
+		if name != "" {
+			if tok.attrs == nil {
+				tok.attrs = map[string]string{}
+			}
+			tok.attrs[name] = html.UnescapeString(val)
+		}
+	}
+	return tok, s, false
+}
+
+func isASCIILetter(c byte) bool { return c|0x20 >= 'a' && c|0x20 <= 'z' }
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// htmlDecorators maps inline tags to Portable Text decorators.
+var htmlDecorators = map[string]string{
+	"strong": "strong", "b": "strong",
+	"em": "em", "i": "em",
+	"code": "code",
+	"u":    "underline",
+	"s":    "strike-through", "strike": "strike-through", "del": "strike-through",
+}
+
+var htmlBlockStyles = map[string]string{
+	"p": "normal", "div": "normal",
+	"h1": "h1", "h2": "h2", "h3": "h3", "h4": "h4", "h5": "h5", "h6": "h6",
+}
+
+// parseHTML converts an HTML fragment to Portable Text. Paragraphs,
+// headings, blockquotes, nested lists, inline formatting, links, <pre>
+// code blocks and images are understood; other elements contribute their
+// text.
+func parseHTML(s string) portabletext.Document {
+	var b docBuilder
+	type openMark struct{ tag, mark string }
+	var marks []openMark
+	var lists []string
+	quote := 0
+	var pending *portabletext.Node // code block waiting for its text
+
+	active := func() []string {
+		if len(marks) == 0 {
+			return nil
+		}
+		out := make([]string, len(marks))
+		for i, m := range marks {
+			out[i] = m.mark
+		}
+		return out
+	}
+	paragraphStyle := func(style string) string {
+		if quote > 0 && style == "normal" {
+			return "blockquote"
+		}
+		return style
+	}
+
+	for _, t := range tokenizeHTML(s) {
+		if t.tag == "" {
+			if pending != nil {
+				pending.Raw["code"] = strings.TrimSuffix(strings.TrimPrefix(t.text, "\n"), "\n")
+				b.add(pending)
+				pending = nil
+				continue
+			}
+			text := collapseSpace(t.text)
+			if b.block == nil && strings.TrimSpace(text) == "" {
+				continue
+			}
+			if b.block == nil {
+				b.start(paragraphStyle("normal"), "", 0)
+			}
+			if prev := b.lastText(); (prev == "" || strings.HasSuffix(prev, " ") || strings.HasSuffix(prev, "\n")) && strings.HasPrefix(text, " ") {
+				text = text[1:]
+			}
+			b.text(text, active())
+			continue
+		}
+
+		switch tag := t.tag; {
+		case htmlBlockStyles[tag] != "":
+			inItem := b.block != nil && b.block.ListItem != nil
+			switch {
+			case inItem && t.end:
+				// </p> inside <li>: the item continues until </li>.
+			case inItem && len(b.block.Children) == 0:
+				// <li><p>: keep the list item.
+			case t.end:
+				b.flush()
+			default:
+				b.start(paragraphStyle(htmlBlockStyles[tag]), "", 0)
+			}
+		case tag == "blockquote":
+			b.flush()
+			if t.end {
+				quote = max(0, quote-1)
+			} else {
+				quote++
+			}
+		case tag == "ul" || tag == "ol":
+			b.flush()
+			if t.end {
+				if len(lists) > 0 {
+					lists = lists[:len(lists)-1]
+				}
+			} else if tag == "ol" {
+				lists = append(lists, "number")
+			} else {
+				lists = append(lists, "bullet")
+			}
+		case tag == "li":
+			if t.end {
+				b.flush()
+			} else if len(lists) > 0 {
+				b.start("normal", lists[len(lists)-1], len(lists))
+			} else {
+				b.start("normal", "bullet", 1)
+			}
+		case tag == "br":
+			if !t.end {
+				b.text("\n", active())
+			}
+		case tag == "pre":
+			if !t.end {
+				pending = portabletext.NewNode("code")
+			}
+		case tag == "code" && pending != nil:
+			if lang, ok := strings.CutPrefix(t.attrs["class"], "language-"); ok {
+				pending.Raw["language"] = lang
+			}
+		case tag == "img":
+			if src := t.attrs["src"]; src != "" {
+				img := portabletext.NewNode("image")
+				img.Raw["url"] = src
+				if alt := t.attrs["alt"]; alt != "" {
+					img.Raw["alt"] = alt
+				}
+				b.add(img)
+			}
+		case tag == "a" || htmlDecorators[tag] != "":
+			if t.end {
+				for i := len(marks) - 1; i >= 0; i-- {
+					if marks[i].tag == tag {
+						marks = append(marks[:i], marks[i+1:]...)
+						break
+					}
+				}
+				continue
+			}
+			mark := htmlDecorators[tag]
+			if tag == "a" {
+				if b.block == nil {
+					b.start(paragraphStyle("normal"), "", 0)
+				}
+				mark = b.link(t.attrs["href"])
+			}
+			marks = append(marks, openMark{tag, mark})
+		}
+	}
+	return b.document()
+}
+
+// collapseSpace replaces runs of HTML whitespace with a single space.
+func collapseSpace(s string) string {
+	var sb strings.Builder
+	space := false
+	for i := 0; i < len(s); i++ {
+		if isHTMLSpace(s[i]) {
+			space = true
+			continue
+		}
+		if space {
+			sb.WriteByte(' ')
+			space = false
+		}
+		sb.WriteByte(s[i])
+	}
+	if space {
+		sb.WriteByte(' ')
+	}
+	return sb.String()
+}
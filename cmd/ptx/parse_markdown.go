@@ -0,0 +1,331 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/derickschaefer/portabletext"
+)
+
+var (
+	mdHeading = regexp.MustCompile(`^ {0,3}(#{1,6})(?:\s+(.*?))?(?:\s+#+)?\s*$`)
+	mdQuote   = regexp.MustCompile(`^ {0,3}>\s?(.*)$`)
+	mdItem    = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	mdFence   = regexp.MustCompile("^ {0,3}(```+|~~~+)\\s*([\\w+-]*)")
+	mdImage   = regexp.MustCompile(`^\s*!\[([^\]]*)\]\(([^)\s]+)\)\s*$`)
+	mdRule    = regexp.MustCompile(`^ {0,3}([-*_])(\s*[-*_]){2,}\s*$`)
+)
+
+// mdBlock is a block whose lines are collected before inline parsing.
+type mdBlock struct {
+	style, listItem string
+	level           int
+	lines           []string
+}
+
+// parseMarkdown converts CommonMark-style Markdown to Portable Text: ATX
+// headings, paragraphs, blockquotes, nested bullet and numbered lists,
+// fenced code, images on their own line, emphasis, strong, strikethrough,
+// inline code, <u> and links. Anything else is kept as text.
+func parseMarkdown(s string) portabletext.Document {
+	var b docBuilder
+	var cur *mdBlock
+	var indents []int // indentation of each open list level
+
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		b.start(cur.style, cur.listItem, cur.level)
+		var text strings.Builder
+		for i, l := range cur.lines {
+			hard := strings.HasSuffix(l, "  ") || (strings.HasSuffix(l, `\`) && !strings.HasSuffix(l, `\\`))
+			l = strings.TrimSpace(l)
+			if hard {
+				l = strings.TrimSuffix(l, `\`)
+			}
+			text.WriteString(l)
+			if i < len(cur.lines)-1 {
+				if hard {
+					text.WriteString("\n")
+				} else {
+					text.WriteString(" ")
+				}
+			}
+		}
+		parseInline(&b, text.String(), nil)
+		b.flush()
+		cur = nil
+	}
+
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			flush()
+			indents = nil
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), m[1]); i++ {
+				code = append(code, lines[i])
+			}
+			n := portabletext.NewNode("code")
+			n.Raw["code"] = strings.Join(code, "\n")
+			if m[2] != "" {
+				n.Raw["language"] = m[2]
+			}
+			b.add(n)
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+
+		if m := mdItem.FindStringSubmatch(line); m != nil && !mdRule.MatchString(line) {
+			flush()
+			indent := len(strings.ReplaceAll(m[1], "\t", "    "))
+			for len(indents) > 0 && indents[len(indents)-1] > indent {
+				indents = indents[:len(indents)-1]
+			}
+			if len(indents) == 0 || indents[len(indents)-1] < indent {
+				indents = append(indents, indent)
+			}
+			kind := "bullet"
+			if m[2][0] >= '0' && m[2][0] <= '9' {
+				kind = "number"
+			}
+			cur = &mdBlock{style: "normal", listItem: kind, level: len(indents), lines: []string{m[3]}}
+			continue
+		}
+
+		if cur != nil && cur.listItem != "" && (line[0] == ' ' || line[0] == '\t') {
+			cur.lines = append(cur.lines, line)
+			continue
+		}
+
+		switch {
+		case mdRule.MatchString(line):
+			flush()
+			indents = nil
+		case mdHeading.MatchString(line):
+			flush()
+			indents = nil
+			m := mdHeading.FindStringSubmatch(line)
+			cur = &mdBlock{style: "h" + string(rune('0'+len(m[1]))), lines: []string{m[2]}}
+			flush()
+		case mdImage.MatchString(line):
+			flush()
+			indents = nil
+			m := mdImage.FindStringSubmatch(line)
+			img := portabletext.NewNode("image")
+			img.Raw["url"] = m[2]
+			if m[1] != "" {
+				img.Raw["alt"] = unescapeMarkdown(m[1])
+			}
+			b.add(img)
+		case mdQuote.MatchString(line):
+			text := mdQuote.FindStringSubmatch(line)[1]
+			if cur == nil || cur.style != "blockquote" {
+				flush()
+				indents = nil
+				cur = &mdBlock{style: "blockquote"}
+			}
+			cur.lines = append(cur.lines, text)
+		default:
+			if cur == nil || cur.listItem != "" || cur.style != "normal" {
+				flush()
+				indents = nil
+				cur = &mdBlock{style: "normal"}
+			}
+			cur.lines = append(cur.lines, line)
+		}
+	}
+	flush()
+	return b.document()
+}
+
+// parseInline writes the inline Markdown s to b with marks applied.
+func parseInline(b *docBuilder, s string, marks []string) {
+	var buf strings.Builder
+	emit := func() {
+		b.text(buf.String(), marks)
+		buf.Reset()
+	}
+	with := func(m string) []string { return append(slices.Clone(marks), m) }
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			buf.WriteByte(s[i+1])
+			i += 2
+			continue
+
+		case c == '`':
+			n := runLength(s, i)
+			delim := s[i : i+n]
+			if end := strings.Index(s[i+n:], delim); end >= 0 {
+				emit()
+				code := s[i+n : i+n+end]
+				if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+					code = code[1 : len(code)-1]
+				}
+				b.text(code, with("code"))
+				i += 2*n + end
+				continue
+			}
+			buf.WriteString(delim)
+			i += n
+			continue
+
+		case c == '[':
+			if text, href, next, ok := mdLink(s, i); ok {
+				emit()
+				parseInline(b, text, with(b.link(href)))
+				i = next
+				continue
+			}
+
+		case strings.HasPrefix(s[i:], "<u>"):
+			if end := strings.Index(s[i+3:], "</u>"); end >= 0 {
+				emit()
+				parseInline(b, s[i+3:i+3+end], with("underline"))
+				i += 3 + end + 4
+				continue
+			}
+
+		case (c == '*' || c == '_') && runLength(s, i) == 3 && canOpen(s, i, 3):
+			if end := findCloser(s, i+3, s[i:i+3]); end > i+3 {
+				emit()
+				parseInline(b, s[i+3:end], append(with("strong"), "em"))
+				i = end + 3
+				continue
+			}
+			buf.WriteString(s[i : i+3])
+			i += 3
+			continue
+
+		case c == '*' || c == '_' || c == '~':
+			n := min(runLength(s, i), 2)
+			mark := map[int]string{1: "em", 2: "strong"}[n]
+			if c == '~' {
+				mark = map[int]string{2: "strike-through"}[n]
+			}
+			if mark != "" && canOpen(s, i, n) {
+				if end := findCloser(s, i+n, s[i:i+n]); end > i+n {
+					emit()
+					parseInline(b, s[i+n:end], with(mark))
+					i = end + n
+					continue
+				}
+			}
+			buf.WriteString(s[i : i+runLength(s, i)])
+			i += runLength(s, i)
+			continue
+		}
+		buf.WriteByte(c)
+		i++
+	}
+	emit()
+}
+
+// mdLink parses "[text](href)" at s[i].
+func mdLink(s string, i int) (text, href string, next int, ok bool) {
+	depth := 0
+	for j := i; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				if j+1 >= len(s) || s[j+1] != '(' {
+					return "", "", 0, false
+				}
+				end := strings.IndexByte(s[j+2:], ')')
+				if end < 0 {
+					return "", "", 0, false
+				}
+				href = strings.TrimSpace(s[j+2 : j+2+end])
+				if sp := strings.IndexAny(href, " \t"); sp >= 0 {
+					href = href[:sp] // drop a "title"
+				}
+				return s[i+1 : j], strings.Trim(href, "<>"), j + 2 + end + 1, true
+			}
+		}
+	}
+	return "", "", 0, false
+}
+
+// canOpen reports whether the delimiter run at s[i:i+n] can open
+// emphasis: it must be followed by non-space, and "_" must not be inside a
+// word (snake_case).
+func canOpen(s string, i, n int) bool {
+	if i+n >= len(s) || s[i+n] == ' ' {
+		return false
+	}
+	return s[i] != '_' || i == 0 || !isWordByte(s[i-1])
+}
+
+// findCloser returns the index of the delimiter run exactly matching delim
+// that closes emphasis opened before from, or -1.
+func findCloser(s string, from int, delim string) int {
+	for j := from; j < len(s); {
+		switch {
+		case s[j] == '\\':
+			j += 2
+		case s[j] == '`':
+			n := runLength(s, j)
+			if end := strings.Index(s[j+n:], s[j:j+n]); end >= 0 {
+				j += 2*n + end
+			} else {
+				j += n
+			}
+		case s[j] == delim[0]:
+			n := runLength(s, j)
+			closes := n == len(delim) && s[j-1] != ' '
+			if closes && delim[0] == '_' && j+n < len(s) && isWordByte(s[j+n]) {
+				closes = false
+			}
+			if closes {
+				return j
+			}
+			j += n
+		default:
+			j++
+		}
+	}
+	return -1
+}
+
+func runLength(s string, i int) int {
+	n := 1
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || isASCIILetter(c) || c >= 0x80
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func unescapeMarkdown(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/derickschaefer/portabletext"
+)
+
+func runQuery(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("query", "selector [file|glob ...]", stderr)
+	fields := fs.String("fields", "", "comma-separated fields to print, e.g. text,asset._ref")
+	asJSON := fs.Bool("json", false, "print one JSON object per match")
+	count := fs.Bool("count", false, "print only the number of matches per file")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return 2
+	}
+	q, err := portabletext.Compile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "ptx query: %v\n", err)
+		return 2
+	}
+	var fieldList []string
+	if *fields != "" {
+		fieldList = strings.Split(*fields, ",")
+	}
+
+	inputs, err := readInputs(fs.Args()[1:], stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "ptx query: %v\n", err)
+		return 2
+	}
+
+	status := 0
+	enc := json.NewEncoder(stdout)
+	enc.SetEscapeHTML(false)
+	for _, in := range inputs {
+		doc, err := decodeInput(in)
+		if err != nil {
+			reportError(stderr, in.name, err)
+			status = 2
+			continue
+		}
+		matches := q.Select(doc)
+		if *count {
+			fmt.Fprintf(stdout, "%s: %d\n", in.name, len(matches))
+			continue
+		}
+		for _, m := range matches {
+			switch {
+			case *asJSON:
+				out := map[string]any{"file": in.name, "path": m.Path.String(), "type": m.Type()}
+				if fieldList != nil {
+					out["fields"] = m.Project(fieldList...)
+				}
+				enc.Encode(out)
+			case fieldList != nil:
+				vals := make([]string, len(fieldList))
+				for i, f := range fieldList {
+					if v, ok := m.Value(f); ok {
+						vals[i] = fmt.Sprint(v)
+					}
+				}
+				fmt.Fprintf(stdout, "%s:%s\t%s\n", in.name, m.Path, strings.Join(vals, "\t"))
+			default:
+				fmt.Fprintf(stdout, "%s:%s\t%s\t%s\n", in.name, m.Path, m.Type(), summarize(m))
+			}
+		}
+	}
+	return status
+}
+
+// summarize returns a short, one-line description of a match.
+func summarize(m portabletext.Match) string {
+	for _, f := range []string{"text", "href", "code", "alt", "asset._ref"} {
+		if v, ok := m.Value(f); ok {
+			if s := fmt.Sprint(v); s != "" {
+				return truncateText(strings.ReplaceAll(s, "\n", " "), 60)
+			}
+		}
+	}
+	return ""
+}
+
+func truncateText(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/derickschaefer/portabletext"
+)
+
+// renderer turns a document into one output format. Node types it cannot
+// represent are recorded in skipped.
+type renderer struct {
+	sb      strings.Builder
+	skipped map[string]int
+}
+
+func (r *renderer) skip(t string) {
+	if r.skipped == nil {
+		r.skipped = map[string]int{}
+	}
+	r.skipped[t]++
+}
+
+// markStyle describes how marks are written inline.
+type markStyle struct {
+	open  func(mark string, def *portabletext.MarkDef) string
+	close func(mark string, def *portabletext.MarkDef) string
+	text  func(text string, marks []string) string
+}
+
+// renderInline writes the spans of n with properly nested marks. Marks
+// shared by the longest run of following spans are opened outermost, so
+// "**bold _both_**" is produced rather than overlapping tags.
+func renderInline(n *portabletext.Node, st markStyle) string {
+	defs := map[string]*portabletext.MarkDef{}
+	for i := range n.MarkDefs {
+		defs[n.MarkDefs[i].Key] = &n.MarkDefs[i]
+	}
+	var spans []*portabletext.Span
+	for i := range n.Children {
+		if c := &n.Children[i]; c.Type == "span" && c.Text != nil {
+			spans = append(spans, c)
+		}
+	}
+
+	var sb strings.Builder
+	var stack []string
+	closeTo := func(keep int) {
+		for len(stack) > keep {
+			m := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			sb.WriteString(st.close(m, defs[m]))
+		}
+	}
+	for i, s := range spans {
+		keep := 0
+		for keep < len(stack) && s.HasMark(stack[keep]) {
+			keep++
+		}
+		closeTo(keep)
+
+		var open []string
+		for _, m := range s.Marks {
+			if !slices.Contains(stack, m) && !slices.Contains(open, m) {
+				open = append(open, m)
+			}
+		}
+		run := func(m string) int {
+			j := i
+			for j < len(spans) && spans[j].HasMark(m) {
+				j++
+			}
+			return j - i
+		}
+		slices.SortStableFunc(open, func(a, b string) int { return run(b) - run(a) })
+		for _, m := range open {
+			stack = append(stack, m)
+			sb.WriteString(st.open(m, defs[m]))
+		}
+		sb.WriteString(st.text(*s.Text, stack))
+	}
+	closeTo(0)
+	return sb.String()
+}
+
+func headingStyle(style string) int {
+	if len(style) == 2 && style[0] == 'h' && style[1] >= '1' && style[1] <= '6' {
+		return int(style[1] - '0')
+	}
+	return 0
+}
+
+// linkHref returns the href of a link markDef, unless it is missing or
+// uses a scheme such as javascript: that must not reach the output.
+func linkHref(def *portabletext.MarkDef) (string, bool) {
+	href, ok := def.Raw["href"].(string)
+	if !ok || def.Type != "link" || portabletext.IsUnsafeHref(href) {
+		return "", false
+	}
+	return href, true
+}
+
+// listState tracks open lists while rendering consecutive list items.
+type listState struct {
+	kinds   []string // listItem of each open level
+	numbers []int    // next number at each level
+}
+
+// enter positions the state at n's level. It returns the kinds of the
+// lists it closed, innermost first, and whether a new list was opened.
+func (ls *listState) enter(n *portabletext.Node) (closed []string, opened bool) {
+	level, kind := max(n.GetListLevel(), 1), *n.ListItem
+	for len(ls.kinds) > level || (len(ls.kinds) == level && ls.kinds[level-1] != kind) {
+		closed = append(closed, ls.kinds[len(ls.kinds)-1])
+		ls.kinds = ls.kinds[:len(ls.kinds)-1]
+		ls.numbers = ls.numbers[:len(ls.numbers)-1]
+	}
+	if len(ls.kinds) < level {
+		ls.kinds = append(ls.kinds, kind)
+		ls.numbers = append(ls.numbers, 1)
+		opened = true
+	}
+	return closed, opened
+}
+
+// leave closes every open list, returning their kinds innermost first.
+func (ls *listState) leave() []string {
+	closed := slices.Clone(ls.kinds)
+	slices.Reverse(closed)
+	*ls = listState{}
+	return closed
+}
+
+func (ls *listState) depth() int { return len(ls.kinds) }
+
+//
+// HTML
+//
+
+var htmlMarks = map[string]string{
+	"strong":         "strong",
+	"em":             "em",
+	"code":           "code",
+	"underline":      "u",
+	"strike-through": "s",
+}
+
+func renderHTML(doc portabletext.Document) (string, map[string]int) {
+	var r renderer
+	st := markStyle{
+		open: func(m string, def *portabletext.MarkDef) string {
+			if def != nil {
+				if href, ok := linkHref(def); ok {
+					return `<a href="` + html.EscapeString(href) + `">`
+				}
+				return `<span class="` + html.EscapeString(def.Type) + `">`
+			}
+			if tag, ok := htmlMarks[m]; ok {
+				return "<" + tag + ">"
+			}
+			return `<span class="` + html.EscapeString(m) + `">`
+		},
+		close: func(m string, def *portabletext.MarkDef) string {
+			if def != nil {
+				if _, ok := linkHref(def); ok {
+					return "</a>"
+				}
+				return "</span>"
+			}
+			if tag, ok := htmlMarks[m]; ok {
+				return "</" + tag + ">"
+			}
+			return "</span>"
+		},
+		text: func(t string, _ []string) string {
+			return strings.ReplaceAll(html.EscapeString(t), "\n", "<br>")
+		},
+	}
+
+	slugs := portabletext.NewSlugger(portabletext.SlugOptions{AnchorField: portabletext.DefaultAnchorField})
+	slugs.ReserveAnchors(doc)
+	var ls listState
+	closeLists := func(kinds []string) {
+		for _, k := range kinds {
+			r.sb.WriteString("</li>\n</" + listTag(k) + ">\n")
+		}
+	}
+	for i := range doc {
+		n := &doc[i]
+		if n.IsBlock() && n.ListItem != nil {
+			closed, opened := ls.enter(n)
+			closeLists(closed)
+			if opened {
+				r.sb.WriteString("<" + listTag(*n.ListItem) + ">\n")
+			} else {
+				r.sb.WriteString("</li>\n")
+			}
+			r.sb.WriteString("<li>" + renderInline(n, st))
+			continue
+		}
+		closeLists(ls.leave())
+
+		switch {
+		case n.IsBlock():
+			tag, attrs := "p", ""
+			if h := headingStyle(n.GetStyle()); h > 0 {
+				tag = fmt.Sprintf("h%d", h)
+				attrs = ` id="` + html.EscapeString(slugs.Slug(n)) + `"`
+			} else if n.GetStyle() == "blockquote" {
+				tag = "blockquote"
+			}
+			r.sb.WriteString("<" + tag + attrs + ">" + renderInline(n, st) + "</" + tag + ">\n")
+		case n.Type == "code":
+			code, _ := n.Raw["code"].(string)
+			class := ""
+			if lang, _ := n.Raw["language"].(string); lang != "" {
+				class = ` class="language-` + html.EscapeString(lang) + `"`
+			}
+			r.sb.WriteString("<pre><code" + class + ">" + html.EscapeString(code) + "</code></pre>\n")
+		case n.Type == "image" && rawString(n.Raw, "url") != "":
+			r.sb.WriteString(`<img src="` + html.EscapeString(rawString(n.Raw, "url")) +
+				`" alt="` + html.EscapeString(rawString(n.Raw, "alt")) + `">` + "\n")
+		default:
+			r.skip(n.Type)
+		}
+	}
+	closeLists(ls.leave())
+	return r.sb.String(), r.skipped
+}
+
+func listTag(kind string) string {
+	if kind == "number" {
+		return "ol"
+	}
+	return "ul"
+}
+
+func rawString(raw map[string]any, key string) string {
+	s, _ := raw[key].(string)
+	return s
+}
+
+//
+// Markdown
+//
+
+var mdEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`, "~", `\~`)
+
+// Paragraph lines that would otherwise start a heading, quote or list.
+var (
+	mdLineStart   = regexp.MustCompile(`(?m)^(\s*)(#|>|[-+](?:\s|$))`)
+	mdNumberStart = regexp.MustCompile(`(?m)^(\s*\d+)\.(\s|$)`)
+)
+
+var mdMarks = map[string]string{
+	"strong":         "**",
+	"em":             "_",
+	"code":           "`",
+	"strike-through": "~~",
+	"underline":      "<u>",
+}
+
+func renderMarkdown(doc portabletext.Document) (string, map[string]int) {
+	var r renderer
+	st := markStyle{
+		open: func(m string, def *portabletext.MarkDef) string {
+			if def != nil {
+				if def.Type == "link" {
+					return "["
+				}
+				return ""
+			}
+			return mdMarks[m]
+		},
+		close: func(m string, def *portabletext.MarkDef) string {
+			if def != nil {
+				if href, ok := linkHref(def); ok {
+					return "](" + strings.ReplaceAll(href, " ", "%20") + ")"
+				}
+				if def.Type == "link" {
+					return "]()"
+				}
+				return ""
+			}
+			if m == "underline" {
+				return "</u>"
+			}
+			return mdMarks[m]
+		},
+		text: func(t string, marks []string) string {
+			if !slices.Contains(marks, "code") {
+				t = mdEscaper.Replace(t)
+			}
+			return strings.ReplaceAll(t, "\n", "  \n")
+		},
+	}
+
+	var ls listState
+	inList := false
+	sep := func() {
+		if r.sb.Len() > 0 {
+			r.sb.WriteString("\n")
+		}
+	}
+	for i := range doc {
+		n := &doc[i]
+		if n.IsBlock() && n.ListItem != nil {
+			if !inList {
+				sep()
+			}
+			inList = true
+			_, opened := ls.enter(n)
+			lvl := ls.depth() - 1
+			if !opened {
+				ls.numbers[lvl]++
+			}
+			marker := "-"
+			if *n.ListItem == "number" {
+				marker = fmt.Sprintf("%d.", ls.numbers[lvl])
+			}
+			r.sb.WriteString(strings.Repeat("    ", lvl) + marker + " " + renderInline(n, st) + "\n")
+			continue
+		}
+		inList = false
+		ls = listState{}
+
+		var out string
+		switch {
+		case n.IsBlock():
+			text := renderInline(n, st)
+			if h := headingStyle(n.GetStyle()); h > 0 {
+				out = strings.Repeat("#", h) + " " + text
+			} else if n.GetStyle() == "blockquote" {
+				out = "> " + strings.ReplaceAll(text, "\n", "\n> ")
+			} else {
+				text = mdLineStart.ReplaceAllString(text, `$1\$2`)
+				out = mdNumberStart.ReplaceAllString(text, `$1\.$2`)
+			}
+		case n.Type == "code":
+			code, _ := n.Raw["code"].(string)
+			lang, _ := n.Raw["language"].(string)
+			out = "```" + lang + "\n" + code + "\n```"
+		case n.Type == "image" && rawString(n.Raw, "url") != "":
+			out = "![" + mdEscaper.Replace(rawString(n.Raw, "alt")) + "](" + rawString(n.Raw, "url") + ")"
+		default:
+			r.skip(n.Type)
+			continue
+		}
+		sep()
+		r.sb.WriteString(out + "\n")
+	}
+	return r.sb.String(), r.skipped
+}
+
+//
+// Plain text
+//
+
+func renderText(doc portabletext.Document) (string, map[string]int) {
+	var r renderer
+	var ls listState
+	inList := false
+	for i := range doc {
+		n := &doc[i]
+		if n.IsBlock() && n.ListItem != nil {
+			if !inList && r.sb.Len() > 0 {
+				r.sb.WriteString("\n")
+			}
+			inList = true
+			_, opened := ls.enter(n)
+			lvl := ls.depth() - 1
+			if !opened {
+				ls.numbers[lvl]++
+			}
+			marker := "-"
+			if *n.ListItem == "number" {
+				marker = fmt.Sprintf("%d.", ls.numbers[lvl])
+			}
+			r.sb.WriteString(strings.Repeat("  ", lvl) + marker + " " + n.GetText() + "\n")
+			continue
+		}
+		inList = false
+		ls = listState{}
+
+		var text string
+		switch {
+		case n.IsBlock():
+			text = n.GetText()
+		case n.Type == "code":
+			text = rawString(n.Raw, "code")
+		default:
+			r.skip(n.Type)
+			continue
+		}
+		if r.sb.Len() > 0 {
+			r.sb.WriteString("\n")
+		}
+		r.sb.WriteString(text + "\n")
+	}
+	return r.sb.String(), r.skipped
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/derickschaefer/portabletext"
+)
+
+type fileStats struct {
+	File string `json:"file"`
+	portabletext.DocStats
+}
+
+func runStats(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("stats", "[file|glob ...]", stderr)
+	asJSON := fs.Bool("json", false, "print statistics as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	inputs, err := readInputs(fs.Args(), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "ptx stats: %v\n", err)
+		return 2
+	}
+
+	status := 0
+	all := []fileStats{}
+	for _, in := range inputs {
+		doc, err := decodeInput(in)
+		if err != nil {
+			reportError(stderr, in.name, err)
+			status = 2
+			continue
+		}
+		all = append(all, fileStats{File: in.name, DocStats: portabletext.Stats(doc)})
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(all)
+		return status
+	}
+	for _, s := range all {
+		fmt.Fprintf(stdout, "%s\n", s.File)
+		fmt.Fprintf(stdout, "  nodes       %d\n", s.Nodes)
+		fmt.Fprintf(stdout, "  blocks      %d\n", s.Blocks)
+		fmt.Fprintf(stdout, "  headings    %d\n", s.Headings)
+		fmt.Fprintf(stdout, "  list items  %d\n", s.ListItems)
+		fmt.Fprintf(stdout, "  spans       %d\n", s.Spans)
+		fmt.Fprintf(stdout, "  words       %d\n", s.Words)
+		fmt.Fprintf(stdout, "  characters  %d\n", s.Characters)
+		fmt.Fprintf(stdout, "  links       %d\n", s.Links)
+		fmt.Fprintf(stdout, "  images      %d\n", s.Images)
+		fmt.Fprintf(stdout, "  reading     %s\n", s.ReadingTime)
+		if s.Words > 0 {
+			fmt.Fprintf(stdout, "  ease        %.1f\n", s.FleschReadingEase)
+			fmt.Fprintf(stdout, "  grade       %.1f\n", s.FleschKincaidGrade)
+		}
+		for _, t := range sortedCounts(s.Types) {
+			fmt.Fprintf(stdout, "  %-11s %d\n", t+":", s.Types[t])
+		}
+	}
+	return status
+}
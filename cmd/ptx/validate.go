@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/derickschaefer/portabletext"
+)
+
+type problem struct {
+	File    string `json:"file"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func runValidate(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("validate", "[file|glob ...]", stderr)
+	var opts portabletext.ValidationOptions
+	fs.BoolVar(&opts.RequireKeys, "require-keys", false, "require _key on every block")
+	fs.BoolVar(&opts.CheckMarkDefRefs, "check-marks", false, "require every mark to have a markDef")
+	fs.BoolVar(&opts.AllowEmptyText, "allow-empty-text", false, "allow spans with empty text")
+	format := fs.String("format", "text", "output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "ptx validate: unknown format %q\n", *format)
+		return 2
+	}
+
+	inputs, err := readInputs(fs.Args(), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "ptx validate: %v\n", err)
+		return 2
+	}
+
+	problems := []problem{}
+	for _, in := range inputs {
+		doc, err := decodeInput(in)
+		if err != nil {
+			problems = append(problems, toProblem(in.name, err))
+			continue
+		}
+		for _, err := range portabletext.ValidateWithOptions(doc, opts) {
+			problems = append(problems, toProblem(in.name, err))
+		}
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(problems)
+	} else {
+		for _, p := range problems {
+			printProblem(stdout, p)
+		}
+	}
+	if len(problems) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func toProblem(name string, err error) problem {
+	var pe *portabletext.Error
+	var ve *portabletext.ValidationError
+	switch {
+	case errors.As(err, &ve):
+		return problem{File: name, Path: ve.Path, Message: ve.Message}
+	case errors.As(err, &pe):
+		return problem{File: name, Path: pe.Path, Message: pe.Err.Error()}
+	}
+	return problem{File: name, Message: err.Error()}
+}
+
+func printProblem(w io.Writer, p problem) {
+	if p.Path != "" {
+		fmt.Fprintf(w, "%s:%s: %s\n", p.File, p.Path, p.Message)
+	} else {
+		fmt.Fprintf(w, "%s: %s\n", p.File, p.Message)
+	}
+}
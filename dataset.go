@@ -0,0 +1,407 @@
+package portabletext
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNoExportData is returned when a tar archive has no data.ndjson entry.
+var ErrNoExportData = errors.New("no data.ndjson in archive")
+
+// ExportOptions controls which documents and fields an ExportReader
+// decodes.
+type ExportOptions struct {
+	// Fields are the Portable Text field paths to decode, in dotted form
+	// with [] for "every element", e.g. "body", "seo.summary" or
+	// "sections[].content". If empty, fields are found with
+	// FindPortableText.
+	Fields []string
+
+	// Types limits decoding to documents with one of these _type values.
+	// Other documents are still returned, with no Fields.
+	Types []string
+}
+
+// ExportDocument is one document from a dataset export.
+type ExportDocument struct {
+	ID     string
+	Type   string
+	Line   int            // 1-based line in data.ndjson
+	Raw    map[string]any // the whole document, numbers as json.Number
+	Fields []*ExportField
+}
+
+// ExportField is a Portable Text field decoded from an ExportDocument.
+// Located.Path is the concrete field path, e.g. "sections[2].content".
+type ExportField struct {
+	ID string // _id of the owning document
+	Located
+}
+
+// Sync stores every field's Doc back into d.Raw, so edits made to the
+// decoded documents are written out by ExportWriter.
+func (d *ExportDocument) Sync() error {
+	for _, f := range d.Fields {
+		if err := f.WriteBack(); err != nil {
+			return wrap("export", d.ID, err)
+		}
+	}
+	return nil
+}
+
+// documentValue converts doc to the generic JSON form used in Raw.
+func documentValue(doc Document) ([]any, error) {
+	if doc == nil {
+		doc = Document{}
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var out []any
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ExportReader streams documents from the output of `sanity dataset
+// export`: plain NDJSON, gzipped NDJSON, or a tar.gz archive containing
+// data.ndjson (asset files in the archive are skipped).
+type ExportReader struct {
+	r     *bufio.Reader
+	opts  ExportOptions
+	types map[string]bool
+	line  int
+	close func() error
+}
+
+// NewExportReader detects the input format and positions the reader at
+// the first document.
+func NewExportReader(r io.Reader, opts ExportOptions) (*ExportReader, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	er := &ExportReader{opts: opts, close: func() error { return nil }}
+	if len(opts.Types) > 0 {
+		er.types = map[string]bool{}
+		for _, t := range opts.Types {
+			er.types[t] = true
+		}
+	}
+
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, wrap("export", "", err)
+		}
+		er.close = zr.Close
+		br = bufio.NewReaderSize(zr, 64*1024)
+	}
+
+	if hdr, _ := br.Peek(262); len(hdr) == 262 && string(hdr[257:262]) == "ustar" {
+		tr := tar.NewReader(br)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				return nil, wrap("export", "", ErrNoExportData)
+			}
+			if err != nil {
+				return nil, wrap("export", "", err)
+			}
+			if h.Typeflag == tar.TypeReg && path.Base(h.Name) == "data.ndjson" {
+				br = bufio.NewReaderSize(tr, 64*1024)
+				break
+			}
+		}
+	}
+
+	er.r = br
+	return er, nil
+}
+
+// Next returns the next document, or io.EOF when the export is exhausted.
+// Malformed lines and fields fail with an *Error naming the line or the
+// document ID and field path.
+func (er *ExportReader) Next() (*ExportDocument, error) {
+	for {
+		b, err := er.r.ReadBytes('\n')
+		if len(b) == 0 && err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, wrap("export", "", err)
+		}
+		er.line++
+		b = bytes.TrimSpace(b)
+		if len(b) == 0 {
+			continue
+		}
+
+		obj, perr := decodeObjectUseNumber(b)
+		if perr != nil {
+			return nil, wrap("export", fmt.Sprintf("line %d", er.line), perr)
+		}
+		d := &ExportDocument{Line: er.line, Raw: obj}
+		d.ID, _ = obj["_id"].(string)
+		d.Type, _ = obj["_type"].(string)
+		if er.types == nil || er.types[d.Type] {
+			if err := er.decodeFields(d); err != nil {
+				return nil, err
+			}
+		}
+		return d, nil
+	}
+}
+
+// Close releases the gzip reader, if any. It does not close the
+// underlying reader.
+func (er *ExportReader) Close() error {
+	return er.close()
+}
+
+func (er *ExportReader) decodeFields(d *ExportDocument) error {
+	if len(er.opts.Fields) == 0 {
+		for _, l := range FindPortableText(d.Raw) {
+			d.Fields = append(d.Fields, &ExportField{ID: d.ID, Located: l})
+		}
+		return nil
+	}
+	for _, sel := range er.opts.Fields {
+		var err error
+		selectField(d.Raw, strings.Split(sel, "."), "", func(p string, arr []any, set func(any)) {
+			if err != nil {
+				return
+			}
+			doc, derr := decodeArray(arr, p)
+			if derr != nil {
+				err = wrap("export", fmt.Sprintf("line %d (%s)", d.Line, d.ID), derr)
+				return
+			}
+			d.Fields = append(d.Fields, &ExportField{ID: d.ID, Located: Located{Path: p, Doc: doc, set: set}})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeArray decodes a generic JSON array into a Document, reporting
+// errors at paths below p.
+func decodeArray(arr []any, p string) (Document, error) {
+	doc := make(Document, 0, len(arr))
+	for i, v := range arr {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, wrap("node", fmt.Sprintf("%s[%d]", p, i), err)
+		}
+		n, err := parseNode(b, fmt.Sprintf("%s[%d]", p, i))
+		if err != nil {
+			return nil, err
+		}
+		doc = append(doc, n)
+	}
+	return doc, nil
+}
+
+// selectField resolves a dotted field selector against obj, calling fn for
+// every array it reaches. Missing fields are skipped.
+func selectField(v any, segs []string, p string, fn func(p string, arr []any, set func(any))) {
+	if len(segs) == 0 {
+		return
+	}
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return
+	}
+	name, each := strings.CutSuffix(segs[0], "[]")
+	val, ok := obj[name]
+	if !ok {
+		return
+	}
+	cp := joinFieldPath(p, name)
+
+	if !each {
+		if len(segs) == 1 {
+			if arr, ok := val.([]any); ok {
+				fn(cp, arr, func(nv any) { obj[name] = nv })
+			}
+			return
+		}
+		selectField(val, segs[1:], cp, fn)
+		return
+	}
+
+	arr, ok := val.([]any)
+	if !ok {
+		return
+	}
+	for i, e := range arr {
+		ep := cp + "[" + strconv.Itoa(i) + "]"
+		if len(segs) == 1 {
+			if inner, ok := e.([]any); ok {
+				fn(ep, inner, func(nv any) { arr[i] = nv })
+			}
+			continue
+		}
+		selectField(e, segs[1:], ep, fn)
+	}
+}
+
+func joinFieldPath(p, name string) string {
+	if p == "" {
+		return name
+	}
+	return p + "." + name
+}
+
+// ExportWriter writes documents back out as NDJSON.
+type ExportWriter struct {
+	w *bufio.Writer
+}
+
+// NewExportWriter returns a writer that emits one document per line.
+// Call Flush when done.
+func NewExportWriter(w io.Writer) *ExportWriter {
+	return &ExportWriter{w: bufio.NewWriter(w)}
+}
+
+// Write syncs d's fields into d.Raw and writes it as one line.
+func (ew *ExportWriter) Write(d *ExportDocument) error {
+	if err := d.Sync(); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(d.Raw); err != nil {
+		return wrap("export", d.ID, err)
+	}
+	_, err := ew.w.Write(buf.Bytes())
+	return err
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (ew *ExportWriter) Flush() error {
+	return ew.w.Flush()
+}
+
+// ProcessExport reads every document from r, calls fn on up to workers
+// documents at a time and, if w is not nil, writes the results to w as
+// NDJSON in input order. Memory is bounded by the number of documents in
+// flight, not by the size of the export. The first error from reading, fn
+// or writing cancels the rest and is returned.
+func ProcessExport(ctx context.Context, r io.Reader, w io.Writer, opts ExportOptions, workers int, fn func(context.Context, *ExportDocument) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	er, err := NewExportReader(r, opts)
+	if err != nil {
+		return err
+	}
+	defer er.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type job struct {
+		doc  *ExportDocument
+		err  error
+		done chan struct{}
+	}
+	jobs := make(chan *job)
+	ordered := make(chan *job, 2*workers)
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+		cancel()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if ctx.Err() == nil {
+					j.err = fn(ctx, j.doc)
+				}
+				close(j.done)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(ordered)
+		defer close(jobs)
+		for ctx.Err() == nil {
+			d, err := er.Next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				fail(err)
+				return
+			}
+			j := &job{doc: d, done: make(chan struct{})}
+			select {
+			case ordered <- j:
+			case <-ctx.Done():
+				return
+			}
+			jobs <- j
+		}
+	}()
+
+	var ew *ExportWriter
+	if w != nil {
+		ew = NewExportWriter(w)
+	}
+	for j := range ordered {
+		<-j.done
+		if ctx.Err() != nil {
+			continue
+		}
+		if j.err != nil {
+			fail(wrap("export", j.doc.ID, j.err))
+			continue
+		}
+		if ew != nil {
+			if err := ew.Write(j.doc); err != nil {
+				fail(err)
+			}
+		}
+	}
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	if ew != nil {
+		return ew.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package portabletext
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const exportNDJSON = `{"_id":"post-1","_type":"post","title":"One","body":[{"_type":"block","children":[{"_type":"span","text":"Hello"}]}],"sections":[{"content":[{"_type":"block","children":[{"_type":"span","text":"Nested"}]}]}]}
+{"_id":"author-1","_type":"author","bio":[{"_type":"block","children":[{"_type":"span","text":"Bio"}]}],"tags":["a","b"]}
+
+{"_id":"post-2","_type":"post","title":"Two","body":[]}
+`
+
+func readAllExport(t *testing.T, r io.Reader, opts ExportOptions) []*ExportDocument {
+	t.Helper()
+	er, err := NewExportReader(r, opts)
+	if err != nil {
+		t.Fatalf("NewExportReader() error = %v", err)
+	}
+	defer er.Close()
+	var docs []*ExportDocument
+	for {
+		d, err := er.Next()
+		if err == io.EOF {
+			return docs
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		docs = append(docs, d)
+	}
+}
+
+func fieldPaths(docs []*ExportDocument) string {
+	var out []string
+	for _, d := range docs {
+		for _, f := range d.Fields {
+			var text []string
+			for i := range f.Doc {
+				text = append(text, f.Doc[i].GetText())
+			}
+			out = append(out, f.ID+":"+f.Path+"="+strings.Join(text, "|"))
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func TestExportReaderAutoDetect(t *testing.T) {
+	docs := readAllExport(t, strings.NewReader(exportNDJSON), ExportOptions{})
+	if len(docs) != 3 {
+		t.Fatalf("got %d documents, want 3", len(docs))
+	}
+	if docs[2].Line != 4 || docs[2].ID != "post-2" {
+		t.Errorf("third document = line %d id %q, want line 4 post-2", docs[2].Line, docs[2].ID)
+	}
+	want := "post-1:body=Hello post-1:sections[0].content=Nested author-1:bio=Bio"
+	if got := fieldPaths(docs); got != want {
+		t.Errorf("fields = %s\nwant %s", got, want)
+	}
+}
+
+func TestExportReaderFieldsAndTypes(t *testing.T) {
+	docs := readAllExport(t, strings.NewReader(exportNDJSON), ExportOptions{
+		Fields: []string{"sections[].content", "bio", "missing.field"},
+		Types:  []string{"post"},
+	})
+	if got := fieldPaths(docs); got != "post-1:sections[0].content=Nested" {
+		t.Errorf("fields = %s", got)
+	}
+}
+
+func TestExportReaderTarGz(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, f := range []struct{ name, body string }{
+		{"export/images/abc.png", "\x89PNG"},
+		{"export/data.ndjson", exportNDJSON},
+	} {
+		tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.body)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(f.body))
+	}
+	tw.Close()
+	zw.Close()
+
+	docs := readAllExport(t, &buf, ExportOptions{Fields: []string{"body"}})
+	if len(docs) != 3 || fieldPaths(docs) != "post-1:body=Hello post-2:body=" {
+		t.Errorf("tar.gz export: %d docs, fields %s", len(docs), fieldPaths(docs))
+	}
+
+	var gz bytes.Buffer
+	zw = gzip.NewWriter(&gz)
+	zw.Write([]byte(exportNDJSON))
+	zw.Close()
+	if docs := readAllExport(t, &gz, ExportOptions{}); len(docs) != 3 {
+		t.Errorf("gzipped NDJSON: got %d docs, want 3", len(docs))
+	}
+}
+
+func TestExportReaderErrors(t *testing.T) {
+	er, _ := NewExportReader(strings.NewReader("{\"_id\":\"a\"}\nnot json\n"), ExportOptions{})
+	er.Next()
+	_, err := er.Next()
+	var pe *Error
+	if !errors.As(err, &pe) || pe.Path != "line 2" {
+		t.Errorf("Next() error = %v, want *Error at line 2", err)
+	}
+
+	er, _ = NewExportReader(strings.NewReader(`{"_id":"x","body":[{"_type":"block"},{"text":"no type"}]}`), ExportOptions{Fields: []string{"body"}})
+	_, err = er.Next()
+	if !errors.Is(err, ErrMissingType) || !strings.Contains(err.Error(), "body[1]") {
+		t.Errorf("Next() error = %v, want ErrMissingType at body[1]", err)
+	}
+}
+
+func TestExportWriteBack(t *testing.T) {
+	docs := readAllExport(t, strings.NewReader(exportNDJSON), ExportOptions{})
+	f := docs[0].Fields[1]
+	*f.Doc[0].Children[0].Text = "Changed"
+
+	var out bytes.Buffer
+	ew := NewExportWriter(&out)
+	for _, d := range docs {
+		if err := ew.Write(d); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	ew.Flush()
+
+	again := readAllExport(t, &out, ExportOptions{})
+	want := "post-1:body=Hello post-1:sections[0].content=Changed author-1:bio=Bio"
+	if got := fieldPaths(again); got != want {
+		t.Errorf("after write back = %s\nwant %s", got, want)
+	}
+	if again[0].Raw["title"] != "One" {
+		t.Error("write back lost non-Portable Text fields")
+	}
+}
+
+func TestProcessExport(t *testing.T) {
+	var in strings.Builder
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&in, `{"_id":"d%d","body":[{"_type":"block","children":[{"_type":"span","text":"t%d"}]}]}`+"\n", i, i)
+	}
+
+	var calls atomic.Int32
+	var out bytes.Buffer
+	err := ProcessExport(context.Background(), strings.NewReader(in.String()), &out, ExportOptions{}, 4,
+		func(ctx context.Context, d *ExportDocument) error {
+			calls.Add(1)
+			s := strings.ToUpper(*d.Fields[0].Doc[0].Children[0].Text)
+			d.Fields[0].Doc[0].Children[0].Text = &s
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("ProcessExport() error = %v", err)
+	}
+	if calls.Load() != 50 {
+		t.Errorf("fn called %d times, want 50", calls.Load())
+	}
+	docs := readAllExport(t, &out, ExportOptions{})
+	for i, d := range docs {
+		if d.ID != fmt.Sprintf("d%d", i) || d.Fields[0].Doc[0].GetText() != fmt.Sprintf("T%d", i) {
+			t.Fatalf("output[%d] = %s %q, want d%d T%d", i, d.ID, d.Fields[0].Doc[0].GetText(), i, i)
+		}
+	}
+
+	boom := errors.New("boom")
+	err = ProcessExport(context.Background(), strings.NewReader(in.String()), nil, ExportOptions{}, 3,
+		func(ctx context.Context, d *ExportDocument) error {
+			if d.ID == "d7" {
+				return boom
+			}
+			return nil
+		})
+	var pe *Error
+	if !errors.Is(err, boom) || !errors.As(err, &pe) || pe.Path != "d7" {
+		t.Errorf("ProcessExport() error = %v, want boom at d7", err)
+	}
+}
@@ -71,6 +71,21 @@ Advanced validation with custom options:
 	}
 	errs := portabletext.ValidateWithOptions(doc, opts)
 
+# Linting
+
+A Linter runs named rules with severities and optional fixes. Rules can be
+added with Register and toggled from a JSON config file:
+
+	l := portabletext.NewLinter()
+	cfg, _ := portabletext.LoadLintConfig(configFile) // {"rules":{"double-space":"off"}}
+	if err := l.Configure(cfg); err != nil {
+		log.Fatal(err)
+	}
+	for _, f := range l.Lint(doc) {
+		fmt.Println(f) // [0].children[1]: span has empty text (warning, empty-text)
+	}
+	fixed, report := l.Fix(doc)
+
 # Traversal
 
 Walk all nodes:
@@ -87,6 +102,15 @@ Walk with context information:
 		return nil
 	})
 
+Range over nodes, spans and mark definitions with iterators (Go 1.23+):
+
+	for path, span := range doc.Spans() {
+		fmt.Println(path, *span.Text)
+	}
+	for path, link := range doc.Links() {
+		fmt.Println(path, link.Raw["href"])
+	}
+
 # Filtering and Transformation
 
 Filter nodes by predicate:
@@ -105,6 +129,208 @@ Transform nodes (returns new document):
 		return n
 	})
 
+Use TransformE for fallible work, cancellation, or expanding one node into
+several; TransformConcurrent runs the same function with bounded parallelism:
+
+	out, err := portabletext.TransformE(ctx, doc, func(ctx context.Context, n *portabletext.Node) ([]portabletext.Node, error) {
+		if n.Type == "divider" {
+			return nil, nil // drop
+		}
+		return []portabletext.Node{*n}, nil
+	})
+
+TransformSpans rewrites the children of every node, receiving the owning node:
+
+	out := portabletext.TransformSpans(doc, func(parent *portabletext.Node, s *portabletext.Span) []portabletext.Span {
+		s.Marks = append(s.Marks, "em")
+		return []portabletext.Span{*s}
+	})
+
+# Outlines
+
+Group blocks under their headings (h1–h6) into a section tree:
+
+	root := portabletext.Outline(doc)
+	root.Walk(func(s *portabletext.Section) error {
+		if s.Heading == nil {
+			return nil // root
+		}
+		fmt.Printf("%s- %s (#%s)\n", strings.Repeat("  ", s.Level), s.Title(), s.Slug)
+		return nil
+	})
+	install := root.Find("installation").Document()
+
+# Queries
+
+Select nodes, spans and mark definitions with a CSS-like selector:
+
+	matches, err := portabletext.Select(doc, "block[style=h2] > span[marks~=strong]")
+	for _, m := range matches {
+		fmt.Println(m.Path, *m.Span.Text)
+	}
+
+Compile once and reuse across documents; project Raw fields by dotted path:
+
+	q := portabletext.MustCompile("image[asset._ref]")
+	for _, m := range q.Select(doc) {
+		fmt.Println(m.Project("asset._ref", "alt"))
+	}
+
+# Finding Portable Text
+
+Locate every Portable Text field in a whole CMS document and write edits
+back in place:
+
+	var page map[string]any // decoded with json.Decoder.UseNumber
+	for _, l := range portabletext.FindPortableText(page) {
+		l.Doc = portabletext.AddAnchors(l.Doc, portabletext.SlugOptions{})
+		if err := l.WriteBack(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+# Dataset Exports
+
+Process a `sanity dataset export` file, rewriting Portable Text fields in
+parallel while keeping the output in input order:
+
+	err := portabletext.ProcessExport(ctx, in, out, portabletext.ExportOptions{}, 8,
+		func(ctx context.Context, d *portabletext.ExportDocument) error {
+			for _, f := range d.Fields {
+				f.Doc = portabletext.AutoLink(f.Doc, portabletext.AutoLinkOptions{URLs: true})
+			}
+			return nil
+		})
+
+# Images
+
+Build CDN URLs for image nodes, with crop and hotspot applied:
+
+	b := portabletext.ImageURLBuilder{ProjectID: "abc123", Dataset: "production"}
+	img, err := portabletext.ParseImage(node.Raw)
+	if err != nil {
+		log.Fatal(err)
+	}
+	thumb := b.URL(img, portabletext.ImageParams{Width: 400, Height: 400, Format: "auto"})
+	r := b.Responsive(img, portabletext.ImageParams{Format: "auto"}, portabletext.SrcSetOptions{MaxWidth: 800})
+	fmt.Printf(`<img src="%s" srcset="%s" sizes="%s">`, r.Src, r.SrcSet, r.Sizes)
+
+# Statistics
+
+Count words, links and marks, and score readability:
+
+	st := portabletext.Stats(doc)
+	fmt.Printf("%d words, %s read, Flesch %.0f\n", st.Words, st.ReadingTime, st.FleschReadingEase)
+
+Per-section figures come from the outline:
+
+	portabletext.Outline(doc).Walk(func(s *portabletext.Section) error {
+		fmt.Println(s.Title(), s.Stats().Words)
+		return nil
+	})
+
+# Excerpts
+
+Cut a document down to a teaser without losing formatting:
+
+	teaser, cut := portabletext.Truncate(doc, portabletext.TruncateOptions{
+		MaxWords:   40,
+		Boundary:   portabletext.TruncateAtSentence,
+		SkipCustom: true,
+	})
+
+# Translation
+
+Send block text to a translation vendor as XLIFF 2.0, with marks and
+inline objects as placeholders, and apply the returned file:
+
+	x, err := portabletext.ExportXLIFF(doc, "en", "de")
+	// ... translate ...
+	de, err := portabletext.ImportXLIFF(doc, bytes.NewReader(translated))
+	if errors.Is(err, portabletext.ErrPlaceholderMismatch) {
+		log.Fatal(err) // placeholders lost or invented, with paths
+	}
+
+UI copy translated with gettext uses numbered tags such as "<0>bold</0>";
+untranslated, fuzzy or broken messages fall back to the source text:
+
+	pot := portabletext.ExportPOT(doc)
+	fr, fallbacks, err := portabletext.MergePO(doc, poFile)
+	for _, f := range fallbacks {
+		log.Println(f) // e.g. "portabletext gettext at [3]: translation marked fuzzy: ..."
+	}
+
+# Localized Fields
+
+Read and write Portable Text stored per locale, in either the
+internationalized-array or the object-per-locale layout:
+
+	f, err := portabletext.ParseLocalized(page["body"])
+	if err != nil {
+		log.Fatal(err)
+	}
+	doc, locale, err := f.Document(portabletext.LocaleFallbacks("de-CH", "en")...)
+	fmt.Println("missing:", f.Missing("en", "de", "fr"))
+	diffs, err := f.Compare("en") // e.g. "de: missing image at [2]"
+
+# Text Offsets
+
+Editors, search engines and Go count text positions differently: Go in
+UTF-8 bytes, JavaScript and the Portable Text Editor in UTF-16 code units,
+readers in user-perceived characters. A TextIndex maps between them and
+from block offsets to spans:
+
+	x := portabletext.NewTextIndex(&doc[0])
+	r, err := x.Convert(7, portabletext.UnitUTF16, portabletext.UnitRune)
+	pos, err := x.Locate(3, portabletext.UnitGrapheme) // pos.Child, pos.Offset, pos.Char
+
+Offsets that split a character wrap ErrInvalidOffset. ChunkOptions.OffsetUnit
+reports chunk sources in the same units.
+
+# Editing
+
+Split, join and reorder blocks by _key. Each operation returns a new
+document, keeps marks and MarkDefs consistent and keys new nodes:
+
+	doc, err = portabletext.SplitBlock(doc, "p1", 12, portabletext.UnitUTF16)
+	next := doc[portabletext.IndexOfKey(doc, "p1")+1]
+	doc, err = portabletext.MergeBlocks(doc, "p1", next.Key)
+	doc, err = portabletext.InsertAfter(doc, "p1", *portabletext.NewBlock("h2").AddSpan("Next"))
+	doc, err = portabletext.Move(doc, "p1", 0)
+	doc, err = portabletext.Remove(doc, "p1")
+
+# Selections and Commands
+
+A Selection has the Portable Text Editor's JSON shape, with offsets in
+UTF-16 code units into a span. Editor commands apply to a selection and
+return the new document and selection:
+
+	var sel portabletext.Selection
+	err := json.Unmarshal(msg, &sel)
+	doc, sel, err = portabletext.InsertText(doc, sel, "Hi")
+	doc, sel, err = portabletext.ToggleDecorator(doc, sel, "strong")
+	doc, sel, err = portabletext.AddAnnotation(doc, sel, "link", map[string]any{"href": url})
+	doc, sel, err = portabletext.DeleteBackward(doc, sel)
+
+PointAt and SelectionPoint.BlockOffset convert to and from block offsets
+in any OffsetUnit.
+
+# Undo and Redo
+
+A History records each version of a document as the operations that
+changed it, so memory follows the size of the edits:
+
+	h := portabletext.NewHistory(doc)
+	h.Begin("typing")
+	doc, sel, err = portabletext.InsertText(doc, sel, "a")
+	h.Record(doc)
+	h.End()
+	doc, err = h.Undo()
+
+Operations can be inverted, applied and exchanged directly with Diff,
+Operation.Invert and History.Apply. A History encodes its undo and redo
+stacks as JSON; the document is saved separately.
+
 # Working with Nodes
 
 Node provides convenience methods:
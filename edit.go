@@ -0,0 +1,299 @@
+package portabletext
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+var (
+	ErrKeyNotFound = errors.New("no node with _key")
+	ErrNotBlock    = errors.New("not a text block")
+)
+
+// The editing operations below address nodes by _key, which stays stable
+// while indexes shift. Each returns a new Document and leaves doc
+// unchanged; nodes that are not edited are copied shallowly and share
+// their children and fields with doc.
+
+// IndexOfKey returns the index of the first node whose _key is key, or -1.
+func IndexOfKey(doc Document, key string) int {
+	if key == "" {
+		return -1
+	}
+	return slices.IndexFunc(doc, func(n Node) bool { return n.Key == key })
+}
+
+// findKey is IndexOfKey returning an error wrapping ErrKeyNotFound.
+func findKey(doc Document, key string) (int, error) {
+	if i := IndexOfKey(doc, key); i >= 0 {
+		return i, nil
+	}
+	return -1, wrap("edit", "", fmt.Errorf("%w: %q", ErrKeyNotFound, key))
+}
+
+// findBlock is findKey for operations on text blocks.
+func findBlock(doc Document, key string) (int, error) {
+	i, err := findKey(doc, key)
+	if err == nil && !doc[i].IsBlock() {
+		err = wrap("edit", nodePath(i).String(), fmt.Errorf("%w: %s", ErrNotBlock, doc[i].Type))
+	}
+	return i, err
+}
+
+// SplitBlock splits the block with _key key at a block-relative text
+// offset, as when pressing Enter at a cursor. The text after the offset
+// moves to a new block inserted after it with a new _key and the same
+// style, list settings and custom fields. A span cut in two keeps its
+// marks on both sides; the second half gets a new _key. Inline objects at
+// the offset start the new block. Each block keeps the MarkDefs its spans
+// use, so annotations cut by the split are present in both; a side left
+// without children gets an empty span.
+func SplitBlock(doc Document, key string, offset int, unit OffsetUnit) (Document, error) {
+	i, err := findBlock(doc, key)
+	if err != nil {
+		return nil, err
+	}
+	n := &doc[i]
+	r, err := NewTextIndex(n).toRune(offset, unit)
+	if err != nil {
+		return nil, wrap("edit", nodePath(i).String(), err)
+	}
+
+	first, second := n.Clone(), n.Clone()
+	second.Key = NewKey()
+	first.Children, second.Children = nil, nil
+	pos := 0
+	for _, c := range cloneSpans(n.Children) {
+		if c.Type != "span" || c.Text == nil {
+			if pos < r {
+				first.Children = append(first.Children, c)
+			} else {
+				second.Children = append(second.Children, c)
+			}
+			continue
+		}
+		text := []rune(*c.Text)
+		switch end := pos + len(text); {
+		case end <= r && len(text) > 0, pos < r && len(text) == 0:
+			first.Children = append(first.Children, c)
+		case pos >= r:
+			second.Children = append(second.Children, c)
+		default:
+			head, tail := string(text[:r-pos]), string(text[r-pos:])
+			rest := cloneSpans([]Span{c})[0]
+			c.Text, rest.Text = &head, &tail
+			setSpanKey(&rest, NewKey())
+			first.Children = append(first.Children, c)
+			second.Children = append(second.Children, rest)
+		}
+		pos += len(text)
+	}
+	first.Children = withSpan(first.Children)
+	second.Children = withSpan(second.Children)
+
+	inFirst, inSecond := usedMarks(first.Children), usedMarks(second.Children)
+	first.MarkDefs = slices.DeleteFunc(first.MarkDefs, func(md MarkDef) bool { return inSecond[md.Key] && !inFirst[md.Key] })
+	second.MarkDefs = slices.DeleteFunc(second.MarkDefs, func(md MarkDef) bool { return !inSecond[md.Key] })
+
+	out := make(Document, 0, len(doc)+1)
+	out = append(out, doc[:i]...)
+	out = append(out, *first, *second)
+	return append(out, doc[i+1:]...), nil
+}
+
+// setSpanKey sets the _key of s, allocating Raw for spans built as struct
+// literals.
+func setSpanKey(s *Span, key string) {
+	if s.Raw == nil {
+		s.Raw = map[string]any{}
+	}
+	s.Raw["_key"] = key
+}
+
+// withSpan returns children, or a single empty span if there are none.
+func withSpan(children []Span) []Span {
+	if len(children) > 0 {
+		return children
+	}
+	empty := ""
+	return []Span{{Type: "span", Text: &empty, Marks: []string{}, Raw: map[string]any{"_key": NewKey()}}}
+}
+
+// usedMarks returns the marks applied by the spans in children.
+func usedMarks(children []Span) map[string]bool {
+	used := map[string]bool{}
+	for _, s := range children {
+		for _, m := range s.Marks {
+			used[m] = true
+		}
+	}
+	return used
+}
+
+// MergeBlocks appends the children of the block keyB to the block keyA and
+// removes keyB, as when pressing Backspace at the start of a block. The
+// merged block keeps keyA's position, _key and style. Annotations of keyB
+// that equal one of keyA's MarkDefs are deduplicated; those whose key
+// clashes with a different MarkDef of keyA are re-keyed, as are clashing
+// span keys. Empty spans at the seam are dropped, and the spans either
+// side of it are joined when they have the same marks.
+func MergeBlocks(doc Document, keyA, keyB string) (Document, error) {
+	i, err := findBlock(doc, keyA)
+	if err != nil {
+		return nil, err
+	}
+	j, err := findBlock(doc, keyB)
+	if err != nil {
+		return nil, err
+	}
+	if i == j {
+		return nil, wrap("edit", nodePath(i).String(), fmt.Errorf("cannot merge block %q into itself", keyA))
+	}
+	a := joinBlocks(doc[i].Clone(), doc[j].Clone())
+
+	out := make(Document, 0, len(doc)-1)
+	for k := range doc {
+		switch k {
+		case i:
+			out = append(out, *a)
+		case j:
+		default:
+			out = append(out, doc[k])
+		}
+	}
+	return out, nil
+}
+
+// joinBlocks appends the children of b to a, both of which it may modify,
+// as described for MergeBlocks.
+func joinBlocks(a, b *Node) *Node {
+	rename := map[string]string{}
+	for _, md := range b.MarkDefs {
+		if k := slices.IndexFunc(a.MarkDefs, func(o MarkDef) bool { return sameMarkDef(o, md) }); k >= 0 {
+			rename[md.Key] = a.MarkDefs[k].Key
+			continue
+		}
+		if slices.ContainsFunc(a.MarkDefs, func(o MarkDef) bool { return o.Key == md.Key }) {
+			rename[md.Key] = NewKey()
+			md.Key = rename[md.Key]
+		}
+		a.MarkDefs = append(a.MarkDefs, md)
+	}
+
+	spanKeys := map[string]bool{}
+	for _, c := range a.Children {
+		if k, ok := c.Raw["_key"].(string); ok {
+			spanKeys[k] = true
+		}
+	}
+	for k := range b.Children {
+		c := &b.Children[k]
+		for m, mark := range c.Marks {
+			if to, ok := rename[mark]; ok {
+				c.Marks[m] = to
+			}
+		}
+		if key, ok := c.Raw["_key"].(string); ok && spanKeys[key] {
+			c.Raw["_key"] = NewKey()
+		}
+	}
+
+	if len(b.Children) > 0 && len(a.Children) > 0 && isEmptySpan(a.Children[len(a.Children)-1]) {
+		a.Children = a.Children[:len(a.Children)-1]
+	}
+	if len(a.Children) > 0 && len(b.Children) > 0 && isEmptySpan(b.Children[0]) {
+		b.Children = b.Children[1:]
+	}
+	if len(a.Children) > 0 && len(b.Children) > 0 {
+		last, next := &a.Children[len(a.Children)-1], b.Children[0]
+		if last.Type == "span" && next.Type == "span" && last.Text != nil && next.Text != nil && sameMarks(last.Marks, next.Marks) {
+			t := *last.Text + *next.Text
+			last.Text = &t
+			b.Children = b.Children[1:]
+		}
+	}
+	a.Children = append(a.Children, b.Children...)
+	return a
+}
+
+func isEmptySpan(s Span) bool {
+	return s.Type == "span" && (s.Text == nil || *s.Text == "")
+}
+
+// sameMarkDef reports whether two annotations have the same type and
+// fields, ignoring their keys.
+func sameMarkDef(a, b MarkDef) bool {
+	return a.Type == b.Type && reflect.DeepEqual(a.Raw, b.Raw)
+}
+
+// sameMarks reports whether two mark lists apply the same marks.
+func sameMarks(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(slices.Compact(a), slices.Compact(b))
+}
+
+// InsertAfter inserts copies of nodes after the node with _key key, or at
+// the start of the document when key is "". Nodes without a _key, or with
+// one already in the document, get a new key, as do their spans without
+// one.
+func InsertAfter(doc Document, key string, nodes ...Node) (Document, error) {
+	at := 0
+	if key != "" {
+		i, err := findKey(doc, key)
+		if err != nil {
+			return nil, err
+		}
+		at = i + 1
+	}
+
+	keys := map[string]bool{}
+	for i := range doc {
+		keys[doc[i].Key] = true
+	}
+	inserted := make([]Node, len(nodes))
+	for k := range nodes {
+		n := nodes[k].Clone()
+		if n.Key == "" || keys[n.Key] {
+			n.Key = NewKey()
+		}
+		keys[n.Key] = true
+		for c := range n.Children {
+			if _, ok := n.Children[c].Raw["_key"].(string); !ok {
+				setSpanKey(&n.Children[c], NewKey())
+			}
+		}
+		inserted[k] = *n
+	}
+
+	out := make(Document, 0, len(doc)+len(nodes))
+	out = append(out, doc[:at]...)
+	out = append(out, inserted...)
+	return append(out, doc[at:]...), nil
+}
+
+// Move moves the node with _key key so that it ends up at index to of the
+// result.
+func Move(doc Document, key string, to int) (Document, error) {
+	i, err := findKey(doc, key)
+	if err != nil {
+		return nil, err
+	}
+	if to < 0 || to >= len(doc) {
+		return nil, wrap("edit", nodePath(i).String(), fmt.Errorf("index %d out of range [0, %d]", to, len(doc)-1))
+	}
+	out := slices.Delete(slices.Clone(doc), i, i+1)
+	return slices.Insert(out, to, doc[i]), nil
+}
+
+// Remove removes the node with _key key.
+func Remove(doc Document, key string) (Document, error) {
+	i, err := findKey(doc, key)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Delete(slices.Clone(doc), i, i+1), nil
+}
@@ -0,0 +1,343 @@
+package portabletext
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// withKeys sets the _key of n and of its children in order.
+func withKeys(n *Node, key string, spanKeys ...string) *Node {
+	n.Key = key
+	for j, k := range spanKeys {
+		n.Children[j].Raw["_key"] = k
+	}
+	return n
+}
+
+func editTestDoc() Document {
+	a := NewBlock("normal").
+		AddMarkDef("l1", "link", map[string]any{"href": "https://example.com"}).
+		AddSpan("Hello ").
+		AddSpan("brave new", "strong", "l1").
+		AddSpan(" world")
+	img := NewNode("image")
+	img.Key = "img"
+	b := NewBlock("normal").
+		AddMarkDef("l9", "link", map[string]any{"href": "https://example.com"}).
+		AddMarkDef("l1", "link", map[string]any{"href": "https://example.org"}).
+		AddSpan("Again", "l9").
+		AddSpan(" and ").
+		AddSpan("more", "l1")
+	return Document{*withKeys(a, "a", "s1", "s2", "s3"), *img, *withKeys(b, "b", "t1", "s1", "t3")}
+}
+
+func nodeKeys(doc Document) []string {
+	var out []string
+	for i := range doc {
+		out = append(out, doc[i].Key)
+	}
+	return out
+}
+
+func spanTexts(n *Node) []string {
+	var out []string
+	for _, c := range n.Children {
+		if c.Text != nil {
+			out = append(out, *c.Text)
+		} else {
+			out = append(out, "<"+c.Type+">")
+		}
+	}
+	return out
+}
+
+func markDefKeys(n *Node) []string {
+	out := []string{}
+	for _, md := range n.MarkDefs {
+		out = append(out, md.Key)
+	}
+	return out
+}
+
+func TestIndexOfKey(t *testing.T) {
+	doc := editTestDoc()
+	for key, want := range map[string]int{"a": 0, "img": 1, "b": 2, "nope": -1, "": -1} {
+		if got := IndexOfKey(doc, key); got != want {
+			t.Errorf("IndexOfKey(%q) = %d, want %d", key, got, want)
+		}
+	}
+}
+
+func TestSplitBlock(t *testing.T) {
+	tests := []struct {
+		name                  string
+		offset                int
+		first, second         []string
+		firstDefs, secondDefs []string
+	}{
+		{"inside an annotated span", 10, []string{"Hello ", "brav"}, []string{"e new", " world"}, []string{"l1"}, []string{"l1"}},
+		{"at a span boundary", 6, []string{"Hello "}, []string{"brave new", " world"}, []string{}, []string{"l1"}},
+		{"at the start", 0, []string{""}, []string{"Hello ", "brave new", " world"}, []string{}, []string{"l1"}},
+		{"at the end", 21, []string{"Hello ", "brave new", " world"}, []string{""}, []string{"l1"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := editTestDoc()
+			out, err := SplitBlock(doc, "a", tt.offset, UnitRune)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(out) != 4 || out[0].Key != "a" || out[1].Key == "" || out[1].Key == "a" || out[2].Key != "img" {
+				t.Fatalf("keys = %v", nodeKeys(out))
+			}
+			if got := spanTexts(&out[0]); !slices.Equal(got, tt.first) {
+				t.Errorf("first = %q, want %q", got, tt.first)
+			}
+			if got := spanTexts(&out[1]); !slices.Equal(got, tt.second) {
+				t.Errorf("second = %q, want %q", got, tt.second)
+			}
+			if got := markDefKeys(&out[0]); !slices.Equal(got, tt.firstDefs) {
+				t.Errorf("first markDefs = %v, want %v", got, tt.firstDefs)
+			}
+			if got := markDefKeys(&out[1]); !slices.Equal(got, tt.secondDefs) {
+				t.Errorf("second markDefs = %v, want %v", got, tt.secondDefs)
+			}
+			if out[1].GetStyle() != "normal" {
+				t.Errorf("second style = %q", out[1].GetStyle())
+			}
+			for _, c := range out[1].Children {
+				if k, _ := c.Raw["_key"].(string); k == "" || k == "s2" && tt.offset == 10 {
+					t.Errorf("second block span key = %q", k)
+				}
+			}
+			if doc[0].GetText() != "Hello brave new world" || len(doc) != 3 {
+				t.Errorf("input document modified")
+			}
+		})
+	}
+}
+
+func TestSplitBlockMarks(t *testing.T) {
+	out, err := SplitBlock(editTestDoc(), "a", 10, UnitRune)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(out[0].Children[1].Marks, []string{"strong", "l1"}) || !slices.Equal(out[1].Children[0].Marks, []string{"strong", "l1"}) {
+		t.Errorf("marks = %v / %v", out[0].Children[1].Marks, out[1].Children[0].Marks)
+	}
+	if out[0].Children[1].Raw["_key"] != "s2" {
+		t.Errorf("first half key = %v, want s2", out[0].Children[1].Raw["_key"])
+	}
+
+	merged, err := MergeBlocks(out, "a", out[1].Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := EncodeString(editTestDoc()[:1])
+	got, _ := EncodeString(merged[:1])
+	if got != want {
+		t.Errorf("split and merge:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestSplitBlockOffsets(t *testing.T) {
+	n := NewBlock("normal").AddSpan("ab")
+	n.Children = append(n.Children, Span{Type: "emoji", Raw: map[string]any{}})
+	doc := Document{*withKeys(n.AddSpan("👍cd"), "k")}
+
+	out, err := SplitBlock(doc, "k", 2, UnitRune)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := spanTexts(&out[1]); !slices.Equal(got, []string{"<emoji>", "👍cd"}) {
+		t.Errorf("inline object at the offset: second = %q", got)
+	}
+
+	out, err = SplitBlock(doc, "k", 4, UnitUTF16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := spanTexts(&out[1]); !slices.Equal(got, []string{"cd"}) {
+		t.Errorf("UTF-16 offset: second = %q", got)
+	}
+
+	if _, err := SplitBlock(doc, "k", 3, UnitUTF16); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("offset inside a surrogate pair: error = %v", err)
+	}
+	if _, err := SplitBlock(doc, "k", 9, UnitRune); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("offset out of range: error = %v", err)
+	}
+	if _, err := SplitBlock(editTestDoc(), "img", 0, UnitRune); !errors.Is(err, ErrNotBlock) {
+		t.Errorf("split image: error = %v", err)
+	}
+	if _, err := SplitBlock(editTestDoc(), "nope", 0, UnitRune); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("unknown key: error = %v", err)
+	}
+}
+
+func TestSplitBlockLiteralSpans(t *testing.T) {
+	text := "Hello"
+	style := "normal"
+	doc := Document{{Type: "block", Key: "k", Style: &style, Children: []Span{{Type: "span", Text: &text}}}}
+
+	out, err := SplitBlock(doc, "k", 2, UnitRune)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := spanTexts(&out[1]); !slices.Equal(got, []string{"llo"}) {
+		t.Errorf("second = %q", got)
+	}
+	if k, _ := out[1].Children[0].Raw["_key"].(string); k == "" {
+		t.Errorf("split span has no key")
+	}
+	if doc[0].Children[0].Raw != nil || *doc[0].Children[0].Text != "Hello" {
+		t.Errorf("input modified")
+	}
+}
+
+func TestMergeBlocks(t *testing.T) {
+	doc := editTestDoc()
+	out, err := MergeBlocks(doc, "a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nodeKeys(out); !slices.Equal(got, []string{"a", "img"}) {
+		t.Fatalf("keys = %v", got)
+	}
+	n := &out[0]
+	if got := spanTexts(n); !slices.Equal(got, []string{"Hello ", "brave new", " world", "Again", " and ", "more"}) {
+		t.Errorf("children = %q", got)
+	}
+
+	// l9 equals l1 and is deduplicated; b's own l1 clashes and is re-keyed.
+	if len(n.MarkDefs) != 2 || n.MarkDefs[0].Key != "l1" {
+		t.Fatalf("markDefs = %+v", n.MarkDefs)
+	}
+	if got := n.Children[3].Marks; !slices.Equal(got, []string{"l1"}) {
+		t.Errorf("deduplicated annotation marks = %v", got)
+	}
+	rekeyed := n.MarkDefs[1]
+	if rekeyed.Key == "l1" || rekeyed.Raw["href"] != "https://example.org" {
+		t.Errorf("re-keyed markDef = %+v", rekeyed)
+	}
+	if got := n.Children[5].Marks; !slices.Equal(got, []string{rekeyed.Key}) {
+		t.Errorf("re-keyed annotation marks = %v, want [%s]", got, rekeyed.Key)
+	}
+
+	seen := map[any]bool{}
+	for _, c := range n.Children {
+		if seen[c.Raw["_key"]] {
+			t.Errorf("duplicate span key %v", c.Raw["_key"])
+		}
+		seen[c.Raw["_key"]] = true
+	}
+	if len(doc) != 3 || len(doc[0].Children) != 3 || doc[2].Children[2].Marks[0] != "l1" {
+		t.Errorf("input document modified")
+	}
+
+	if _, err := MergeBlocks(doc, "a", "a"); err == nil {
+		t.Errorf("merge into itself: no error")
+	}
+	if _, err := MergeBlocks(doc, "a", "img"); !errors.Is(err, ErrNotBlock) {
+		t.Errorf("merge image: error = %v", err)
+	}
+	if _, err := MergeBlocks(doc, "nope", "a"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("unknown key: error = %v", err)
+	}
+}
+
+func TestMergeBlocksSeam(t *testing.T) {
+	doc := Document{
+		*withKeys(NewBlock("normal").AddSpan("foo"), "x"),
+		*withKeys(NewBlock("normal").AddSpan("").AddSpan("bar"), "y"),
+		*withKeys(NewBlock("normal").AddSpan(""), "z"),
+	}
+	out, err := MergeBlocks(doc, "x", "y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := spanTexts(&out[0]); !slices.Equal(got, []string{"foobar"}) {
+		t.Errorf("children = %q", got)
+	}
+	out, err = MergeBlocks(out, "z", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nodeKeys(out); !slices.Equal(got, []string{"z"}) {
+		t.Errorf("keys = %v", got)
+	}
+	if got := spanTexts(&out[0]); !slices.Equal(got, []string{"foobar"}) {
+		t.Errorf("children = %q", got)
+	}
+}
+
+func TestInsertAfter(t *testing.T) {
+	doc := editTestDoc()
+	dup := NewBlock("normal").AddSpan("dup")
+	dup.Key = "a"
+	out, err := InsertAfter(doc, "img", *NewBlock("h2").AddSpan("Title"), *dup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := nodeKeys(out)
+	if len(out) != 5 || keys[0] != "a" || keys[1] != "img" || keys[4] != "b" {
+		t.Fatalf("keys = %v", keys)
+	}
+	if keys[2] == "" || keys[3] == "" || keys[3] == "a" {
+		t.Errorf("new node keys = %v", keys[2:4])
+	}
+	if k, _ := out[2].Children[0].Raw["_key"].(string); k == "" {
+		t.Errorf("new span has no key")
+	}
+	if dup.Key != "a" || len(doc) != 3 {
+		t.Errorf("inputs modified")
+	}
+
+	out, err = InsertAfter(doc, "", *NewBlock("h1").AddSpan("Top"))
+	if err != nil || out[0].GetStyle() != "h1" || len(out) != 4 {
+		t.Errorf("insert at start = %v, %v", nodeKeys(out), err)
+	}
+	if _, err := InsertAfter(doc, "nope"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("unknown key: error = %v", err)
+	}
+}
+
+func TestMoveRemove(t *testing.T) {
+	doc := editTestDoc()
+	tests := []struct {
+		key  string
+		to   int
+		want []string
+	}{
+		{"a", 2, []string{"img", "b", "a"}},
+		{"b", 0, []string{"b", "a", "img"}},
+		{"img", 1, []string{"a", "img", "b"}},
+	}
+	for _, tt := range tests {
+		out, err := Move(doc, tt.key, tt.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := nodeKeys(out); !slices.Equal(got, tt.want) {
+			t.Errorf("Move(%q, %d) = %v, want %v", tt.key, tt.to, got, tt.want)
+		}
+	}
+	if _, err := Move(doc, "a", 3); err == nil {
+		t.Errorf("Move out of range: no error")
+	}
+
+	out, err := Remove(doc, "img")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nodeKeys(out); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("Remove = %v", got)
+	}
+	if _, err := Remove(doc, "nope"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("unknown key: error = %v", err)
+	}
+	if got := nodeKeys(doc); !slices.Equal(got, []string{"a", "img", "b"}) {
+		t.Errorf("input document modified: %v", got)
+	}
+}
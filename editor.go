@@ -0,0 +1,557 @@
+package portabletext
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"unicode/utf8"
+)
+
+// The commands below apply an editor action to the selection in doc and
+// return the updated document and selection, leaving doc unchanged. Text
+// blocks they edit are normalized as the Portable Text Editor does:
+// adjacent spans with the same marks are joined, empty spans dropped and
+// unused MarkDefs removed. Span keys may change, so use the returned
+// selection rather than the old one.
+
+// InsertText replaces the selection with text, which may contain
+// newlines, and collapses the selection after it. Typed text takes the
+// marks of the span before the cursor, except that it does not extend an
+// annotation past its end.
+func InsertText(doc Document, sel Selection, text string) (Document, Selection, error) {
+	anchor, focus, err := resolveSelection(doc, sel)
+	if err != nil {
+		return nil, Selection{}, err
+	}
+	out := slices.Clone(doc)
+	p, _ := ordered(anchor, focus)
+	if anchor != focus {
+		out, p = deleteRange(out, anchor, focus)
+	}
+	if !out[p.block].IsBlock() {
+		return nil, Selection{}, wrap("edit", nodePath(p.block).String(), fmt.Errorf("%w: cannot type into %s", ErrNotBlock, out[p.block].Type))
+	}
+	n := out[p.block].Clone()
+	insertText(n, p.off, text)
+	normalizeBlock(n)
+	out[p.block] = *n
+	p.off += utf8.RuneCountInString(text)
+	return out, selectionFor(out, p, p), nil
+}
+
+// DeleteBackward deletes the selection or, for a caret, what Backspace
+// deletes: the grapheme cluster before it, the preceding custom node, or
+// the boundary with the previous text block, which merges the two. A
+// selected custom node or inline object is removed.
+func DeleteBackward(doc Document, sel Selection) (Document, Selection, error) {
+	return deleteCommand(doc, sel, false)
+}
+
+// DeleteForward is the mirror of DeleteBackward for the Delete key.
+func DeleteForward(doc Document, sel Selection) (Document, Selection, error) {
+	return deleteCommand(doc, sel, true)
+}
+
+func deleteCommand(doc Document, sel Selection, forward bool) (Document, Selection, error) {
+	anchor, focus, err := resolveSelection(doc, sel)
+	if err != nil {
+		return nil, Selection{}, err
+	}
+	out := slices.Clone(doc)
+	if anchor != focus {
+		out, p := deleteRange(out, anchor, focus)
+		return out, selectionFor(out, p, p), nil
+	}
+
+	p := anchor
+	if !out[p.block].IsBlock() {
+		out = slices.Delete(out, p.block, p.block+1)
+		switch {
+		case len(out) == 0:
+			out = Document{*emptyBlock()}
+		case p.block == len(out) || !forward && p.block > 0:
+			p.block--
+			p.off = textLen(&out[p.block])
+		}
+		return out, selectionFor(out, p, p), nil
+	}
+
+	if p.obj > 0 {
+		// Backspace and Delete remove a selected inline object.
+		n := out[p.block].Clone()
+		n.Children = slices.Delete(n.Children, p.obj-1, p.obj)
+		normalizeBlock(n)
+		out[p.block] = *n
+		p.obj = 0
+		return out, selectionFor(out, p, p), nil
+	}
+
+	x := NewTextIndex(&out[p.block])
+	switch {
+	case !forward && p.off > 0:
+		g := x.graphemes[sort.SearchInts(x.graphemes, p.off)-1]
+		out, p = deleteRange(out, textPoint{block: p.block, off: g}, p)
+	case forward && p.off < textLen(&out[p.block]):
+		g := x.graphemes[sort.SearchInts(x.graphemes, p.off+1)]
+		out, _ = deleteRange(out, p, textPoint{block: p.block, off: g})
+	case !forward && p.block > 0:
+		if out[p.block-1].IsBlock() {
+			p = textPoint{block: p.block - 1, off: textLen(&out[p.block-1])}
+			out = joinAt(out, p.block)
+		} else {
+			out = slices.Delete(out, p.block-1, p.block)
+			p.block--
+		}
+	case forward && p.block < len(out)-1:
+		if out[p.block+1].IsBlock() {
+			out = joinAt(out, p.block)
+		} else {
+			out = slices.Delete(out, p.block+1, p.block+2)
+		}
+	}
+	return out, selectionFor(out, p, p), nil
+}
+
+// joinAt merges the text block after doc[i] into it.
+func joinAt(doc Document, i int) Document {
+	n := joinBlocks(doc[i].Clone(), doc[i+1].Clone())
+	normalizeBlock(n)
+	doc[i] = *n
+	return slices.Delete(doc, i+1, i+2)
+}
+
+// ToggleDecorator removes decorator from the selected text if all of it
+// has the decorator, and adds it to all of it otherwise. A collapsed
+// selection is left unchanged.
+func ToggleDecorator(doc Document, sel Selection, decorator string) (Document, Selection, error) {
+	anchor, focus, err := resolveSelection(doc, sel)
+	if err != nil {
+		return nil, Selection{}, err
+	}
+	start, end := ordered(anchor, focus)
+	all := true
+	textRanges(doc, start, end, func(i, s, e int) {
+		forSpans(&doc[i], s, e, func(c *Span) { all = all && slices.Contains(c.Marks, decorator) })
+	})
+
+	out := slices.Clone(doc)
+	textRanges(out, start, end, func(i, s, e int) {
+		n := out[i].Clone()
+		splitSpansAt(n, s, e)
+		forSpans(n, s, e, func(c *Span) {
+			c.Marks = slices.DeleteFunc(c.Marks, func(m string) bool { return m == decorator })
+			if !all {
+				c.Marks = append(c.Marks, decorator)
+			}
+		})
+		normalizeBlock(n)
+		out[i] = *n
+	})
+	return out, selectionFor(out, anchor, focus), nil
+}
+
+// AddAnnotation annotates the selected text with a new MarkDef of type
+// markType and fields, one per block since MarkDef keys are scoped to
+// their block. Annotations of the same type on the selected text are
+// replaced. A collapsed selection is left unchanged.
+func AddAnnotation(doc Document, sel Selection, markType string, fields map[string]any) (Document, Selection, error) {
+	anchor, focus, err := resolveSelection(doc, sel)
+	if err != nil {
+		return nil, Selection{}, err
+	}
+	start, end := ordered(anchor, focus)
+	out := slices.Clone(doc)
+	textRanges(out, start, end, func(i, s, e int) {
+		n := out[i].Clone()
+		splitSpansAt(n, s, e)
+		md := MarkDef{Key: NewKey(), Type: markType, Raw: deepCopyMap(fields)}
+		if md.Raw == nil {
+			md.Raw = map[string]any{}
+		}
+		same := annotationsOfType(n, markType)
+		forSpans(n, s, e, func(c *Span) {
+			c.Marks = slices.DeleteFunc(c.Marks, func(m string) bool { return same[m] })
+			c.Marks = append(c.Marks, md.Key)
+		})
+		n.MarkDefs = append(n.MarkDefs, md)
+		normalizeBlock(n)
+		out[i] = *n
+	})
+	return out, selectionFor(out, anchor, focus), nil
+}
+
+// RemoveAnnotation removes the annotations of type markType that the
+// selection touches, including the parts of them outside the selection.
+// For a caret, that is the annotation around or next to it.
+func RemoveAnnotation(doc Document, sel Selection, markType string) (Document, Selection, error) {
+	anchor, focus, err := resolveSelection(doc, sel)
+	if err != nil {
+		return nil, Selection{}, err
+	}
+	start, end := ordered(anchor, focus)
+	out := slices.Clone(doc)
+	for i := start.block; i <= end.block; i++ {
+		if !out[i].IsBlock() {
+			continue
+		}
+		s, e := 0, textLen(&out[i])
+		if i == start.block {
+			s = start.off
+		}
+		if i == end.block {
+			e = end.off
+		}
+		typed := annotationsOfType(&out[i], markType)
+		touched := map[string]bool{}
+		pos := 0
+		for _, c := range out[i].Children {
+			l := spanLen(c)
+			if l > 0 && (pos < e && pos+l > s || s == e && pos <= s && s <= pos+l) {
+				for _, m := range c.Marks {
+					if typed[m] {
+						touched[m] = true
+					}
+				}
+			}
+			pos += l
+		}
+		if len(touched) == 0 {
+			continue
+		}
+		n := out[i].Clone()
+		for j := range n.Children {
+			n.Children[j].Marks = slices.DeleteFunc(n.Children[j].Marks, func(m string) bool { return touched[m] })
+		}
+		normalizeBlock(n)
+		out[i] = *n
+	}
+	return out, selectionFor(out, anchor, focus), nil
+}
+
+// ToggleList makes the selected text blocks items of list type listItem
+// ("bullet", "number", ...) at level 1, keeping existing levels, or turns
+// them back into plain blocks if they all already are.
+func ToggleList(doc Document, sel Selection, listItem string) (Document, Selection, error) {
+	anchor, focus, blocks, err := selectedBlocks(doc, sel)
+	if err != nil {
+		return nil, Selection{}, err
+	}
+	all := true
+	for _, i := range blocks {
+		all = all && doc[i].ListItem != nil && *doc[i].ListItem == listItem
+	}
+	return editBlocks(doc, anchor, focus, blocks, func(n *Node) {
+		if all {
+			n.ListItem, n.Level = nil, nil
+			return
+		}
+		l := listItem
+		n.ListItem = &l
+		if n.Level == nil {
+			level := 1
+			n.Level = &level
+		}
+	})
+}
+
+// SetStyle sets the style ("normal", "h1", "blockquote", ...) of the
+// selected text blocks.
+func SetStyle(doc Document, sel Selection, style string) (Document, Selection, error) {
+	anchor, focus, blocks, err := selectedBlocks(doc, sel)
+	if err != nil {
+		return nil, Selection{}, err
+	}
+	return editBlocks(doc, anchor, focus, blocks, func(n *Node) {
+		s := style
+		n.Style = &s
+	})
+}
+
+// selectedBlocks resolves sel and returns the indexes of the text blocks
+// it covers.
+func selectedBlocks(doc Document, sel Selection) (anchor, focus textPoint, blocks []int, err error) {
+	if anchor, focus, err = resolveSelection(doc, sel); err != nil {
+		return
+	}
+	start, end := ordered(anchor, focus)
+	for i := start.block; i <= end.block; i++ {
+		if doc[i].IsBlock() {
+			blocks = append(blocks, i)
+		}
+	}
+	return
+}
+
+// editBlocks applies fn to copies of the blocks.
+func editBlocks(doc Document, anchor, focus textPoint, blocks []int, fn func(n *Node)) (Document, Selection, error) {
+	out := slices.Clone(doc)
+	for _, i := range blocks {
+		n := out[i].Clone()
+		fn(n)
+		out[i] = *n
+	}
+	return out, selectionFor(out, anchor, focus), nil
+}
+
+// textRanges calls fn with the rune range [s, e) of each text block
+// between start and end that has selected text.
+func textRanges(doc Document, start, end textPoint, fn func(i, s, e int)) {
+	for i := start.block; i <= end.block; i++ {
+		if !doc[i].IsBlock() {
+			continue
+		}
+		s, e := 0, textLen(&doc[i])
+		if i == start.block {
+			s = start.off
+		}
+		if i == end.block {
+			e = end.off
+		}
+		if s < e {
+			fn(i, s, e)
+		}
+	}
+}
+
+// forSpans calls fn for each non-empty span of n that overlaps the rune
+// range [s, e).
+func forSpans(n *Node, s, e int, fn func(c *Span)) {
+	pos := 0
+	for j := range n.Children {
+		l := spanLen(n.Children[j])
+		if l > 0 && pos < e && pos+l > s {
+			fn(&n.Children[j])
+		}
+		pos += l
+	}
+}
+
+// splitSpansAt splits the spans of n so that the rune offsets at are span
+// boundaries. New spans get new keys.
+func splitSpansAt(n *Node, at ...int) {
+	for _, off := range at {
+		pos := 0
+		for j := 0; j < len(n.Children); j++ {
+			c := n.Children[j]
+			l := spanLen(c)
+			if pos < off && off < pos+l {
+				text := []rune(*c.Text)
+				head, tail := string(text[:off-pos]), string(text[off-pos:])
+				rest := cloneSpans([]Span{c})[0]
+				n.Children[j].Text, rest.Text = &head, &tail
+				setSpanKey(&rest, NewKey())
+				n.Children = slices.Insert(n.Children, j+1, rest)
+				break
+			}
+			pos += l
+		}
+	}
+}
+
+// deleteRange removes the content between a and b, in either order, and
+// returns the point where it was. Text blocks at either end keep the text
+// outside the range and are joined; nodes in between, and custom nodes at
+// either end, are removed. The document is never left empty.
+func deleteRange(doc Document, a, b textPoint) (Document, textPoint) {
+	start, end := ordered(a, b)
+	if start.block == end.block {
+		if n := &doc[start.block]; n.IsBlock() {
+			c := n.Clone()
+			deleteText(c, start, end)
+			doc[start.block] = *c
+		}
+		start.obj = 0
+		return doc, start
+	}
+
+	var head, tail *Node
+	if doc[start.block].IsBlock() {
+		head = doc[start.block].Clone()
+		deleteText(head, start, textPoint{off: textLen(head)})
+	}
+	if doc[end.block].IsBlock() {
+		tail = doc[end.block].Clone()
+		deleteText(tail, textPoint{}, end)
+	}
+	start.obj = 0
+	out := slices.Clone(doc[:start.block])
+	switch {
+	case head != nil && tail != nil:
+		head = joinBlocks(head, tail)
+		normalizeBlock(head)
+		out = append(out, *head)
+	case head != nil:
+		out = append(out, *head)
+	case tail != nil:
+		out = append(out, *tail)
+		start.off = 0
+	}
+	out = append(out, doc[end.block+1:]...)
+
+	switch {
+	case len(out) == 0:
+		out = Document{*emptyBlock()}
+		start = textPoint{}
+	case start.block == len(out):
+		start = textPoint{block: start.block - 1, off: textLen(&out[start.block-1])}
+	}
+	return out, start
+}
+
+// deleteText removes the text between the points s and e of the block n,
+// with the inline objects the range covers: those inside it, those at an
+// edge that is the start or end of the text, and those a point is on.
+func deleteText(n *Node, s, e textPoint) {
+	if s.off > e.off || s.off == e.off && s.obj == e.obj {
+		return
+	}
+	// Remove objects first: they have no width, and splitting spans would
+	// shift the child indexes that s and e refer to.
+	end, pos, j := textLen(n), 0, 0
+	n.Children = slices.DeleteFunc(n.Children, func(c Span) bool {
+		at, k := pos, j
+		pos, j = pos+spanLen(c), j+1
+		if c.Type == "span" {
+			return false
+		}
+		afterStart := at > s.off || at == s.off && (s.obj == 0 && s.off == 0 || s.obj > 0 && k >= s.obj-1)
+		beforeEnd := at < e.off || at == e.off && (e.obj == 0 && e.off == end || e.obj > 0 && k <= e.obj-1)
+		return afterStart && beforeEnd
+	})
+
+	splitSpansAt(n, s.off, e.off)
+	pos = 0
+	n.Children = slices.DeleteFunc(n.Children, func(c Span) bool {
+		l := spanLen(c)
+		inside := l > 0 && pos >= s.off && pos+l <= e.off
+		pos += l
+		return inside
+	})
+	normalizeBlock(n)
+}
+
+// insertText inserts text at rune offset off of the block n.
+func insertText(n *Node, off int, text string) {
+	pos, before, at := 0, -1, -1
+	for j, c := range n.Children {
+		if c.Type != "span" || c.Text == nil {
+			continue
+		}
+		l := spanLen(c)
+		if pos < off && off == pos+l && before < 0 {
+			before = j
+		}
+		if pos <= off && off < pos+l || l == 0 && pos == off {
+			if at < 0 {
+				at = j
+			}
+		}
+		pos += l
+	}
+
+	j, rel := at, 0
+	if before >= 0 {
+		j, rel = before, spanLen(n.Children[before])
+	} else if j >= 0 {
+		pos = 0
+		for _, c := range n.Children[:j] {
+			pos += spanLen(c)
+		}
+		rel = off - pos
+	}
+	annotations := map[string]bool{}
+	for _, md := range n.MarkDefs {
+		annotations[md.Key] = true
+	}
+	if j >= 0 && !(before >= 0 && hasAnnotation(&n.Children[j], annotations)) {
+		runes := []rune(*n.Children[j].Text)
+		t := string(runes[:rel]) + text + string(runes[rel:])
+		n.Children[j].Text = &t
+		return
+	}
+
+	// Start a new span: after an annotation, with only its decorators.
+	s := Span{Type: "span", Text: &text, Marks: []string{}, Raw: map[string]any{"_key": NewKey()}}
+	at = len(n.Children)
+	if before >= 0 {
+		for _, m := range n.Children[before].Marks {
+			if !annotations[m] {
+				s.Marks = append(s.Marks, m)
+			}
+		}
+		at = before + 1
+	} else {
+		pos = 0
+		for k, c := range n.Children {
+			if pos >= off && c.Type != "span" {
+				at = k
+				break
+			}
+			pos += spanLen(c)
+		}
+	}
+	n.Children = slices.Insert(n.Children, at, s)
+}
+
+// annotationsOfType returns the keys of n's MarkDefs of type markType.
+func annotationsOfType(n *Node, markType string) map[string]bool {
+	keys := map[string]bool{}
+	for _, md := range n.MarkDefs {
+		if md.Type == markType {
+			keys[md.Key] = true
+		}
+	}
+	return keys
+}
+
+// normalizeBlock joins adjacent spans of n with the same marks, drops
+// empty spans (keeping one if the block would have no children) and
+// removes MarkDefs no span uses.
+func normalizeBlock(n *Node) {
+	var children, empty []Span
+	for _, c := range n.Children {
+		if isEmptySpan(c) && c.Text != nil {
+			empty = append(empty, c)
+			continue
+		}
+		if k := len(children) - 1; k >= 0 && c.Type == "span" && c.Text != nil &&
+			children[k].Type == "span" && children[k].Text != nil && sameMarks(children[k].Marks, c.Marks) {
+			t := *children[k].Text + *c.Text
+			children[k].Text = &t
+			continue
+		}
+		children = append(children, c)
+	}
+	if len(children) == 0 && len(empty) > 0 {
+		children = empty[:1]
+	}
+	n.Children = withSpan(children)
+	used := usedMarks(n.Children)
+	n.MarkDefs = slices.DeleteFunc(n.MarkDefs, func(md MarkDef) bool { return !used[md.Key] })
+}
+
+// emptyBlock returns a new normal block with one empty span.
+func emptyBlock() *Node {
+	n := NewBlock("normal")
+	n.Key = NewKey()
+	n.Children = withSpan(nil)
+	return n
+}
+
+// spanLen returns the length in runes of a span's text, 0 for inline
+// objects.
+func spanLen(c Span) int {
+	if c.Type != "span" || c.Text == nil {
+		return 0
+	}
+	return utf8.RuneCountInString(*c.Text)
+}
+
+// textLen returns the length in runes of a block's text.
+func textLen(n *Node) int {
+	l := 0
+	for _, c := range n.Children {
+		l += spanLen(c)
+	}
+	return l
+}
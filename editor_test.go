@@ -0,0 +1,422 @@
+package portabletext
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// editorTestDoc is:
+//
+//	[a] "Hello " "bold"(strong) " world"
+//	[b] "A " "link"(l1) " here"
+//	[img]
+//	[c] "Last 👍🏽"
+func editorTestDoc() Document {
+	a := NewBlock("normal").AddSpan("Hello ").AddSpan("bold", "strong").AddSpan(" world")
+	b := NewBlock("normal").
+		AddMarkDef("l1", "link", map[string]any{"href": "https://example.com"}).
+		AddSpan("A ").AddSpan("link", "l1").AddSpan(" here")
+	img := NewNode("image")
+	img.Key = "img"
+	c := NewBlock("normal").AddSpan("Last 👍🏽")
+	return Document{*withKeys(a, "a", "s1", "s2", "s3"), *withKeys(b, "b", "t1", "t2", "t3"), *img, *withKeys(c, "c", "u1")}
+}
+
+// selRange returns a selection between block-relative UTF-16 offsets.
+func selRange(anchorKey string, anchor int, focusKey string, focus int) Selection {
+	return Selection{Anchor: SelectionPoint{BlockKey: anchorKey, Offset: anchor}, Focus: SelectionPoint{BlockKey: focusKey, Offset: focus}}
+}
+
+func caretAt(key string, off int) Selection { return selRange(key, off, key, off) }
+
+// caretOffset returns the block key and rune offset of a collapsed selection.
+func caretOffset(t *testing.T, doc Document, sel Selection) (string, int) {
+	t.Helper()
+	if !sel.IsCollapsed() {
+		t.Fatalf("selection %+v is not collapsed", sel)
+	}
+	off, err := sel.Focus.BlockOffset(doc, UnitRune)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sel.Focus.BlockKey, off
+}
+
+func TestInsertText(t *testing.T) {
+	doc := editorTestDoc()
+	before, _ := EncodeString(doc)
+
+	out, sel, err := InsertText(doc, caretAt("a", 10), "er")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := spanTexts(&out[0]); !slices.Equal(got, []string{"Hello ", "bolder", " world"}) {
+		t.Errorf("typing after bold = %q", got)
+	}
+	if k, off := caretOffset(t, out, sel); k != "a" || off != 12 {
+		t.Errorf("caret = %s:%d", k, off)
+	}
+
+	out, _, err = InsertText(doc, caretAt("b", 6), "s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := spanTexts(&out[1]); !slices.Equal(got, []string{"A ", "link", "s here"}) {
+		t.Errorf("typing after a link = %q", got)
+	}
+
+	for _, s := range []Selection{selRange("a", 6, "b", 2), selRange("b", 2, "a", 6)} {
+		out, sel, err = InsertText(doc, s, "X")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := nodeKeys(out); !slices.Equal(got, []string{"a", "img", "c"}) {
+			t.Fatalf("keys = %v", got)
+		}
+		if got := spanTexts(&out[0]); !slices.Equal(got, []string{"Hello X", "link", " here"}) {
+			t.Errorf("replace across blocks = %q", got)
+		}
+		if got := markDefKeys(&out[0]); !slices.Equal(got, []string{"l1"}) {
+			t.Errorf("markDefs = %v", got)
+		}
+		if k, off := caretOffset(t, out, sel); k != "a" || off != 7 {
+			t.Errorf("caret = %s:%d", k, off)
+		}
+	}
+
+	if _, _, err := InsertText(doc, caretAt("img", 0), "x"); !errors.Is(err, ErrNotBlock) {
+		t.Errorf("typing into an image: error = %v", err)
+	}
+	if _, _, err := InsertText(doc, caretAt("nope", 0), "x"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("unknown block: error = %v", err)
+	}
+	if after, _ := EncodeString(doc); after != before {
+		t.Errorf("input document modified")
+	}
+}
+
+func TestDeleteBackward(t *testing.T) {
+	doc := editorTestDoc()
+	tests := []struct {
+		name    string
+		doc     Document
+		sel     Selection
+		keys    []string
+		block   int
+		text    string
+		caret   string
+		caretAt int
+	}{
+		{"grapheme cluster", doc, caretAt("c", 9), []string{"a", "b", "img", "c"}, 3, "Last ", "c", 5},
+		{"inside text", doc, caretAt("a", 3), []string{"a", "b", "img", "c"}, 0, "Helo bold world", "a", 2},
+		{"custom node before", doc, caretAt("c", 0), []string{"a", "b", "c"}, 2, "Last 👍🏽", "c", 0},
+		{"merge with previous", doc, caretAt("b", 0), []string{"a", "img", "c"}, 0, "Hello bold worldA link here", "a", 16},
+		{"start of document", doc, caretAt("a", 0), []string{"a", "b", "img", "c"}, 0, "Hello bold world", "a", 0},
+		{"selected custom node", doc, caretAt("img", 0), []string{"a", "b", "c"}, 1, "A link here", "b", 11},
+		{"range", doc, selRange("a", 2, "a", 12), []string{"a", "b", "img", "c"}, 0, "Heorld", "a", 2},
+		{"range over a custom node", doc, selRange("c", 4, "b", 1), []string{"a", "b"}, 1, "A 👍🏽", "b", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, sel, err := DeleteBackward(tt.doc, tt.sel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := nodeKeys(out); !slices.Equal(got, tt.keys) {
+				t.Fatalf("keys = %v, want %v", got, tt.keys)
+			}
+			if got := out[tt.block].GetText(); got != tt.text {
+				t.Errorf("text = %q, want %q", got, tt.text)
+			}
+			if k, off := caretOffset(t, out, sel); k != tt.caret || off != tt.caretAt {
+				t.Errorf("caret = %s:%d, want %s:%d", k, off, tt.caret, tt.caretAt)
+			}
+		})
+	}
+}
+
+func TestDeleteInlineObjects(t *testing.T) {
+	// block returns a block keyed key whose children are spans, or inline
+	// icons for strings starting with "@", which become their keys.
+	block := func(key string, children ...string) Node {
+		n := NewBlock("normal")
+		n.Key = key
+		for _, c := range children {
+			if k, ok := strings.CutPrefix(c, "@"); ok {
+				n.Children = append(n.Children, Span{Type: "icon", Raw: map[string]any{"_key": k}})
+			} else {
+				n.AddSpan(c)
+			}
+		}
+		return *n
+	}
+	onObject := func(block, key string) Selection { return Caret(SelectionPoint{block, key, 0}) }
+
+	tests := []struct {
+		name    string
+		doc     Document
+		sel     Selection
+		forward bool
+		want    [][]string
+		caretAt int
+	}{
+		{"all text with a leading object", Document{block("k", "@i1", "abc")}, selRange("k", 0, "k", 3), false, [][]string{{""}}, 0},
+		{"whole document", Document{block("x", "@i1", "ab"), block("y", "cd", "@i2")}, selRange("x", 0, "y", 2), false, [][]string{{""}}, 0},
+		{"object inside", Document{block("k", "ab", "@i1", "cd")}, selRange("k", 1, "k", 3), false, [][]string{{"ad"}}, 1},
+		{"object at a middle edge", Document{block("k", "ab", "@i1", "cd")}, selRange("k", 0, "k", 2), false, [][]string{{"<icon>", "cd"}}, 0},
+		{"range ending on an object", Document{block("k", "ab", "@i1", "cd")}, Selection{Anchor: SelectionPoint{"k", "", 1}, Focus: SelectionPoint{"k", "i1", 0}}, false, [][]string{{"acd"}}, 1},
+		{"backspace on an object", Document{block("k", "ab", "@i1", "cd")}, onObject("k", "i1"), false, [][]string{{"abcd"}}, 2},
+		{"delete on an object", Document{block("k", "ab", "@i1", "cd")}, onObject("k", "i1"), true, [][]string{{"abcd"}}, 2},
+		{"backspace on a leading object", Document{block("k", "@i1", "@i2", "ab")}, onObject("k", "i1"), false, [][]string{{"<icon>", "ab"}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := DeleteBackward
+			if tt.forward {
+				cmd = DeleteForward
+			}
+			out, sel, err := cmd(tt.doc, tt.sel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got [][]string
+			for i := range out {
+				got = append(got, spanTexts(&out[i]))
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("children = %q, want %q", got, tt.want)
+			}
+			if _, off := caretOffset(t, out, sel); off != tt.caretAt {
+				t.Errorf("caret = %d, want %d", off, tt.caretAt)
+			}
+		})
+	}
+}
+
+func TestDeleteForward(t *testing.T) {
+	doc := editorTestDoc()
+	tests := []struct {
+		name    string
+		sel     Selection
+		keys    []string
+		block   int
+		text    string
+		caret   string
+		caretAt int
+	}{
+		{"inside text", caretAt("a", 0), []string{"a", "b", "img", "c"}, 0, "ello bold world", "a", 0},
+		{"grapheme cluster", caretAt("c", 5), []string{"a", "b", "img", "c"}, 3, "Last ", "c", 5},
+		{"merge with next", caretAt("a", 16), []string{"a", "img", "c"}, 0, "Hello bold worldA link here", "a", 16},
+		{"custom node after", caretAt("b", 11), []string{"a", "b", "c"}, 1, "A link here", "b", 11},
+		{"selected custom node", caretAt("img", 0), []string{"a", "b", "c"}, 2, "Last 👍🏽", "c", 0},
+		{"end of document", caretAt("c", 9), []string{"a", "b", "img", "c"}, 3, "Last 👍🏽", "c", 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, sel, err := DeleteForward(doc, tt.sel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := nodeKeys(out); !slices.Equal(got, tt.keys) {
+				t.Fatalf("keys = %v, want %v", got, tt.keys)
+			}
+			if got := out[tt.block].GetText(); got != tt.text {
+				t.Errorf("text = %q, want %q", got, tt.text)
+			}
+			if k, off := caretOffset(t, out, sel); k != tt.caret || off != tt.caretAt {
+				t.Errorf("caret = %s:%d, want %s:%d", k, off, tt.caret, tt.caretAt)
+			}
+		})
+	}
+
+	out, _, err := DeleteForward(doc, selRange("a", 0, "c", 9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 || out[0].Key != "a" || out[0].GetText() != "" || len(out[0].Children) != 1 {
+		t.Errorf("delete everything = %v %q", nodeKeys(out), spanTexts(&out[0]))
+	}
+	if _, _, err := DeleteForward(Document{*NewNode("image")}, caretAt("", 0)); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("node without a key: error = %v", err)
+	}
+}
+
+func TestToggleDecorator(t *testing.T) {
+	doc := editorTestDoc()
+	tests := []struct {
+		name      string
+		sel       Selection
+		dec       string
+		want      []string
+		roundTrip bool // toggling again restores the original
+	}{
+		{"add", selRange("a", 0, "a", 5), "em", []string{"Hello", " ", "bold", " world"}, true},
+		{"remove", selRange("a", 6, "a", 10), "strong", []string{"Hello bold world"}, true},
+		{"extend", selRange("a", 7, "a", 14), "strong", []string{"Hello ", "bold wor", "ld"}, false},
+		{"collapsed", caretAt("a", 7), "strong", []string{"Hello ", "bold", " world"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, sel, err := ToggleDecorator(doc, tt.sel, tt.dec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := spanTexts(&out[0]); !slices.Equal(got, tt.want) {
+				t.Errorf("children = %q, want %q", got, tt.want)
+			}
+			a, _ := sel.Anchor.BlockOffset(out, UnitUTF16)
+			f, _ := sel.Focus.BlockOffset(out, UnitUTF16)
+			if a != tt.sel.Anchor.Offset || f != tt.sel.Focus.Offset {
+				t.Errorf("selection = %d-%d, want %d-%d", a, f, tt.sel.Anchor.Offset, tt.sel.Focus.Offset)
+			}
+			if !tt.roundTrip {
+				return
+			}
+			back, _, err := ToggleDecorator(out, sel, tt.dec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := spanTexts(&back[0]); !slices.Equal(got, spanTexts(&doc[0])) {
+				t.Errorf("toggled twice = %q", got)
+			}
+		})
+	}
+
+	out, sel, err := ToggleDecorator(doc, selRange("c", 5, "a", 12), "em")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sel.Backward {
+		t.Errorf("backward selection lost")
+	}
+	if got := spanTexts(&out[1]); !slices.Equal(got, []string{"A ", "link", " here"}) || !slices.Contains(out[1].Children[1].Marks, "em") {
+		t.Errorf("middle block = %q %v", got, out[1].Children[1].Marks)
+	}
+	if got := spanTexts(&out[3]); !slices.Equal(got, []string{"Last ", "👍🏽"}) {
+		t.Errorf("last block = %q", got)
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	doc := editorTestDoc()
+	out, _, err := AddAnnotation(doc, selRange("a", 6, "b", 1), "link", map[string]any{"href": "https://example.org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out[0].MarkDefs) != 1 || out[0].MarkDefs[0].Raw["href"] != "https://example.org" {
+		t.Fatalf("first block markDefs = %+v", out[0].MarkDefs)
+	}
+	k := out[0].MarkDefs[0].Key
+	if got := spanTexts(&out[0]); !slices.Equal(got, []string{"Hello ", "bold", " world"}) ||
+		!slices.Equal(out[0].Children[1].Marks, []string{"strong", k}) || !slices.Equal(out[0].Children[2].Marks, []string{k}) {
+		t.Errorf("first block = %q %v", got, out[0].Children)
+	}
+	if got := spanTexts(&out[1]); !slices.Equal(got, []string{"A", " ", "link", " here"}) || len(out[1].MarkDefs) != 2 {
+		t.Errorf("second block = %q %+v", got, out[1].MarkDefs)
+	}
+
+	// Re-linking replaces the link on the selected text.
+	out, _, err = AddAnnotation(doc, selRange("b", 2, "b", 6), "link", map[string]any{"href": "https://example.net"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out[1].MarkDefs) != 1 || out[1].MarkDefs[0].Key == "l1" {
+		t.Errorf("re-link markDefs = %+v", out[1].MarkDefs)
+	}
+
+	for _, s := range []Selection{caretAt("b", 4), caretAt("b", 6), selRange("b", 0, "b", 3)} {
+		out, _, err = RemoveAnnotation(doc, s, "link")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := spanTexts(&out[1]); !slices.Equal(got, []string{"A link here"}) || len(out[1].MarkDefs) != 0 {
+			t.Errorf("RemoveAnnotation(%+v) = %q %+v", s, got, out[1].MarkDefs)
+		}
+	}
+	out, _, err = RemoveAnnotation(doc, caretAt("b", 1), "link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out[1].MarkDefs) != 1 {
+		t.Errorf("caret away from the link removed it")
+	}
+}
+
+func TestEditorLiteralSpans(t *testing.T) {
+	text, style := "Hello", "normal"
+	doc := Document{{Type: "block", Key: "k", Style: &style, Children: []Span{{Type: "span", Text: &text}}}}
+	sel := selRange("k", 1, "k", 3)
+
+	tests := []struct {
+		name string
+		cmd  func() (Document, Selection, error)
+		want []string
+	}{
+		{"ToggleDecorator", func() (Document, Selection, error) { return ToggleDecorator(doc, sel, "strong") }, []string{"H", "el", "lo"}},
+		{"AddAnnotation", func() (Document, Selection, error) { return AddAnnotation(doc, sel, "link", nil) }, []string{"H", "el", "lo"}},
+		{"InsertText", func() (Document, Selection, error) { return InsertText(doc, sel, "i") }, []string{"Hilo"}},
+		{"DeleteBackward", func() (Document, Selection, error) { return DeleteBackward(doc, sel) }, []string{"Hlo"}},
+		{"DeleteForward", func() (Document, Selection, error) { return DeleteForward(doc, sel) }, []string{"Hlo"}},
+	}
+	for _, tt := range tests {
+		out, _, err := tt.cmd()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := spanTexts(&out[0]); !slices.Equal(got, tt.want) {
+			t.Errorf("%s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if text != "Hello" || doc[0].Children[0].Raw != nil {
+		t.Errorf("input modified")
+	}
+}
+
+func TestBlockCommands(t *testing.T) {
+	doc := editorTestDoc()
+	out, _, err := ToggleList(doc, selRange("a", 3, "b", 0), "bullet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{0, 1} {
+		if out[i].ListItem == nil || *out[i].ListItem != "bullet" || out[i].GetListLevel() != 1 {
+			t.Errorf("block %d not a bullet", i)
+		}
+	}
+	if out[3].ListItem != nil {
+		t.Errorf("unselected block changed")
+	}
+	back, _, err := ToggleList(out, selRange("a", 3, "b", 0), "bullet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back[0].ListItem != nil || back[0].Level != nil {
+		t.Errorf("toggle off: %+v", back[0])
+	}
+	mixed, _, err := ToggleList(out, selRange("b", 0, "c", 0), "bullet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mixed[1].ListItem == nil || mixed[3].ListItem == nil {
+		t.Errorf("mixed selection should become a list")
+	}
+
+	out, sel, err := SetStyle(doc, selRange("a", 2, "c", 1), "h2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{0, 1, 3} {
+		if out[i].GetStyle() != "h2" {
+			t.Errorf("block %d style = %q", i, out[i].GetStyle())
+		}
+	}
+	if out[2].Style != nil || doc[0].GetStyle() != "normal" {
+		t.Errorf("image or input changed")
+	}
+	if sel.Anchor.BlockKey != "a" || sel.Focus.BlockKey != "c" {
+		t.Errorf("selection = %+v", sel)
+	}
+}
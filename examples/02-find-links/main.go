@@ -34,38 +34,22 @@ func main() {
 	}
 
 	// Find all links
-	linkCount := 0
-	err = portabletext.Walk(doc, func(node *portabletext.Node) error {
-		for _, md := range node.MarkDefs {
-			if md.Type == "link" {
-				linkCount++
-
-				// Extract link details
-				href, _ := md.Raw["href"].(string)
-				title, _ := md.Raw["title"].(string)
-
-				fmt.Printf("[%d] Key: %s\n", linkCount, md.Key)
-				fmt.Printf("    URL: %s\n", href)
-				if title != "" {
-					fmt.Printf("    Title: %s\n", title)
-				}
-
-				// Find text using this link
-				for _, child := range node.Children {
-					if child.HasMark(md.Key) && child.Text != nil {
-						fmt.Printf("    Text: %s\n", *child.Text)
-					}
-				}
-				fmt.Println()
-			}
+	links := portabletext.ExtractLinks(doc)
+	for i, link := range links {
+		title, _ := link.MarkDef.Raw["title"].(string)
+
+		fmt.Printf("[%d] Key: %s\n", i+1, link.MarkDef.Key)
+		fmt.Printf("    URL: %s\n", link.Href)
+		if title != "" {
+			fmt.Printf("    Title: %s\n", title)
 		}
-		return nil
-	})
-
-	if err != nil {
-		log.Fatalf("Walk failed: %v", err)
+		if link.Text != "" {
+			fmt.Printf("    Text: %s\n", link.Text)
+		}
+		fmt.Println()
 	}
 
+	linkCount := len(links)
 	if linkCount == 0 {
 		fmt.Println("No links found")
 	} else {
@@ -0,0 +1,377 @@
+package portabletext
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUntranslated = errors.New("no translation")
+	ErrFuzzy        = errors.New("translation marked fuzzy")
+)
+
+// poTag matches the numbered tags of a gettext message: <0>, </0> and <0/>.
+var poTag = regexp.MustCompile(`<(/?)(\d{1,9})(/?)>`)
+
+// poMessage returns the msgid for a block: its text with marks as
+// numbered tags ("<0>bold</0>") and inline objects as "<1/>". Text that
+// looks like a tag is escaped as "\<0>".
+func poMessage(n *Node) string {
+	parts, _ := inlineParts(n)
+	var sb strings.Builder
+	for i, p := range parts {
+		switch p.kind {
+		case partText:
+			sb.WriteString(poEscapeText(p.text, i+1 < len(parts) && parts[i+1].kind != partText))
+		case partOpen:
+			fmt.Fprintf(&sb, "<%d>", p.code)
+		case partClose:
+			fmt.Fprintf(&sb, "</%d>", p.code)
+		case partPlaceholder:
+			fmt.Fprintf(&sb, "<%d/>", p.code)
+		}
+	}
+	return sb.String()
+}
+
+// poEscapeText escapes text so that poChildren reads it back literally: a
+// backslash goes before each tag-like sequence, and a run of backslashes
+// before a tag, literal or real, is doubled. tagFollows reports whether a
+// real tag comes after text.
+func poEscapeText(text string, tagFollows bool) string {
+	var sb strings.Builder
+	last := 0
+	for _, m := range poTag.FindAllStringIndex(text, -1) {
+		sb.WriteString(text[last:m[0]])
+		sb.WriteString(strings.Repeat(`\`, trailingBackslashes(text[last:m[0]])+1))
+		sb.WriteString(text[m[0]:m[1]])
+		last = m[1]
+	}
+	sb.WriteString(text[last:])
+	if tagFollows {
+		sb.WriteString(strings.Repeat(`\`, trailingBackslashes(text[last:])))
+	}
+	return sb.String()
+}
+
+func trailingBackslashes(s string) int {
+	return len(s) - len(strings.TrimRight(s, `\`))
+}
+
+// ExportPOT extracts the text of doc as a gettext template (.pot) with one
+// message per block. Marks become numbered tags such as "<0>bold</0>" and
+// inline objects self-closing tags such as "<1/>"; an extracted comment
+// tells translators what each tag stands for. Text that looks like a tag
+// is escaped with a backslash, as in "\<0>". Blocks with the same
+// message share one entry, referenced by their _keys (or paths, for
+// blocks without a key). Custom nodes are not exported.
+func ExportPOT(doc Document) []byte {
+	type entry struct {
+		msgid string
+		node  *Node
+		refs  []string
+	}
+	var entries []*entry
+	byID := map[string]*entry{}
+	for i := range doc {
+		n := &doc[i]
+		if !n.IsBlock() || strings.TrimSpace(n.GetText()) == "" {
+			continue
+		}
+		ref := n.Key
+		if ref == "" {
+			ref = nodePath(i).String()
+		}
+		id := poMessage(n)
+		if e, ok := byID[id]; ok {
+			e.refs = append(e.refs, ref)
+			continue
+		}
+		e := &entry{msgid: id, node: n, refs: []string{ref}}
+		byID[id] = e
+		entries = append(entries, e)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("msgid \"\"\nmsgstr \"\"\n")
+	b.WriteString("\"MIME-Version: 1.0\\n\"\n")
+	b.WriteString("\"Content-Type: text/plain; charset=UTF-8\\n\"\n")
+	b.WriteString("\"Content-Transfer-Encoding: 8bit\\n\"\n")
+	for _, e := range entries {
+		b.WriteString("\n")
+		if c := poTagComment(e.node); c != "" {
+			fmt.Fprintf(&b, "#. %s\n", c)
+		}
+		fmt.Fprintf(&b, "#: %s\n", strings.Join(e.refs, " "))
+		writePOString(&b, "msgid", e.msgid)
+		b.WriteString("msgstr \"\"\n")
+	}
+	return b.Bytes()
+}
+
+// poTagComment describes the tags of a block's message, e.g.
+// "<0>: strong, <1>: link, <2/>: image".
+func poTagComment(n *Node) string {
+	_, codes := inlineParts(n)
+	annotations := map[string]string{}
+	for _, md := range n.MarkDefs {
+		annotations[md.Key] = md.Type
+	}
+	var desc []string
+	for k, c := range codes {
+		switch {
+		case c.ph:
+			desc = append(desc, fmt.Sprintf("<%d/>: %s", k, n.Children[c.child].Type))
+		case annotations[c.mark] != "":
+			desc = append(desc, fmt.Sprintf("<%d>: %s", k, annotations[c.mark]))
+		default:
+			desc = append(desc, fmt.Sprintf("<%d>: %s", k, c.mark))
+		}
+	}
+	return strings.Join(desc, ", ")
+}
+
+// writePOString writes a keyword and its quoted string, splitting
+// multi-line strings after each newline as xgettext does.
+func writePOString(b *bytes.Buffer, keyword, s string) {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= 1 {
+		fmt.Fprintf(b, "%s %s\n", keyword, poQuote(s))
+		return
+	}
+	fmt.Fprintf(b, "%s \"\"\n", keyword)
+	for _, l := range lines {
+		fmt.Fprintf(b, "%s\n", poQuote(l))
+	}
+}
+
+var poEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+
+func poQuote(s string) string { return `"` + poEscaper.Replace(s) + `"` }
+
+// poUnquote decodes a C-style quoted PO string.
+func poUnquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("expected quoted string, got %s", s)
+	}
+	s = s[1 : len(s)-1]
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i == len(s) {
+			return "", errors.New("unterminated escape")
+		}
+		switch s[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'a':
+			sb.WriteByte('\a')
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'v':
+			sb.WriteByte('\v')
+		case '\\', '"', '\'', '?':
+			sb.WriteByte(s[i])
+		default:
+			return "", fmt.Errorf("unknown escape \\%c", s[i])
+		}
+	}
+	return sb.String(), nil
+}
+
+// poEntry is a message read from a .po file.
+type poEntry struct {
+	ctx, id, str string
+	hasCtx       bool
+	fuzzy        bool
+}
+
+// parsePO reads the messages of a .po file, keyed by msgid. Entries with a
+// msgctxt, obsolete entries and the header are skipped; for plural
+// entries the first form is used.
+func parsePO(r io.Reader) (map[string]poEntry, error) {
+	entries := map[string]poEntry{}
+	var cur poEntry
+	var field *string // where continuation lines go
+	started := false
+	inStr := false
+	finish := func() {
+		if started && !cur.hasCtx && cur.id != "" {
+			entries[cur.id] = cur
+		}
+		cur, field, started, inStr = poEntry{}, nil, false, false
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 16*1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		l := strings.TrimSpace(sc.Text())
+		if line == 1 {
+			l = strings.TrimPrefix(l, "\ufeff")
+		}
+		var err error
+		switch {
+		case l == "":
+			finish()
+		case strings.HasPrefix(l, "#~"):
+			field = nil // obsolete entry
+		case strings.HasPrefix(l, "#,"):
+			if inStr {
+				finish()
+			}
+			cur.fuzzy = cur.fuzzy || strings.Contains(l, "fuzzy")
+		case strings.HasPrefix(l, "#"):
+			if inStr {
+				finish()
+			}
+		case strings.HasPrefix(l, `"`):
+			if field == nil {
+				return nil, wrap("gettext", fmt.Sprintf("line %d", line), errors.New("string without keyword"))
+			}
+			var s string
+			s, err = poUnquote(l)
+			*field += s
+		default:
+			keyword, rest, _ := strings.Cut(l, " ")
+			rest = strings.TrimSpace(rest)
+			if (keyword == "msgctxt" || keyword == "msgid") && inStr {
+				finish()
+			}
+			started = true
+			var s string
+			if s, err = poUnquote(rest); err != nil {
+				break
+			}
+			switch {
+			case keyword == "msgctxt":
+				cur.ctx, cur.hasCtx, field = s, true, &cur.ctx
+			case keyword == "msgid":
+				cur.id, field = s, &cur.id
+			case keyword == "msgstr" || keyword == "msgstr[0]":
+				cur.str, field, inStr = s, &cur.str, true
+			case keyword == "msgid_plural":
+				field = new(string)
+			case strings.HasPrefix(keyword, "msgstr["):
+				field, inStr = new(string), true
+			default:
+				err = fmt.Errorf("unknown keyword %q", keyword)
+			}
+		}
+		if err != nil {
+			return nil, wrap("gettext", fmt.Sprintf("line %d", line), err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, wrap("gettext", "", err)
+	}
+	finish()
+	return entries, nil
+}
+
+// MergePO localizes doc with the translations in a .po file made from
+// ExportPOT, returning a copy in which each translated block keeps its
+// _key, style and MarkDefs. Translated tags get the marks and inline
+// objects of the source block, and span keys are reused in order.
+//
+// Blocks whose message is missing, untranslated, fuzzy or has tags that
+// do not match the source keep their source text. Each fallback is
+// reported as an *Error with the block (or span) path, wrapping
+// ErrUntranslated, ErrFuzzy or ErrPlaceholderMismatch. The error result is
+// only for unreadable .po files.
+func MergePO(doc Document, r io.Reader) (Document, []error, error) {
+	entries, err := parsePO(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := make(Document, len(doc))
+	var report []error
+	for i := range doc {
+		out[i] = *doc[i].Clone()
+		n := &doc[i]
+		if !n.IsBlock() || strings.TrimSpace(n.GetText()) == "" {
+			continue
+		}
+		id := poMessage(n)
+		e, ok := entries[id]
+		switch {
+		case !ok || e.str == "":
+			report = append(report, wrap("gettext", nodePath(i).String(), fmt.Errorf("%w: %q", ErrUntranslated, id)))
+		case e.fuzzy:
+			report = append(report, wrap("gettext", nodePath(i).String(), fmt.Errorf("%w: %q", ErrFuzzy, id)))
+		default:
+			children, errs := poChildren(n, i, e.str)
+			if len(errs) > 0 {
+				report = append(report, errs...)
+				continue
+			}
+			out[i].Children = children
+		}
+	}
+	return out, report, nil
+}
+
+// poChildren rebuilds the children of doc[i], the block n, from a
+// translated message.
+func poChildren(n *Node, i int, msg string) ([]Span, []error) {
+	b := newSpanBuilder("gettext", n, i, func(k int, ph bool) string {
+		if ph {
+			return fmt.Sprintf("<%d/>", k)
+		}
+		return fmt.Sprintf("<%d>", k)
+	})
+	var open []int
+	last := 0
+	for _, m := range poTag.FindAllStringSubmatchIndex(msg, -1) {
+		// An odd run of backslashes escapes the tag; halve the run either way.
+		text := msg[last:m[0]]
+		run := trailingBackslashes(text)
+		text = text[:len(text)-run] + strings.Repeat(`\`, run/2)
+		last = m[1]
+		closing, selfClosing := m[3] > m[2], m[7] > m[6]
+		if run%2 == 1 || closing && selfClosing {
+			b.write(text + msg[m[0]:m[1]]) // "</0/>" is not a tag either
+			continue
+		}
+		b.write(text)
+		k, _ := strconv.Atoi(msg[m[4]:m[5]])
+		switch {
+		case selfClosing:
+			b.placeholder(k)
+		case !closing:
+			open = append(open, k)
+			b.open(k)
+		case len(open) == 0 || open[len(open)-1] != k:
+			b.mismatch(nodePath(i), "</%d> does not close the innermost tag", k)
+		default:
+			open = open[:len(open)-1]
+			b.close()
+		}
+	}
+	b.write(msg[last:])
+	for _, k := range open {
+		b.mismatch(nodePath(i), "<%d> is not closed", k)
+	}
+	return b.finish()
+}
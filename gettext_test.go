@@ -0,0 +1,226 @@
+package portabletext
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func gettextTestDoc() Document {
+	b := NewBlock("normal").
+		AddMarkDef("l1", "link", map[string]any{"href": "/help"}).
+		AddSpan("Press ").
+		AddSpan("Save", "strong").
+		AddSpan(" or see ").
+		AddSpan("help", "l1")
+	b.Key = "b1"
+	b.Children = append(b.Children, Span{Type: "icon", Raw: map[string]any{"name": "info"}})
+	for j := range b.Children {
+		b.Children[j].Raw["_key"] = "s" + string(rune('1'+j))
+	}
+
+	cancel := func(key string) Node {
+		n := NewBlock("normal").AddSpan("Cancel")
+		n.Key = key
+		return *n
+	}
+	multi := NewBlock("normal").AddSpan("Line one\nLine \"two\"")
+	return Document{*b, cancel("c1"), *NewNode("image"), cancel("c2"), *multi}
+}
+
+func TestExportPOT(t *testing.T) {
+	got := string(ExportPOT(gettextTestDoc()))
+	want := `msgid ""
+msgstr ""
+"MIME-Version: 1.0\n"
+"Content-Type: text/plain; charset=UTF-8\n"
+"Content-Transfer-Encoding: 8bit\n"
+
+#. <0>: strong, <1>: link, <2/>: icon
+#: b1
+msgid "Press <0>Save</0> or see <1>help</1><2/>"
+msgstr ""
+
+#: c1 c2
+msgid "Cancel"
+msgstr ""
+
+#: [4]
+msgid ""
+"Line one\n"
+"Line \"two\""
+msgstr ""
+`
+	if got != want {
+		t.Errorf("ExportPOT() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMergePO(t *testing.T) {
+	doc := gettextTestDoc()
+	po := `# German
+msgid ""
+msgstr ""
+"Language: de\n"
+
+#: b1
+msgid "Press <0>Save</0> or see <1>help</1><2/>"
+msgstr "<2/>Drücken Sie <0>Speichern</0> "
+"oder lesen Sie die <1>Hilfe</1>"
+
+#, fuzzy
+msgid "Cancel"
+msgstr "Abbrechen"
+
+msgid ""
+"Line one\n"
+"Line \"two\""
+msgstr "Zeile eins\nZeile \"zwei\""
+`
+	got, report, err := MergePO(doc, strings.NewReader(po))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := got[0]
+	if b.GetText() != "Drücken Sie Speichern oder lesen Sie die Hilfe" || b.Key != "b1" {
+		t.Errorf("block 0 = %q (%s)", b.GetText(), b.Key)
+	}
+	var shape []string
+	for _, c := range b.Children {
+		shape = append(shape, c.Type+":"+strings.Join(c.Marks, "+"))
+	}
+	if !slices.Equal(shape, []string{"icon:", "span:", "span:strong", "span:", "span:l1"}) {
+		t.Errorf("children = %v", shape)
+	}
+	if b.Children[0].Raw["name"] != "info" || b.Children[1].Raw["_key"] != "s1" || len(b.MarkDefs) != 1 {
+		t.Errorf("inline object, span keys or markDefs not kept: %+v", b)
+	}
+	if got[4].GetText() != "Zeile eins\nZeile \"zwei\"" {
+		t.Errorf("multi-line = %q", got[4].GetText())
+	}
+
+	// Fuzzy entries fall back to the source text and are reported.
+	if got[1].GetText() != "Cancel" || got[3].GetText() != "Cancel" {
+		t.Errorf("fuzzy translation used: %q", got[1].GetText())
+	}
+	var paths []string
+	for _, e := range report {
+		var pe *Error
+		if !errors.As(e, &pe) || !errors.Is(e, ErrFuzzy) {
+			t.Errorf("report entry %v, want fuzzy *Error", e)
+			continue
+		}
+		paths = append(paths, pe.Path)
+	}
+	if !slices.Equal(paths, []string{"[1]", "[3]"}) {
+		t.Errorf("report paths = %v", paths)
+	}
+	if doc[0].GetText() != "Press Save or see help" {
+		t.Error("MergePO modified its input")
+	}
+}
+
+func TestMergePOFallbacks(t *testing.T) {
+	doc := gettextTestDoc()
+	tests := []struct {
+		name   string
+		msgstr string
+		want   error
+		paths  []string
+	}{
+		{"untranslated", `""`, ErrUntranslated, []string{"[0]"}},
+		{"lost tag", `"Drücken <0>Speichern</0><2/>"`, ErrPlaceholderMismatch, []string{"[0].children[3]"}},
+		{"unknown tag", `"<0>S</0> <1>h</1><2/><7/>"`, ErrPlaceholderMismatch, []string{"[0]"}},
+		{"tag kind", `"<0>S</0> <1/><2/>"`, ErrPlaceholderMismatch, []string{"[0]", "[0].children[3]"}},
+		{"crossed", `"<0>S <1>h</0></1><2/>"`, ErrPlaceholderMismatch, []string{"[0]", "[0]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			po := "msgid \"Press <0>Save</0> or see <1>help</1><2/>\"\nmsgstr " + tt.msgstr + "\n"
+			got, report, err := MergePO(doc, strings.NewReader(po))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got[0].GetText() != "Press Save or see help" {
+				t.Errorf("text = %q, want source fallback", got[0].GetText())
+			}
+			var paths []string
+			for _, e := range report {
+				var pe *Error
+				if errors.As(e, &pe) && pe.Path[:3] == "[0]" {
+					if !errors.Is(e, tt.want) {
+						t.Errorf("report %v, want %v", e, tt.want)
+					}
+					paths = append(paths, pe.Path)
+				}
+			}
+			if !slices.Equal(paths, tt.paths) {
+				t.Errorf("paths = %v, want %v", paths, tt.paths)
+			}
+		})
+	}
+}
+
+func TestParsePO(t *testing.T) {
+	po := `msgid ""
+msgstr "Language: fr\n"
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir (menu)"
+msgid "Open"
+msgstr "Ouvrir"
+
+msgid "file"
+msgid_plural "files"
+msgstr[0] "fichier"
+msgstr[1] "fichiers"
+
+#~ msgid "Old"
+#~ msgstr "Vieux"
+`
+	entries, err := parsePO(strings.NewReader(po))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries["Open"].str != "Ouvrir" || entries["file"].str != "fichier" {
+		t.Errorf("parsePO() = %+v", entries)
+	}
+
+	for _, bad := range []string{"msgid \"x\nmsgstr \"\"\n", "\"orphan\"\n", "msgid \"\\q\"\n", "msgfoo \"x\"\n"} {
+		_, err := parsePO(strings.NewReader(bad))
+		var pe *Error
+		if !errors.As(err, &pe) || pe.Path != "line 1" {
+			t.Errorf("parsePO(%q) error = %v, want error at line 1", bad, err)
+		}
+	}
+}
+
+func TestPOLiteralTags(t *testing.T) {
+	b := NewBlock("normal").AddSpan(`Type <0> or a\`).AddSpan(`</1>`, "strong").AddSpan(`, not \<2/>`)
+	b.Key = "b1"
+	doc := Document{*b}
+
+	id := poMessage(&doc[0])
+	if want := `Type \<0> or a\\<0>\</1></0>, not \\\<2/>`; id != want {
+		t.Errorf("poMessage() = %s, want %s", id, want)
+	}
+	if !strings.Contains(string(ExportPOT(doc)), poQuote(id)) {
+		t.Errorf("ExportPOT() does not contain the escaped msgid")
+	}
+
+	// An identity translation reproduces the literal text and marks.
+	po := "msgid " + poQuote(id) + "\nmsgstr " + poQuote(id) + "\n"
+	got, report, err := MergePO(doc, strings.NewReader(po))
+	if err != nil || len(report) != 0 {
+		t.Fatalf("MergePO() = %v, %v", report, err)
+	}
+	var shape []string
+	for _, s := range got[0].Children {
+		shape = append(shape, *s.Text+strings.Join(s.Marks, ","))
+	}
+	if want := []string{`Type <0> or a\`, `</1>strong`, `, not \<2/>`}; !slices.Equal(shape, want) {
+		t.Errorf("children = %q, want %q", shape, want)
+	}
+}
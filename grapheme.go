@@ -0,0 +1,136 @@
+package portabletext
+
+import "unicode"
+
+// graphemeCategory is an approximation of the Unicode Grapheme_Cluster_Break
+// property, good enough for the text editors produce: combining marks,
+// emoji with modifiers and ZWJ sequences, flags and Hangul syllables.
+type graphemeCategory int
+
+const (
+	gcOther graphemeCategory = iota
+	gcCR
+	gcLF
+	gcControl
+	gcExtend
+	gcZWJ
+	gcRegionalIndicator
+	gcSpacingMark
+	gcL
+	gcV
+	gcT
+	gcLV
+	gcLVT
+	gcPictographic
+)
+
+func graphemeCategoryOf(r rune) graphemeCategory {
+	switch {
+	case r == '\r':
+		return gcCR
+	case r == '\n':
+		return gcLF
+	case r == 0x200D:
+		return gcZWJ
+	case r >= 0x1F1E6 && r <= 0x1F1FF:
+		return gcRegionalIndicator
+	case unicode.In(r, unicode.Mn, unicode.Me),
+		r == 0x200C,
+		r >= 0xFE00 && r <= 0xFE0F,   // variation selectors
+		r >= 0x1F3FB && r <= 0x1F3FF, // emoji skin tone modifiers
+		r >= 0xE0020 && r <= 0xE007F: // tags, as in subdivision flags
+		return gcExtend
+	case unicode.Is(unicode.Mc, r):
+		return gcSpacingMark
+	case unicode.In(r, unicode.Cc, unicode.Zl, unicode.Zp, unicode.Cf):
+		return gcControl
+	case r >= 0x1100 && r <= 0x115F, r >= 0xA960 && r <= 0xA97C:
+		return gcL
+	case r >= 0x1160 && r <= 0x11A7, r >= 0xD7B0 && r <= 0xD7C6:
+		return gcV
+	case r >= 0x11A8 && r <= 0x11FF, r >= 0xD7CB && r <= 0xD7FB:
+		return gcT
+	case r >= 0xAC00 && r <= 0xD7A3:
+		if (r-0xAC00)%28 == 0 {
+			return gcLV
+		}
+		return gcLVT
+	case isPictographic(r):
+		return gcPictographic
+	}
+	return gcOther
+}
+
+// isPictographic approximates Extended_Pictographic.
+func isPictographic(r rune) bool {
+	switch r {
+	case 0x00A9, 0x00AE, 0x203C, 0x2049, 0x2122, 0x2139, 0x2328, 0x23CF,
+		0x24C2, 0x25B6, 0x25C0, 0x2B50, 0x2B55, 0x3030, 0x303D, 0x3297, 0x3299:
+		return true
+	}
+	return r >= 0x2194 && r <= 0x21AA ||
+		r >= 0x231A && r <= 0x231B ||
+		r >= 0x23E9 && r <= 0x23FA ||
+		r >= 0x25AA && r <= 0x25AB ||
+		r >= 0x25FB && r <= 0x25FE ||
+		r >= 0x2600 && r <= 0x27BF ||
+		r >= 0x2934 && r <= 0x2935 ||
+		r >= 0x2B05 && r <= 0x2B1C ||
+		r >= 0x1F000 && r <= 0x1FAFF ||
+		r >= 0x1FC00 && r <= 0x1FFFD
+}
+
+// graphemeStarts returns the index in runes of the first rune of each
+// extended grapheme cluster, followed by len(runes).
+func graphemeStarts(runes []rune) []int {
+	starts := make([]int, 0, len(runes)+1)
+	var prev graphemeCategory
+	emoji := 0 // 1 after Pictographic Extend*, 2 after a ZWJ that follows one
+	ri := 0    // length of the current run of regional indicators
+	for i, r := range runes {
+		c := graphemeCategoryOf(r)
+		if i == 0 || graphemeBreak(prev, c, emoji == 2, ri%2 == 1) {
+			starts = append(starts, i)
+		}
+
+		switch {
+		case c == gcPictographic:
+			emoji = 1
+		case c == gcExtend && emoji == 1:
+		case c == gcZWJ && emoji == 1:
+			emoji = 2
+		default:
+			emoji = 0
+		}
+		if c == gcRegionalIndicator {
+			ri++
+		} else {
+			ri = 0
+		}
+		prev = c
+	}
+	return append(starts, len(runes))
+}
+
+// graphemeBreak reports whether there is a cluster boundary between runes
+// of categories prev and c.
+func graphemeBreak(prev, c graphemeCategory, emojiZWJ, oddRI bool) bool {
+	switch {
+	case prev == gcCR && c == gcLF:
+		return false
+	case prev == gcCR || prev == gcLF || prev == gcControl,
+		c == gcCR || c == gcLF || c == gcControl:
+		return true
+	case prev == gcL && (c == gcL || c == gcV || c == gcLV || c == gcLVT),
+		(prev == gcLV || prev == gcV) && (c == gcV || c == gcT),
+		(prev == gcLVT || prev == gcT) && c == gcT:
+		return false
+	case c == gcExtend || c == gcZWJ || c == gcSpacingMark:
+		return false
+	case prev == gcZWJ && c == gcPictographic && emojiZWJ:
+		return false
+	case prev == gcRegionalIndicator && c == gcRegionalIndicator && oddRI:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,337 @@
+package portabletext
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+	ErrConflict      = errors.New("operation does not match document")
+)
+
+// Operation is a change to the top level of a Document. A "splice"
+// replaces the nodes Remove at Index with Insert; a "move" moves the node
+// at Index so that it ends up at To. Operations carry only the nodes they
+// change, so their size follows the edit rather than the document.
+type Operation struct {
+	Type   string `json:"type"` // "splice" or "move"
+	Index  int    `json:"index"`
+	To     int    `json:"to,omitempty"`
+	Remove []Node `json:"remove,omitempty"`
+	Insert []Node `json:"insert,omitempty"`
+}
+
+// Invert returns the operation that undoes op.
+func (op Operation) Invert() Operation {
+	if op.Type == "move" {
+		return Operation{Type: "move", Index: op.To, To: op.Index}
+	}
+	return Operation{Type: op.Type, Index: op.Index, Remove: op.Insert, Insert: op.Remove}
+}
+
+// Apply applies op to doc, returning a new Document. It returns an error
+// wrapping ErrConflict if the nodes op removes are not the ones in doc.
+func (op Operation) Apply(doc Document) (Document, error) {
+	switch op.Type {
+	case "splice":
+		if op.Index < 0 || op.Index+len(op.Remove) > len(doc) {
+			return nil, wrap("history", "", fmt.Errorf("%w: splice of %d nodes at %d in a document of %d", ErrConflict, len(op.Remove), op.Index, len(doc)))
+		}
+		for k := range op.Remove {
+			if !sameNode(&doc[op.Index+k], &op.Remove[k]) {
+				return nil, wrap("history", nodePath(op.Index+k).String(), ErrConflict)
+			}
+		}
+		out := make(Document, 0, len(doc)-len(op.Remove)+len(op.Insert))
+		out = append(out, doc[:op.Index]...)
+		out = append(out, cloneNodes(op.Insert)...)
+		return append(out, doc[op.Index+len(op.Remove):]...), nil
+	case "move":
+		if op.Index < 0 || op.Index >= len(doc) || op.To < 0 || op.To >= len(doc) {
+			return nil, wrap("history", "", fmt.Errorf("%w: move from %d to %d in a document of %d", ErrConflict, op.Index, op.To, len(doc)))
+		}
+		out := slices.Delete(slices.Clone(doc), op.Index, op.Index+1)
+		return slices.Insert(out, op.To, doc[op.Index]), nil
+	}
+	return nil, wrap("history", "", fmt.Errorf("unknown operation type %q", op.Type))
+}
+
+func (op *Operation) UnmarshalJSON(b []byte) error {
+	var v struct {
+		Type   string            `json:"type"`
+		Index  int               `json:"index"`
+		To     int               `json:"to"`
+		Remove []json.RawMessage `json:"remove"`
+		Insert []json.RawMessage `json:"insert"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return wrap("history", "", err)
+	}
+	*op = Operation{Type: v.Type, Index: v.Index, To: v.To}
+	for _, f := range []struct {
+		name string
+		raw  []json.RawMessage
+		dst  *[]Node
+	}{{"remove", v.Remove, &op.Remove}, {"insert", v.Insert, &op.Insert}} {
+		for i, raw := range f.raw {
+			n, err := parseNode(raw, fmt.Sprintf("%s[%d]", f.name, i))
+			if err != nil {
+				return err
+			}
+			*f.dst = append(*f.dst, n)
+		}
+	}
+	return nil
+}
+
+// Diff returns the operations that turn a into b: none if they are equal,
+// a "move" if a single node changed place, and otherwise one "splice" of
+// the nodes between the longest unchanged prefix and suffix.
+func Diff(a, b Document) []Operation {
+	p := 0
+	for p < len(a) && p < len(b) && sameNode(&a[p], &b[p]) {
+		p++
+	}
+	s := 0
+	for s < len(a)-p && s < len(b)-p && sameNode(&a[len(a)-1-s], &b[len(b)-1-s]) {
+		s++
+	}
+	ra, rb := a[p:len(a)-s], b[p:len(b)-s]
+	if len(ra) == 0 && len(rb) == 0 {
+		return nil
+	}
+	if l := len(ra); l > 1 && l == len(rb) {
+		if sameNode(&ra[0], &rb[l-1]) && sameNodes(ra[1:], rb[:l-1]) {
+			return []Operation{{Type: "move", Index: p, To: p + l - 1}}
+		}
+		if sameNode(&ra[l-1], &rb[0]) && sameNodes(ra[:l-1], rb[1:]) {
+			return []Operation{{Type: "move", Index: p + l - 1, To: p}}
+		}
+	}
+	return []Operation{{Type: "splice", Index: p, Remove: cloneNodes(ra), Insert: cloneNodes(rb)}}
+}
+
+// sameNode reports whether a and b are equal, or encode to the same JSON,
+// as nodes built in Go and decoded from JSON may differ only in number
+// types and nil versus empty fields.
+func sameNode(a, b *Node) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	ja, err1 := json.Marshal(a)
+	jb, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && bytes.Equal(ja, jb)
+}
+
+func sameNodes(a, b []Node) bool {
+	return slices.EqualFunc(a, b, func(x, y Node) bool { return sameNode(&x, &y) })
+}
+
+func cloneNodes(in []Node) []Node {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]Node, len(in))
+	for i := range in {
+		out[i] = *in[i].Clone()
+	}
+	return out
+}
+
+// Transaction is a group of operations undone and redone together.
+type Transaction struct {
+	Label string      `json:"label,omitempty"`
+	Ops   []Operation `json:"ops"`
+}
+
+// History tracks a document through a series of edits with undo and redo.
+// Record each new version of the document; History stores the operations
+// between versions rather than copies. Use Begin and End to group edits,
+// such as a word of typing, into one undoable transaction.
+//
+// History keeps its own copy of the current document, so a document may be
+// edited in place, for example with Walk, and then recorded.
+//
+// A History is not safe for concurrent use.
+type History struct {
+	// Limit caps the number of transactions that can be undone; older ones
+	// are forgotten. Zero means no limit.
+	Limit int
+
+	doc   Document
+	undo  []Transaction
+	redo  []Transaction
+	open  *Transaction
+	depth int
+}
+
+// NewHistory starts a history at doc.
+func NewHistory(doc Document) *History { return &History{doc: cloneNodes(doc)} }
+
+// Document returns a copy of the current document.
+func (h *History) Document() Document { return cloneNodes(h.doc) }
+
+// Record makes next the current document, recording the operations from
+// the previous one as a transaction, or as part of the open one. Recording
+// an unchanged document does nothing. Recording clears the redo stack.
+func (h *History) Record(next Document) {
+	ops := Diff(h.doc, next)
+	if len(ops) == 0 {
+		return
+	}
+	h.record(ops)
+	h.doc = cloneNodes(next)
+}
+
+// Apply applies ops to the current document and records them.
+func (h *History) Apply(ops ...Operation) error {
+	doc := h.doc
+	for _, op := range ops {
+		var err error
+		if doc, err = op.Apply(doc); err != nil {
+			return err
+		}
+	}
+	h.record(ops)
+	h.doc = doc
+	return nil
+}
+
+func (h *History) record(ops []Operation) {
+	if len(ops) == 0 {
+		return
+	}
+	h.redo = nil
+	if h.open != nil {
+		h.open.Ops = append(h.open.Ops, ops...)
+		return
+	}
+	h.push(Transaction{Ops: ops})
+}
+
+func (h *History) push(t Transaction) {
+	h.undo = append(h.undo, t)
+	if h.Limit > 0 && len(h.undo) > h.Limit {
+		h.undo = slices.Delete(h.undo, 0, len(h.undo)-h.Limit)
+	}
+}
+
+// Begin opens a transaction: edits recorded until the matching End are
+// undone as one. Calls nest; only the outermost label is kept.
+func (h *History) Begin(label string) {
+	if h.depth == 0 {
+		h.open = &Transaction{Label: label}
+	}
+	h.depth++
+}
+
+// End closes the transaction opened by the matching Begin.
+func (h *History) End() {
+	if h.depth == 0 {
+		return
+	}
+	h.depth--
+	if h.depth == 0 {
+		if len(h.open.Ops) > 0 {
+			h.push(*h.open)
+		}
+		h.open = nil
+	}
+}
+
+// closeAll ends any open transaction.
+func (h *History) closeAll() {
+	for h.depth > 0 {
+		h.End()
+	}
+}
+
+// CanUndo reports whether there is a transaction to undo.
+func (h *History) CanUndo() bool {
+	return len(h.undo) > 0 || h.open != nil && len(h.open.Ops) > 0
+}
+
+// CanRedo reports whether there is an undone transaction to redo.
+func (h *History) CanRedo() bool { return len(h.redo) > 0 }
+
+// Undo reverts the last transaction, ending any open one first, and
+// returns the resulting document.
+func (h *History) Undo() (Document, error) {
+	h.closeAll()
+	if len(h.undo) == 0 {
+		return cloneNodes(h.doc), wrap("history", "", ErrNothingToUndo)
+	}
+	t := h.undo[len(h.undo)-1]
+	doc := h.doc
+	for i := len(t.Ops) - 1; i >= 0; i-- {
+		var err error
+		if doc, err = t.Ops[i].Invert().Apply(doc); err != nil {
+			return cloneNodes(h.doc), err
+		}
+	}
+	h.undo = h.undo[:len(h.undo)-1]
+	h.redo = append(h.redo, t)
+	h.doc = doc
+	return cloneNodes(doc), nil
+}
+
+// Redo reapplies the last undone transaction and returns the resulting
+// document.
+func (h *History) Redo() (Document, error) {
+	h.closeAll()
+	if len(h.redo) == 0 {
+		return cloneNodes(h.doc), wrap("history", "", ErrNothingToRedo)
+	}
+	t := h.redo[len(h.redo)-1]
+	doc := h.doc
+	for _, op := range t.Ops {
+		var err error
+		if doc, err = op.Apply(doc); err != nil {
+			return cloneNodes(h.doc), err
+		}
+	}
+	h.redo = h.redo[:len(h.redo)-1]
+	h.push(t)
+	h.doc = doc
+	return cloneNodes(doc), nil
+}
+
+// historyJSON is the serialized form of a History.
+type historyJSON struct {
+	Undo []Transaction `json:"undo"`
+	Redo []Transaction `json:"redo"`
+}
+
+// MarshalJSON encodes the undo and redo stacks, with an open transaction
+// as if it had ended. The document is not included: save it alongside.
+func (h *History) MarshalJSON() ([]byte, error) {
+	v := historyJSON{Undo: slices.Clone(h.undo), Redo: h.redo}
+	if h.open != nil && len(h.open.Ops) > 0 {
+		v.Undo = append(v.Undo, *h.open)
+	}
+	if v.Undo == nil {
+		v.Undo = []Transaction{}
+	}
+	if v.Redo == nil {
+		v.Redo = []Transaction{}
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON restores the undo and redo stacks. Unmarshal into a
+// History created by NewHistory with the document as it was when the
+// history was saved; a mismatch surfaces as ErrConflict on Undo or Redo.
+func (h *History) UnmarshalJSON(b []byte) error {
+	var v historyJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return wrap("history", "", err)
+	}
+	h.undo, h.redo, h.open, h.depth = v.Undo, v.Redo, nil, 0
+	return nil
+}
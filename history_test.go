@@ -0,0 +1,292 @@
+package portabletext
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func mustEncode(t *testing.T, doc Document) string {
+	t.Helper()
+	s, err := EncodeString(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// historyTestVersions records a split, some typing and a move, returning
+// the history and the encoded document after each step.
+func historyTestVersions(t *testing.T) (*History, []string) {
+	t.Helper()
+	doc := editorTestDoc()
+	h := NewHistory(doc)
+	versions := []string{mustEncode(t, doc)}
+
+	doc, err := SplitBlock(doc, "a", 6, UnitRune)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Record(doc)
+	versions = append(versions, mustEncode(t, doc))
+
+	doc, _, err = InsertText(doc, caretAt("b", 0), "Then ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Record(doc)
+	versions = append(versions, mustEncode(t, doc))
+
+	doc, err = Move(doc, "c", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Record(doc)
+	versions = append(versions, mustEncode(t, doc))
+	return h, versions
+}
+
+func TestHistoryUndoRedo(t *testing.T) {
+	h, versions := historyTestVersions(t)
+	if !h.CanUndo() || h.CanRedo() {
+		t.Fatalf("CanUndo/CanRedo = %v/%v", h.CanUndo(), h.CanRedo())
+	}
+	for i := len(versions) - 2; i >= 0; i-- {
+		doc, err := h.Undo()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := mustEncode(t, doc); got != versions[i] {
+			t.Errorf("undo to version %d:\n got %s\nwant %s", i, got, versions[i])
+		}
+	}
+	if _, err := h.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("Undo at the start: error = %v", err)
+	}
+	for i := 1; i < len(versions); i++ {
+		doc, err := h.Redo()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := mustEncode(t, doc); got != versions[i] {
+			t.Errorf("redo to version %d:\n got %s\nwant %s", i, got, versions[i])
+		}
+	}
+	if _, err := h.Redo(); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("Redo at the end: error = %v", err)
+	}
+
+	// A new edit after undo discards the redo stack.
+	doc, _ := h.Undo()
+	doc, _ = Remove(doc, "img")
+	h.Record(doc)
+	if h.CanRedo() {
+		t.Errorf("CanRedo after a new edit")
+	}
+	h.Record(doc)
+	if doc, _ := h.Undo(); mustEncode(t, doc) != versions[2] {
+		t.Errorf("recording an unchanged document added a transaction")
+	}
+}
+
+func TestHistoryInPlaceEdits(t *testing.T) {
+	doc := editorTestDoc()
+	start := mustEncode(t, doc)
+	h := NewHistory(doc)
+
+	// Edit the recorded document in place.
+	Walk(doc, func(n *Node) error {
+		if n.IsBlock() {
+			style := "h2"
+			n.Style = &style
+		}
+		return nil
+	})
+	h.Record(doc)
+	if !h.CanUndo() {
+		t.Fatal("in-place edit was not recorded")
+	}
+	undone, err := h.Undo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mustEncode(t, undone) != start {
+		t.Errorf("undo of an in-place edit:\n got %s\nwant %s", mustEncode(t, undone), start)
+	}
+
+	// Editing a returned document does not change the history.
+	undone[0].Key = "changed"
+	if h.Document()[0].Key != "a" {
+		t.Errorf("Document() shares nodes with the caller")
+	}
+}
+
+func TestHistoryTransactions(t *testing.T) {
+	doc := editorTestDoc()
+	start := mustEncode(t, doc)
+	h := NewHistory(doc)
+
+	h.Begin("typing")
+	sel := caretAt("a", 0)
+	for _, s := range []string{"O", "h", " "} {
+		var err error
+		if doc, sel, err = InsertText(doc, sel, s); err != nil {
+			t.Fatal(err)
+		}
+		h.Begin("nested")
+		h.Record(doc)
+		h.End()
+	}
+	h.End()
+	h.End() // unbalanced End is ignored
+
+	h.Begin("empty")
+	h.End()
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"label":"typing"`) || strings.Contains(string(data), "nested") || strings.Contains(string(data), "empty") {
+		t.Errorf("history JSON = %s", data)
+	}
+
+	got, err := h.Undo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mustEncode(t, got) != start || h.CanUndo() {
+		t.Errorf("one undo should revert the whole transaction")
+	}
+
+	// Undo ends an open transaction.
+	h.Begin("open")
+	d, _ := Remove(got, "img")
+	h.Record(d)
+	if !h.CanUndo() {
+		t.Fatalf("CanUndo with an open transaction")
+	}
+	if got, err := h.Undo(); err != nil || len(got) != 4 {
+		t.Errorf("Undo open transaction = %d nodes, %v", len(got), err)
+	}
+}
+
+func TestHistoryJSON(t *testing.T) {
+	h, versions := historyTestVersions(t)
+	h.Undo()
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewHistory(h.Document())
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	if doc, err := restored.Redo(); err != nil || mustEncode(t, doc) != versions[3] {
+		t.Fatalf("Redo after restore = %v", err)
+	}
+	for range 3 {
+		if _, err := restored.Undo(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := mustEncode(t, restored.Document()); got != versions[0] {
+		t.Errorf("restored history undone:\n got %s\nwant %s", got, versions[0])
+	}
+
+	wrong := NewHistory(Document{*withKeys(NewBlock("normal").AddSpan("other"), "x")})
+	if err := json.Unmarshal(data, wrong); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wrong.Undo(); !errors.Is(err, ErrConflict) {
+		t.Errorf("Undo on a different document: error = %v", err)
+	}
+	if err := json.Unmarshal([]byte(`{"undo":[{"ops":[{"type":"splice","insert":[{}]}]}]}`), wrong); !errors.Is(err, ErrMissingType) {
+		t.Errorf("invalid node: error = %v", err)
+	}
+	var perr *Error
+	if err := json.Unmarshal([]byte(`{"undo":{}}`), wrong); !errors.As(err, &perr) || perr.Op != "history" {
+		t.Errorf("malformed history: error = %v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	var doc Document
+	for i := range 100 {
+		doc = append(doc, *withKeys(NewBlock("normal").AddSpan(fmt.Sprintf("Paragraph %d", i)), fmt.Sprint("k", i)))
+	}
+	if ops := Diff(doc, doc); ops != nil {
+		t.Errorf("Diff of equal documents = %v", ops)
+	}
+
+	edited, _, err := InsertText(doc, caretAt("k50", 0), "New ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := Diff(doc, edited)
+	if len(ops) != 1 || ops[0].Type != "splice" || ops[0].Index != 50 || len(ops[0].Remove) != 1 || len(ops[0].Insert) != 1 {
+		t.Errorf("Diff of a text edit = %+v", ops)
+	}
+
+	moved, _ := Move(doc, "k3", 90)
+	ops = Diff(doc, moved)
+	if len(ops) != 1 || ops[0].Type != "move" || ops[0].Index != 3 || ops[0].To != 90 {
+		t.Errorf("Diff of a move = %+v", ops)
+	}
+	back, err := ops[0].Invert().Apply(moved)
+	if err != nil || mustEncode(t, back) != mustEncode(t, doc) {
+		t.Errorf("inverted move: %v", err)
+	}
+
+	moved, _ = Move(doc, "k90", 3)
+	if ops := Diff(doc, moved); len(ops) != 1 || ops[0].Type != "move" || ops[0].Index != 90 || ops[0].To != 3 {
+		t.Errorf("Diff of a move backward = %+v", ops)
+	}
+
+	// Nodes decoded from JSON equal the ones built in Go.
+	decoded, err := DecodeString(mustEncode(t, doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ops := Diff(doc, decoded); ops != nil {
+		t.Errorf("Diff after a JSON round trip = %+v", ops)
+	}
+}
+
+func TestHistoryApplyAndLimit(t *testing.T) {
+	doc := editorTestDoc()
+	h := NewHistory(doc)
+	h.Limit = 2
+	hr := *withKeys(NewNode("break"), "hr")
+	if err := h.Apply(Operation{Type: "splice", Index: 1, Insert: []Node{hr}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := nodeKeys(h.Document()); strings.Join(got, " ") != "a hr b img c" {
+		t.Errorf("after Apply = %v", got)
+	}
+	if err := h.Apply(Operation{Type: "splice", Index: 0, Remove: []Node{hr}}); !errors.Is(err, ErrConflict) {
+		t.Errorf("removing the wrong node: error = %v", err)
+	}
+	if err := h.Apply(Operation{Type: "rotate"}); err == nil {
+		t.Errorf("unknown operation type: no error")
+	}
+	h.Apply(Operation{Type: "move", Index: 0, To: 4})
+	h.Apply(Operation{Type: "splice", Index: 0, Remove: []Node{hr}})
+
+	undone := 0
+	for h.CanUndo() {
+		if _, err := h.Undo(); err != nil {
+			t.Fatal(err)
+		}
+		undone++
+	}
+	if undone != 2 {
+		t.Errorf("undid %d transactions with Limit 2", undone)
+	}
+	if got := nodeKeys(h.Document()); strings.Join(got, " ") != "a hr b img c" {
+		t.Errorf("after undo = %v", got)
+	}
+}
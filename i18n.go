@@ -0,0 +1,328 @@
+package portabletext
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrNotLocalized = errors.New("not a localized field")
+	ErrNoLocale     = errors.New("no document for locale")
+)
+
+// LocaleLayout is how a field stores one value per locale.
+type LocaleLayout int
+
+const (
+	// LocaleArray is the internationalized-array pattern:
+	// [{"_key": "en", "value": [...]}, {"_key": "de", "value": [...]}]
+	LocaleArray LocaleLayout = iota
+	// LocaleObject is the object-per-locale pattern:
+	// {"en": [...], "de": [...]}; fields starting with "_" are ignored.
+	LocaleObject
+)
+
+// LocalizedField is a Portable Text field stored once per locale, wrapping
+// the value as decoded from JSON into maps and slices. Set updates that
+// value in place, so the enclosing document can be re-encoded afterwards;
+// see Value for the one case where the caller must store it back.
+type LocalizedField struct {
+	Layout LocaleLayout
+
+	arr []any
+	obj map[string]any
+}
+
+// ParseLocalized recognizes v as a localized field: an array whose
+// elements are objects with a string _key and a value field, or an object
+// whose non-underscore fields are all arrays or null.
+func ParseLocalized(v any) (*LocalizedField, error) {
+	switch x := v.(type) {
+	case []any:
+		for i, e := range x {
+			m, ok := e.(map[string]any)
+			if !ok {
+				return nil, wrap("i18n", nodePath(i).String(), ErrNotLocalized)
+			}
+			if _, ok := m["_key"].(string); !ok {
+				return nil, wrap("i18n", nodePath(i).String(), ErrNotLocalized)
+			}
+			if _, ok := m["value"]; !ok {
+				return nil, wrap("i18n", nodePath(i).String(), ErrNotLocalized)
+			}
+		}
+		return &LocalizedField{Layout: LocaleArray, arr: x}, nil
+	case map[string]any:
+		for k, e := range x {
+			if strings.HasPrefix(k, "_") || e == nil {
+				continue
+			}
+			if _, ok := e.([]any); !ok {
+				return nil, wrap("i18n", k, ErrNotLocalized)
+			}
+		}
+		return &LocalizedField{Layout: LocaleObject, obj: x}, nil
+	}
+	return nil, wrap("i18n", "", ErrNotLocalized)
+}
+
+// Value returns the field's JSON value. With LocaleArray, Set appends new
+// locales to a new slice, so store Value back into the enclosing object
+// after adding a locale.
+func (f *LocalizedField) Value() any {
+	if f.Layout == LocaleArray {
+		return f.arr
+	}
+	return f.obj
+}
+
+// raw returns the stored value for locale, its path for errors, and
+// whether the locale is present.
+func (f *LocalizedField) raw(locale string) (any, string, bool) {
+	if f.Layout == LocaleObject {
+		v, ok := f.obj[locale]
+		return v, locale, ok && !strings.HasPrefix(locale, "_")
+	}
+	for i, e := range f.arr {
+		if m := e.(map[string]any); m["_key"] == locale {
+			return m["value"], fmt.Sprintf("[%d].value", i), true
+		}
+	}
+	return nil, "", false
+}
+
+// Locales returns the locales that have content, in stored order for
+// LocaleArray and sorted for LocaleObject.
+func (f *LocalizedField) Locales() []string {
+	var out []string
+	if f.Layout == LocaleObject {
+		for _, k := range sortedKeys(f.obj) {
+			if arr, _ := f.obj[k].([]any); len(arr) > 0 && !strings.HasPrefix(k, "_") {
+				out = append(out, k)
+			}
+		}
+		return out
+	}
+	for _, e := range f.arr {
+		m := e.(map[string]any)
+		if arr, _ := m["value"].([]any); len(arr) > 0 {
+			out = append(out, m["_key"].(string))
+		}
+	}
+	return out
+}
+
+// Missing returns the locales among want that have no content.
+func (f *LocalizedField) Missing(want ...string) []string {
+	have := f.Locales()
+	var out []string
+	for _, l := range want {
+		if !slices.Contains(have, l) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// Document decodes the document for the first locale in the fallback
+// chain that has content and reports which locale that was. It returns
+// an error wrapping ErrNoLocale when none has, or a decode error with a
+// path such as "[1].value[3]" or "de[3]".
+func (f *LocalizedField) Document(locales ...string) (Document, string, error) {
+	for _, l := range locales {
+		v, p, ok := f.raw(l)
+		arr, _ := v.([]any)
+		if !ok || len(arr) == 0 {
+			continue
+		}
+		doc, err := decodeArray(arr, p)
+		if err != nil {
+			return nil, "", wrap("i18n", p, err)
+		}
+		return doc, l, nil
+	}
+	return nil, "", wrap("i18n", "", fmt.Errorf("%w: %s", ErrNoLocale, strings.Join(locales, ", ")))
+}
+
+// Set stores doc as the content for locale, replacing any existing value.
+// A new LocaleArray entry copies the _type of the existing entries.
+func (f *LocalizedField) Set(locale string, doc Document) error {
+	v, err := documentValue(doc)
+	if err != nil {
+		return wrap("i18n", locale, err)
+	}
+	if f.Layout == LocaleObject {
+		f.obj[locale] = v
+		return nil
+	}
+	entry := map[string]any{"_key": locale, "value": v}
+	for _, e := range f.arr {
+		m := e.(map[string]any)
+		if m["_key"] == locale {
+			m["value"] = v
+			return nil
+		}
+		if t, ok := m["_type"]; ok {
+			entry["_type"] = t
+		}
+	}
+	f.arr = append(f.arr, entry)
+	return nil
+}
+
+// LocaleFallbacks returns a fallback chain for locale: the locale itself,
+// then each shorter prefix split at "-" or "_", then defaults, without
+// duplicates. LocaleFallbacks("de-CH", "en") is [de-CH de en].
+func LocaleFallbacks(locale string, defaults ...string) []string {
+	var out []string
+	add := func(l string) {
+		if l != "" && !slices.Contains(out, l) {
+			out = append(out, l)
+		}
+	}
+	for l := locale; l != ""; {
+		add(l)
+		i := strings.LastIndexAny(l, "-_")
+		if i < 0 {
+			break
+		}
+		l = l[:i]
+	}
+	for _, l := range defaults {
+		add(l)
+	}
+	return out
+}
+
+// LocaleDiff is a structural difference between the base locale and
+// another locale of a field, as found by Compare.
+type LocaleDiff struct {
+	Locale string
+	Op     string // "missing" (only in the base), "extra" (only in Locale) or "changed"
+	Path   Path   // in the base document for "missing", else in Locale's
+	Detail string // the node, or what changed
+}
+
+func (d LocaleDiff) String() string {
+	return fmt.Sprintf("%s: %s %s at %s", d.Locale, d.Op, d.Detail, d.Path)
+}
+
+// Compare reports how the structure of each locale's document differs
+// from the base locale's: nodes are aligned by type, style and list
+// position, ignoring text. Aligned blocks are "changed" when their inline
+// objects or annotation types differ. Without locales, every locale with
+// content other than base is compared.
+func (f *LocalizedField) Compare(base string, locales ...string) ([]LocaleDiff, error) {
+	a, _, err := f.Document(base)
+	if err != nil {
+		return nil, err
+	}
+	if len(locales) == 0 {
+		locales = slices.DeleteFunc(f.Locales(), func(l string) bool { return l == base })
+	}
+
+	var diffs []LocaleDiff
+	for _, l := range locales {
+		b, _, err := f.Document(l)
+		if errors.Is(err, ErrNoLocale) {
+			b = Document{}
+		} else if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, compareStructure(l, a, b)...)
+	}
+	return diffs, nil
+}
+
+// compareStructure aligns a and b by node shape with a longest common
+// subsequence and reports the nodes left over.
+func compareStructure(locale string, a, b Document) []LocaleDiff {
+	sa := make([]string, len(a))
+	for i := range a {
+		sa[i] = nodeShape(&a[i])
+	}
+	sb := make([]string, len(b))
+	for j := range b {
+		sb[j] = nodeShape(&b[j])
+	}
+
+	// lcs[i][j] is the LCS length of sa[i:] and sb[j:].
+	lcs := make([][]int, len(sa)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(sb)+1)
+	}
+	for i := len(sa) - 1; i >= 0; i-- {
+		for j := len(sb) - 1; j >= 0; j-- {
+			if sa[i] == sb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diffs []LocaleDiff
+	i, j := 0, 0
+	for i < len(sa) || j < len(sb) {
+		switch {
+		case i < len(sa) && j < len(sb) && sa[i] == sb[j]:
+			if d := inlineShapeDiff(&a[i], &b[j]); d != "" {
+				diffs = append(diffs, LocaleDiff{Locale: locale, Op: "changed", Path: nodePath(j), Detail: d})
+			}
+			i++
+			j++
+		case j == len(sb) || (i < len(sa) && lcs[i+1][j] >= lcs[i][j+1]):
+			diffs = append(diffs, LocaleDiff{Locale: locale, Op: "missing", Path: nodePath(i), Detail: sa[i]})
+			i++
+		default:
+			diffs = append(diffs, LocaleDiff{Locale: locale, Op: "extra", Path: nodePath(j), Detail: sb[j]})
+			j++
+		}
+	}
+	return diffs
+}
+
+// nodeShape describes a node without its text: "image", "block h2" or
+// "block normal bullet 2".
+func nodeShape(n *Node) string {
+	if !n.IsBlock() {
+		return n.Type
+	}
+	s := "block " + n.GetStyle()
+	if n.ListItem != nil {
+		s += fmt.Sprintf(" %s %d", *n.ListItem, n.GetListLevel())
+	}
+	return s
+}
+
+// inlineShapeDiff compares the inline objects and annotation types of two
+// aligned blocks.
+func inlineShapeDiff(a, b *Node) string {
+	objects := func(n *Node) []string {
+		var out []string
+		for _, c := range n.Children {
+			if c.Type != "span" {
+				out = append(out, c.Type)
+			}
+		}
+		return out
+	}
+	annotations := func(n *Node) []string {
+		var out []string
+		for _, md := range n.MarkDefs {
+			out = append(out, md.Type)
+		}
+		slices.Sort(out)
+		return out
+	}
+	var d []string
+	if oa, ob := objects(a), objects(b); !slices.Equal(oa, ob) {
+		d = append(d, fmt.Sprintf("inline objects [%s] -> [%s]", strings.Join(oa, " "), strings.Join(ob, " ")))
+	}
+	if aa, ab := annotations(a), annotations(b); !slices.Equal(aa, ab) {
+		d = append(d, fmt.Sprintf("annotations [%s] -> [%s]", strings.Join(aa, " "), strings.Join(ab, " ")))
+	}
+	return strings.Join(d, "; ")
+}
@@ -0,0 +1,179 @@
+package portabletext
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func decodeJSONValue(t *testing.T, s string) any {
+	t.Helper()
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func i18nArrayField(t *testing.T) any {
+	t.Helper()
+	en := `[` + strings.Join([]string{
+		`{"_type":"block","_key":"a","style":"h2","markDefs":[],"children":[{"_type":"span","text":"Hello"}]}`,
+		`{"_type":"block","_key":"b","style":"normal","markDefs":[{"_key":"l","_type":"link","href":"/x"}],"children":[{"_type":"span","text":"Body","marks":["l"]}]}`,
+		`{"_type":"image","_key":"c","asset":{"_ref":"image-1"}}`,
+	}, ",") + `]`
+	de := `[` + strings.Join([]string{
+		`{"_type":"block","_key":"a","style":"h2","markDefs":[],"children":[{"_type":"span","text":"Hallo"}]}`,
+		`{"_type":"block","_key":"b","style":"normal","markDefs":[],"children":[{"_type":"span","text":"Text"}]}`,
+		`{"_type":"code","_key":"d","code":"x"}`,
+	}, ",") + `]`
+	return decodeJSONValue(t, `[
+		{"_key":"en","_type":"internationalizedArrayBlockContentValue","value":`+en+`},
+		{"_key":"de","_type":"internationalizedArrayBlockContentValue","value":`+de+`},
+		{"_key":"fr","_type":"internationalizedArrayBlockContentValue","value":[]}
+	]`)
+}
+
+func TestLocalizedArray(t *testing.T) {
+	f, err := ParseLocalized(i18nArrayField(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Layout != LocaleArray {
+		t.Errorf("Layout = %v, want LocaleArray", f.Layout)
+	}
+	if got := f.Locales(); !slices.Equal(got, []string{"en", "de"}) {
+		t.Errorf("Locales() = %v", got)
+	}
+	if got := f.Missing("en", "fr", "it"); !slices.Equal(got, []string{"fr", "it"}) {
+		t.Errorf("Missing() = %v", got)
+	}
+
+	doc, used, err := f.Document(LocaleFallbacks("fr-CA", "de", "en")...)
+	if err != nil || used != "de" || doc[0].GetText() != "Hallo" {
+		t.Errorf("Document(fr-CA, fr, de, en) = %v, %q, %v", doc, used, err)
+	}
+	if _, _, err := f.Document("it"); !errors.Is(err, ErrNoLocale) {
+		t.Errorf("Document(it) error = %v, want ErrNoLocale", err)
+	}
+
+	fr := Document{*NewBlock("h2").AddSpan("Bonjour")}
+	if err := f.Set("fr", fr); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("it", fr); err != nil {
+		t.Fatal(err)
+	}
+	arr := f.Value().([]any)
+	if len(arr) != 4 {
+		t.Fatalf("Value() has %d entries, want 4", len(arr))
+	}
+	it := arr[3].(map[string]any)
+	if it["_key"] != "it" || it["_type"] != "internationalizedArrayBlockContentValue" {
+		t.Errorf("new entry = %v", it)
+	}
+	if got, _, _ := f.Document("fr"); got[0].GetText() != "Bonjour" {
+		t.Errorf("Document(fr) after Set = %q", got[0].GetText())
+	}
+	out, err := json.Marshal(f.Value())
+	if err != nil || !strings.Contains(string(out), `"text":"Bonjour"`) {
+		t.Errorf("re-encoded value = %s, %v", out, err)
+	}
+}
+
+func TestLocalizedObject(t *testing.T) {
+	v := decodeJSONValue(t, `{"_type":"localeBlockContent","en":[`+
+		strings.Join([]string{
+			`{"_type":"block","_key":"a","style":"normal","children":[{"_type":"span","text":"One"}]}`,
+		}, ",")+`],"de":null}`)
+	f, err := ParseLocalized(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Layout != LocaleObject || !slices.Equal(f.Locales(), []string{"en"}) {
+		t.Errorf("Layout = %v, Locales() = %v", f.Layout, f.Locales())
+	}
+	doc, used, err := f.Document("de", "en")
+	if err != nil || used != "en" {
+		t.Fatalf("Document(de, en) = %q, %v", used, err)
+	}
+	if err := f.Set("de", doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.(map[string]any)["de"].([]any); !ok {
+		t.Error("Set did not update the object in place")
+	}
+	if _, _, err := f.Document("_type"); !errors.Is(err, ErrNoLocale) {
+		t.Errorf("Document(_type) error = %v", err)
+	}
+}
+
+func TestParseLocalizedInvalid(t *testing.T) {
+	for _, s := range []string{
+		`"text"`,
+		`[{"_type":"block"}]`,
+		`[{"_key":"en"}]`,
+		`{"en":"plain string"}`,
+	} {
+		if _, err := ParseLocalized(decodeJSONValue(t, s)); !errors.Is(err, ErrNotLocalized) {
+			t.Errorf("ParseLocalized(%s) error = %v, want ErrNotLocalized", s, err)
+		}
+	}
+
+	f, _ := ParseLocalized(decodeJSONValue(t, `{"en":[{"_key":"a"}]}`))
+	_, _, err := f.Document("en")
+	var pe *Error
+	if !errors.As(err, &pe) || pe.Path != "en" || !errors.Is(err, ErrMissingType) {
+		t.Errorf("Document() error = %v, want ErrMissingType at en", err)
+	}
+}
+
+func TestLocaleFallbacks(t *testing.T) {
+	tests := []struct {
+		locale   string
+		defaults []string
+		want     []string
+	}{
+		{"de-CH", []string{"en"}, []string{"de-CH", "de", "en"}},
+		{"zh_Hant_TW", nil, []string{"zh_Hant_TW", "zh_Hant", "zh"}},
+		{"en", []string{"en"}, []string{"en"}},
+		{"", []string{"en"}, []string{"en"}},
+	}
+	for _, tt := range tests {
+		if got := LocaleFallbacks(tt.locale, tt.defaults...); !slices.Equal(got, tt.want) {
+			t.Errorf("LocaleFallbacks(%q, %v) = %v, want %v", tt.locale, tt.defaults, got, tt.want)
+		}
+	}
+}
+
+func TestLocalizedCompare(t *testing.T) {
+	f, err := ParseLocalized(i18nArrayField(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := f.Compare("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range diffs {
+		got = append(got, d.String())
+	}
+	want := []string{
+		"de: changed annotations [link] -> [] at [1]",
+		"de: missing image at [2]",
+		"de: extra code at [2]",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Compare(en) = %q, want %q", got, want)
+	}
+
+	diffs, _ = f.Compare("en", "fr")
+	if len(diffs) != 3 || diffs[0].Op != "missing" || diffs[2].Detail != "image" {
+		t.Errorf("Compare(en, fr) = %v", diffs)
+	}
+}
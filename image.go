@@ -0,0 +1,309 @@
+package portabletext
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DefaultImageCDN is the base URL used when ImageURLBuilder.BaseURL is empty.
+const DefaultImageCDN = "https://cdn.sanity.io"
+
+// DefaultSrcSetWidths are the candidate widths used by Responsive when
+// SrcSetOptions.Widths is empty.
+var DefaultSrcSetWidths = []int{320, 640, 960, 1280, 1920, 2560}
+
+// ErrInvalidAssetID is returned for image asset IDs that do not have the
+// form image-<hash>-<width>x<height>-<format>.
+var ErrInvalidAssetID = errors.New("invalid image asset ID")
+
+// ImageAsset is the information encoded in a Sanity image asset ID.
+type ImageAsset struct {
+	ID     string // e.g. "image-abc123-1200x800-jpg"
+	Hash   string // e.g. "abc123"
+	Width  int
+	Height int
+	Format string // e.g. "jpg", "png", "svg"
+}
+
+// ParseImageAssetID parses an asset ID such as "image-abc123-1200x800-jpg".
+func ParseImageAssetID(id string) (ImageAsset, error) {
+	parts := strings.Split(id, "-")
+	if len(parts) != 4 || parts[0] != "image" || parts[1] == "" || parts[3] == "" {
+		return ImageAsset{}, fmt.Errorf("%w: %q", ErrInvalidAssetID, id)
+	}
+	w, h, ok := strings.Cut(parts[2], "x")
+	width, werr := strconv.Atoi(w)
+	height, herr := strconv.Atoi(h)
+	if !ok || werr != nil || herr != nil || width <= 0 || height <= 0 {
+		return ImageAsset{}, fmt.Errorf("%w: %q", ErrInvalidAssetID, id)
+	}
+	return ImageAsset{ID: id, Hash: parts[1], Width: width, Height: height, Format: parts[3]}, nil
+}
+
+// ImageCrop is the crop stored on an image field, as fractions of the
+// original size trimmed from each edge.
+type ImageCrop struct {
+	Top, Bottom, Left, Right float64
+}
+
+// ImageHotspot is the focus area stored on an image field: its center (X, Y)
+// and size, as fractions of the original size.
+type ImageHotspot struct {
+	X, Y, Width, Height float64
+}
+
+// Image is an image field with its parsed asset, crop and hotspot.
+type Image struct {
+	Asset   ImageAsset
+	Crop    *ImageCrop    // nil if not set
+	Hotspot *ImageHotspot // nil if not set
+	Alt     string
+}
+
+// ParseImage reads an image from the Raw fields of an image node or inline
+// image (n.Raw or s.Raw). The asset may be a reference ({"_ref": id}) or a
+// dereferenced asset document ({"_id": id}).
+func ParseImage(raw map[string]any) (*Image, error) {
+	asset, _ := raw["asset"].(map[string]any)
+	id, _ := asset["_ref"].(string)
+	if id == "" {
+		id, _ = asset["_id"].(string)
+	}
+	if id == "" {
+		return nil, fmt.Errorf("%w: missing asset reference", ErrInvalidAssetID)
+	}
+	a, err := ParseImageAssetID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	img := &Image{Asset: a}
+	img.Alt, _ = raw["alt"].(string)
+	if c, ok := raw["crop"].(map[string]any); ok {
+		img.Crop = &ImageCrop{
+			Top:    rawFloat(c["top"], 0),
+			Bottom: rawFloat(c["bottom"], 0),
+			Left:   rawFloat(c["left"], 0),
+			Right:  rawFloat(c["right"], 0),
+		}
+	}
+	if h, ok := raw["hotspot"].(map[string]any); ok {
+		img.Hotspot = &ImageHotspot{
+			X:      rawFloat(h["x"], 0.5),
+			Y:      rawFloat(h["y"], 0.5),
+			Width:  rawFloat(h["width"], 1),
+			Height: rawFloat(h["height"], 1),
+		}
+	}
+	return img, nil
+}
+
+func rawFloat(v any, def float64) float64 {
+	switch x := v.(type) {
+	case json.Number:
+		if f, err := x.Float64(); err == nil {
+			return f
+		}
+	case float64:
+		return x
+	case int:
+		return float64(x)
+	}
+	return def
+}
+
+// ImageRect is a rectangle in source image pixels.
+type ImageRect struct {
+	Left, Top, Width, Height int
+}
+
+// Rect returns the region of the source image to show at the given output
+// size, in the same way as Sanity's image-url library: the crop is applied
+// first, then, if both width and height are set and the aspect ratio
+// differs, the crop is trimmed further around the hotspot's center. A crop
+// that leaves an empty region is ignored.
+func (img *Image) Rect(width, height int) ImageRect {
+	a := img.Asset
+	crop := ImageCrop{}
+	if img.Crop != nil {
+		crop = *img.Crop
+	}
+	hot := ImageHotspot{X: 0.5, Y: 0.5, Width: 1, Height: 1}
+	if img.Hotspot != nil {
+		hot = *img.Hotspot
+	}
+
+	w, h := float64(a.Width), float64(a.Height)
+	cl, ct := crop.Left*w, crop.Top*h
+	r := ImageRect{
+		Left:   int(math.Round(cl)),
+		Top:    int(math.Round(ct)),
+		Width:  int(math.Round(w - crop.Right*w - cl)),
+		Height: int(math.Round(h - crop.Bottom*h - ct)),
+	}
+	if r.Width <= 0 || r.Height <= 0 {
+		// A crop that leaves no pixels, e.g. left+right=1, is ignored.
+		r = ImageRect{Width: a.Width, Height: a.Height}
+	}
+	if width <= 0 || height <= 0 || r.Width <= 0 || r.Height <= 0 {
+		return r
+	}
+
+	want := float64(width) / float64(height)
+	if float64(r.Width)/float64(r.Height) > want {
+		// Crop is wider than requested: trim left and right.
+		nw := int(math.Round(float64(r.Height) * want))
+		center := int(math.Round(hot.X * w))
+		left := max(0, int(math.Round(float64(center)-float64(nw)/2)))
+		left = min(max(left, r.Left), r.Left+r.Width-nw)
+		return ImageRect{Left: left, Top: r.Top, Width: nw, Height: r.Height}
+	}
+	// Crop is taller than requested: trim top and bottom.
+	nh := int(math.Round(float64(r.Width) / want))
+	center := int(math.Round(hot.Y * h))
+	top := max(0, int(math.Round(float64(center)-float64(nh)/2)))
+	top = min(max(top, r.Top), r.Top+r.Height-nh)
+	return ImageRect{Left: r.Left, Top: top, Width: r.Width, Height: nh}
+}
+
+// ImageParams are the image pipeline parameters added to a URL. Zero values
+// are omitted.
+type ImageParams struct {
+	Width   int
+	Height  int
+	Fit     string // "clip", "crop", "fill", "fillmax", "max", "scale", "min"
+	Format  string // "jpg", "png", "webp", or "auto" for auto=format
+	Quality int    // 0-100
+	DPR     int    // device pixel ratio, 1-3
+}
+
+// ImageURLBuilder builds CDN URLs for images in one project and dataset.
+// It does no network I/O.
+type ImageURLBuilder struct {
+	ProjectID string
+	Dataset   string
+	BaseURL   string // defaults to DefaultImageCDN
+}
+
+// URL returns the CDN URL for img with p applied. The crop and hotspot are
+// sent as a rect parameter when they select less than the whole image.
+func (b ImageURLBuilder) URL(img *Image, p ImageParams) string {
+	a := img.Asset
+	base := b.BaseURL
+	if base == "" {
+		base = DefaultImageCDN
+	}
+	u := fmt.Sprintf("%s/images/%s/%s/%s-%dx%d.%s", strings.TrimRight(base, "/"),
+		url.PathEscape(b.ProjectID), url.PathEscape(b.Dataset), a.Hash, a.Width, a.Height, a.Format)
+
+	var q []string
+	if r := img.Rect(p.Width, p.Height); r != (ImageRect{Width: a.Width, Height: a.Height}) {
+		q = append(q, fmt.Sprintf("rect=%d,%d,%d,%d", r.Left, r.Top, r.Width, r.Height))
+	}
+	if p.Width > 0 {
+		q = append(q, "w="+strconv.Itoa(p.Width))
+	}
+	if p.Height > 0 {
+		q = append(q, "h="+strconv.Itoa(p.Height))
+	}
+	if p.Fit != "" {
+		q = append(q, "fit="+url.QueryEscape(p.Fit))
+	}
+	switch p.Format {
+	case "":
+	case "auto":
+		q = append(q, "auto=format")
+	default:
+		q = append(q, "fm="+url.QueryEscape(p.Format))
+	}
+	if p.Quality > 0 {
+		q = append(q, "q="+strconv.Itoa(p.Quality))
+	}
+	if p.DPR > 0 {
+		q = append(q, "dpr="+strconv.Itoa(p.DPR))
+	}
+	if len(q) == 0 {
+		return u
+	}
+	return u + "?" + strings.Join(q, "&")
+}
+
+// SrcSetOptions controls Responsive.
+type SrcSetOptions struct {
+	// Widths are the candidate widths; defaults to DefaultSrcSetWidths.
+	// Widths larger than the cropped source are dropped.
+	Widths []int
+
+	// Sizes is the sizes attribute. If empty it is derived from MaxWidth:
+	// "(max-width: 800px) 100vw, 800px", or "100vw" without one.
+	Sizes string
+
+	// MaxWidth is the largest width the image is displayed at, in CSS pixels.
+	MaxWidth int
+}
+
+// ResponsiveImage holds the attributes for an <img> element.
+type ResponsiveImage struct {
+	Src    string
+	SrcSet string
+	Sizes  string
+	Width  int // intrinsic size of Src, for layout
+	Height int
+	Alt    string
+}
+
+// Responsive returns src, srcset and sizes values for img. p supplies the
+// shared parameters; if p.Height is set, each candidate keeps the aspect
+// ratio p.Width:p.Height (or the crop's when p.Width is zero).
+func (b ImageURLBuilder) Responsive(img *Image, p ImageParams, opts SrcSetOptions) ResponsiveImage {
+	crop := img.Rect(0, 0)
+	ratio := 0.0
+	if crop.Width > 0 {
+		ratio = float64(crop.Height) / float64(crop.Width)
+	}
+	if p.Width > 0 && p.Height > 0 {
+		ratio = float64(p.Height) / float64(p.Width)
+	}
+
+	widths := opts.Widths
+	if len(widths) == 0 {
+		widths = DefaultSrcSetWidths
+	}
+	var fit []int
+	for _, w := range widths {
+		if w > 0 && w <= crop.Width {
+			fit = append(fit, w)
+		}
+	}
+	if len(fit) == 0 {
+		fit = []int{crop.Width}
+	}
+
+	out := ResponsiveImage{Alt: img.Alt}
+	var set []string
+	for _, w := range fit {
+		c := p
+		c.Width = w
+		if p.Height > 0 {
+			c.Height = int(math.Round(float64(w) * ratio))
+		}
+		u := b.URL(img, c)
+		set = append(set, fmt.Sprintf("%s %dw", u, w))
+		out.Src, out.Width, out.Height = u, w, int(math.Round(float64(w)*ratio))
+	}
+	out.SrcSet = strings.Join(set, ", ")
+
+	out.Sizes = opts.Sizes
+	if out.Sizes == "" {
+		out.Sizes = "100vw"
+		if opts.MaxWidth > 0 {
+			out.Sizes = fmt.Sprintf("(max-width: %dpx) 100vw, %dpx", opts.MaxWidth, opts.MaxWidth)
+		}
+	}
+	return out
+}
@@ -0,0 +1,114 @@
+package portabletext
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseImageAssetID(t *testing.T) {
+	a, err := ParseImageAssetID("image-abc123-1200x800-jpg")
+	if err != nil {
+		t.Fatalf("ParseImageAssetID() error = %v", err)
+	}
+	want := ImageAsset{ID: "image-abc123-1200x800-jpg", Hash: "abc123", Width: 1200, Height: 800, Format: "jpg"}
+	if a != want {
+		t.Errorf("ParseImageAssetID() = %+v, want %+v", a, want)
+	}
+
+	for _, id := range []string{"", "file-abc-pdf", "image-abc-1200-jpg", "image-abc-0x10-png", "image--10x10-png", "image-abc-10x10"} {
+		if _, err := ParseImageAssetID(id); !errors.Is(err, ErrInvalidAssetID) {
+			t.Errorf("ParseImageAssetID(%q) error = %v, want ErrInvalidAssetID", id, err)
+		}
+	}
+}
+
+func TestImageURL(t *testing.T) {
+	b := ImageURLBuilder{ProjectID: "proj", Dataset: "production"}
+	img := &Image{Asset: ImageAsset{Hash: "abc", Width: 2000, Height: 1000, Format: "jpg"}}
+	base := "https://cdn.sanity.io/images/proj/production/abc-2000x1000.jpg"
+
+	tests := []struct {
+		name string
+		img  *Image
+		p    ImageParams
+		want string
+	}{
+		{"plain", img, ImageParams{}, base},
+		{"width only", img, ImageParams{Width: 800, Format: "auto", Quality: 75}, base + "?w=800&auto=format&q=75"},
+		{"square centered", img, ImageParams{Width: 500, Height: 500, Fit: "crop", Format: "webp"}, base + "?rect=500,0,1000,1000&w=500&h=500&fit=crop&fm=webp"},
+		{"hotspot clamped", &Image{Asset: img.Asset, Hotspot: &ImageHotspot{X: 0.9, Y: 0.5, Width: 0.1, Height: 0.1}},
+			ImageParams{Width: 500, Height: 500}, base + "?rect=1000,0,1000,1000&w=500&h=500"},
+		{"crop only", &Image{Asset: img.Asset, Crop: &ImageCrop{Left: 0.1, Right: 0.1}},
+			ImageParams{Width: 400}, base + "?rect=200,0,1600,1000&w=400"},
+		{"crop taller than output", &Image{Asset: img.Asset, Crop: &ImageCrop{Left: 0.25, Right: 0.25}, Hotspot: &ImageHotspot{X: 0.5, Y: 0.9, Width: 1, Height: 1}},
+			ImageParams{Width: 200, Height: 100}, base + "?rect=500,500,1000,500&w=200&h=100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.URL(tt.img, tt.p); got != tt.want {
+				t.Errorf("URL() = %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseImage(t *testing.T) {
+	doc, err := DecodeString(`[{"_type":"image","alt":"A cat",
+		"asset":{"_type":"reference","_ref":"image-cat-1000x500-png"},
+		"crop":{"top":0,"bottom":0,"left":0.5,"right":0},
+		"hotspot":{"x":0.75,"y":0.5,"width":0.2,"height":0.2}}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := ParseImage(doc[0].Raw)
+	if err != nil {
+		t.Fatalf("ParseImage() error = %v", err)
+	}
+	if img.Alt != "A cat" || img.Asset.Format != "png" || img.Crop.Left != 0.5 || img.Hotspot.X != 0.75 {
+		t.Errorf("ParseImage() = %+v", img)
+	}
+	if r := img.Rect(0, 0); r != (ImageRect{Left: 500, Top: 0, Width: 500, Height: 500}) {
+		t.Errorf("Rect() = %+v", r)
+	}
+
+	deref := map[string]any{"asset": map[string]any{"_id": "image-x-10x10-gif"}}
+	if img, err := ParseImage(deref); err != nil || img.Asset.Hash != "x" {
+		t.Errorf("ParseImage(dereferenced) = %+v, %v", img, err)
+	}
+	if _, err := ParseImage(map[string]any{"alt": "no asset"}); !errors.Is(err, ErrInvalidAssetID) {
+		t.Errorf("ParseImage(no asset) error = %v", err)
+	}
+}
+
+func TestResponsive(t *testing.T) {
+	b := ImageURLBuilder{ProjectID: "p", Dataset: "d", BaseURL: "https://img.example/"}
+	img := &Image{Asset: ImageAsset{Hash: "h", Width: 1000, Height: 500, Format: "jpg"}, Alt: "wide"}
+
+	r := b.Responsive(img, ImageParams{Format: "auto"}, SrcSetOptions{Widths: []int{320, 640, 1280}, MaxWidth: 720})
+	if want := "https://img.example/images/p/d/h-1000x500.jpg?w=320&auto=format 320w, https://img.example/images/p/d/h-1000x500.jpg?w=640&auto=format 640w"; r.SrcSet != want {
+		t.Errorf("SrcSet = %s\nwant %s", r.SrcSet, want)
+	}
+	if r.Sizes != "(max-width: 720px) 100vw, 720px" || r.Width != 640 || r.Height != 320 || r.Alt != "wide" {
+		t.Errorf("Responsive() = %+v", r)
+	}
+	if !strings.HasSuffix(r.Src, "w=640&auto=format") {
+		t.Errorf("Src = %s, want the largest candidate", r.Src)
+	}
+
+	sq := b.Responsive(img, ImageParams{Width: 1, Height: 1}, SrcSetOptions{Widths: []int{100, 200}})
+	if !strings.Contains(sq.SrcSet, "w=200&h=200 200w") || sq.Sizes != "100vw" || sq.Height != 200 {
+		t.Errorf("Responsive(square) = %+v", sq)
+	}
+
+	// A crop with no width would divide by zero; it is ignored.
+	empty := *img
+	empty.Crop = &ImageCrop{Left: 0.6, Right: 0.4}
+	if r := empty.Rect(0, 0); r != (ImageRect{Width: 1000, Height: 500}) {
+		t.Errorf("Rect(empty crop) = %+v", r)
+	}
+	er := b.Responsive(&empty, ImageParams{}, SrcSetOptions{Widths: []int{320}})
+	if er.Width != 320 || er.Height != 160 || strings.Contains(er.Src, "rect=") {
+		t.Errorf("Responsive(empty crop) = %+v", er)
+	}
+}
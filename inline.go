@@ -0,0 +1,238 @@
+package portabletext
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrPlaceholderMismatch reports translated text whose mark or inline
+// object placeholders do not match the source block.
+var ErrPlaceholderMismatch = errors.New("translation placeholder mismatch")
+
+// inlineCode is a placeholder used when block text leaves the document for
+// translation: a paired code that applies mark to the text it encloses,
+// or a standalone code for the inline object at child.
+type inlineCode struct {
+	ph    bool
+	mark  string
+	child int // the inline object, or the span where the mark opens
+}
+
+type inlinePartKind int
+
+const (
+	partText inlinePartKind = iota
+	partOpen
+	partClose
+	partPlaceholder
+)
+
+// inlinePart is a run of text or a code boundary in a block's inline
+// content, as produced by inlineParts.
+type inlinePart struct {
+	kind inlinePartKind
+	text string
+	code int // index into the codes, for all but partText
+}
+
+// inlineParts flattens the children of a block into text and properly
+// nested codes. Codes are numbered in document order; when marks overlap,
+// the mark that runs longest is opened outermost.
+func inlineParts(n *Node) ([]inlinePart, []inlineCode) {
+	var parts []inlinePart
+	var codes []inlineCode
+	var open []int // codes of the open marks, outermost first
+	for j, c := range n.Children {
+		if c.Type != "span" {
+			// Close marks that do not continue after the object.
+			for k, o := range open {
+				if markRun(n.Children, j+1, codes[o].mark) == 0 {
+					for len(open) > k {
+						parts = append(parts, inlinePart{kind: partClose, code: open[len(open)-1]})
+						open = open[:len(open)-1]
+					}
+					break
+				}
+			}
+			codes = append(codes, inlineCode{ph: true, child: j})
+			parts = append(parts, inlinePart{kind: partPlaceholder, code: len(codes) - 1})
+			continue
+		}
+
+		keep := 0
+		for keep < len(open) && slices.Contains(c.Marks, codes[open[keep]].mark) {
+			keep++
+		}
+		for len(open) > keep {
+			parts = append(parts, inlinePart{kind: partClose, code: open[len(open)-1]})
+			open = open[:len(open)-1]
+		}
+
+		var opening []string
+		for _, m := range c.Marks {
+			isOpen := slices.ContainsFunc(open, func(k int) bool { return codes[k].mark == m })
+			if !isOpen && !slices.Contains(opening, m) {
+				opening = append(opening, m)
+			}
+		}
+		slices.SortStableFunc(opening, func(a, b string) int {
+			return markRun(n.Children, j, b) - markRun(n.Children, j, a)
+		})
+		for _, m := range opening {
+			codes = append(codes, inlineCode{mark: m, child: j})
+			open = append(open, len(codes)-1)
+			parts = append(parts, inlinePart{kind: partOpen, code: len(codes) - 1})
+		}
+
+		if c.Text != nil && *c.Text != "" {
+			parts = append(parts, inlinePart{kind: partText, text: *c.Text})
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		parts = append(parts, inlinePart{kind: partClose, code: open[i]})
+	}
+	return parts, codes
+}
+
+// markRun counts the spans from children[j] on that carry mark; inline
+// objects do not interrupt a run.
+func markRun(children []Span, j int, mark string) int {
+	n := 0
+	for ; j < len(children); j++ {
+		if children[j].Type != "span" {
+			continue
+		}
+		if !slices.Contains(children[j].Marks, mark) {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// spanBuilder rebuilds the children of block doc[i] from translated inline
+// content, checking that every code of the original is used exactly once.
+// Span keys of the original are reused in order.
+type spanBuilder struct {
+	op    string
+	n     *Node
+	i     int
+	codes []inlineCode
+	label func(k int, ph bool) string // code k as written in the file format
+
+	children []Span
+	text     strings.Builder
+	marks    []string
+	stack    []bool // whether each open element applied a mark
+	seen     []bool
+	keys     []string
+	errs     []error
+}
+
+func newSpanBuilder(op string, n *Node, i int, label func(k int, ph bool) string) *spanBuilder {
+	_, codes := inlineParts(n)
+	b := &spanBuilder{op: op, n: n, i: i, codes: codes, label: label, seen: make([]bool, len(codes))}
+	for _, c := range n.Children {
+		if k, ok := c.Raw["_key"].(string); ok && c.Type == "span" {
+			b.keys = append(b.keys, k)
+		}
+	}
+	return b
+}
+
+// mismatch records an ErrPlaceholderMismatch at path.
+func (b *spanBuilder) mismatch(path Path, format string, args ...any) {
+	err := fmt.Errorf("%w: "+format, append([]any{ErrPlaceholderMismatch}, args...)...)
+	b.errs = append(b.errs, wrap(b.op, path.String(), err))
+}
+
+func (b *spanBuilder) write(s string) { b.text.WriteString(s) }
+
+// use validates a reference to code k and marks it seen.
+func (b *spanBuilder) use(k int, ph bool) (inlineCode, bool) {
+	if k < 0 || k >= len(b.codes) || b.codes[k].ph != ph {
+		b.mismatch(nodePath(b.i), "unknown %s", b.label(k, ph))
+		return inlineCode{}, false
+	}
+	if b.seen[k] {
+		b.mismatch(nodePath(b.i), "%s appears more than once", b.label(k, ph))
+		return inlineCode{}, false
+	}
+	b.seen[k] = true
+	return b.codes[k], true
+}
+
+// open starts the paired code k. A negative k opens an element that
+// applies no mark, such as a markup added by translation tools.
+func (b *spanBuilder) open(k int) {
+	if k >= 0 {
+		if c, ok := b.use(k, false); ok && !slices.Contains(b.marks, c.mark) {
+			b.flush()
+			b.marks = append(b.marks, c.mark)
+			b.stack = append(b.stack, true)
+			return
+		}
+	}
+	b.stack = append(b.stack, false)
+}
+
+// close ends the innermost element started by open.
+func (b *spanBuilder) close() {
+	if len(b.stack) == 0 {
+		return
+	}
+	mark := b.stack[len(b.stack)-1]
+	b.stack = b.stack[:len(b.stack)-1]
+	if mark {
+		b.flush()
+		b.marks = b.marks[:len(b.marks)-1]
+	}
+}
+
+// placeholder inserts a copy of the inline object for code k.
+func (b *spanBuilder) placeholder(k int) {
+	if c, ok := b.use(k, true); ok {
+		b.flush()
+		b.children = append(b.children, cloneSpans(b.n.Children[c.child : c.child+1])[0])
+	}
+}
+
+func (b *spanBuilder) flush() {
+	if b.text.Len() == 0 {
+		return
+	}
+	t := b.text.String()
+	b.text.Reset()
+	s := Span{Type: "span", Text: &t, Marks: slices.Clone(b.marks), Raw: map[string]any{}}
+	if s.Marks == nil {
+		s.Marks = []string{}
+	}
+	if len(b.keys) > 0 {
+		s.Raw["_key"], b.keys = b.keys[0], b.keys[1:]
+	} else {
+		s.Raw["_key"] = NewKey()
+	}
+	b.children = append(b.children, s)
+}
+
+// finish returns the rebuilt children and any mismatches, including codes
+// that were never used.
+func (b *spanBuilder) finish() ([]Span, []error) {
+	b.flush()
+	for k, c := range b.codes {
+		if b.seen[k] {
+			continue
+		}
+		if c.ph {
+			b.mismatch(childPath(b.i, c.child), "%s for the %s object is missing", b.label(k, true), b.n.Children[c.child].Type)
+		} else {
+			b.mismatch(childPath(b.i, c.child), "%s for mark %q is missing", b.label(k, false), c.mark)
+		}
+	}
+	if b.children == nil {
+		b.children = []Span{}
+	}
+	return b.children, b.errs
+}
@@ -0,0 +1,74 @@
+package portabletext
+
+import "iter"
+
+// Nodes yields every top-level node with its index.
+// Nodes are yielded by pointer, so fn may mutate them in place as with Walk.
+func (doc Document) Nodes() iter.Seq2[int, *Node] {
+	return func(yield func(int, *Node) bool) {
+		for i := range doc {
+			if !yield(i, &doc[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Blocks yields every top-level node of _type "block" with its index.
+func (doc Document) Blocks() iter.Seq2[int, *Node] {
+	return func(yield func(int, *Node) bool) {
+		for i := range doc {
+			if doc[i].IsBlock() && !yield(i, &doc[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Spans yields every child of _type "span" in every node, with its path.
+func (doc Document) Spans() iter.Seq2[Path, *Span] {
+	return doc.children(func(s *Span) bool { return s.Type == "span" })
+}
+
+// InlineObjects yields every child that is not a span (e.g. inline images
+// or references), with its path.
+func (doc Document) InlineObjects() iter.Seq2[Path, *Span] {
+	return doc.children(func(s *Span) bool { return s.Type != "span" })
+}
+
+// MarkDefs yields every mark definition in every node, with its path.
+func (doc Document) MarkDefs() iter.Seq2[Path, *MarkDef] {
+	return doc.markDefs(func(*MarkDef) bool { return true })
+}
+
+// Links yields every mark definition of _type "link", with its path.
+// The target is usually found in md.Raw["href"].
+func (doc Document) Links() iter.Seq2[Path, *MarkDef] {
+	return doc.markDefs(func(md *MarkDef) bool { return md.Type == "link" })
+}
+
+func (doc Document) children(pred func(*Span) bool) iter.Seq2[Path, *Span] {
+	return func(yield func(Path, *Span) bool) {
+		for i := range doc {
+			for j := range doc[i].Children {
+				s := &doc[i].Children[j]
+				if pred(s) && !yield(childPath(i, j), s) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (doc Document) markDefs(pred func(*MarkDef) bool) iter.Seq2[Path, *MarkDef] {
+	return func(yield func(Path, *MarkDef) bool) {
+		for i := range doc {
+			for j := range doc[i].MarkDefs {
+				md := &doc[i].MarkDefs[j]
+				if pred(md) && !yield(markDefPath(i, j), md) {
+					return
+				}
+			}
+		}
+	}
+}
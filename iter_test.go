@@ -0,0 +1,91 @@
+package portabletext
+
+import "testing"
+
+func iterTestDoc() Document {
+	b1 := NewBlock("h1").AddSpan("Title")
+	b2 := NewBlock("normal").AddSpan("See ").AddSpan("docs", "l1")
+	b2.AddMarkDef("l1", "link", map[string]any{"href": "https://example.com"})
+	b2.AddMarkDef("c1", "comment", nil)
+	b2.Children = append(b2.Children, Span{Type: "inlineImage", Raw: map[string]any{}})
+	return Document{*b1, *NewNode("image"), *b2}
+}
+
+func TestDocumentNodesAndBlocks(t *testing.T) {
+	doc := iterTestDoc()
+
+	var nodes, blocks []int
+	for i := range doc.Nodes() {
+		nodes = append(nodes, i)
+	}
+	for i := range doc.Blocks() {
+		blocks = append(blocks, i)
+	}
+	if len(nodes) != 3 {
+		t.Errorf("Nodes() yielded %v, want 3 nodes", nodes)
+	}
+	if len(blocks) != 2 || blocks[0] != 0 || blocks[1] != 2 {
+		t.Errorf("Blocks() yielded %v, want [0 2]", blocks)
+	}
+
+	// Early break must stop iteration.
+	count := 0
+	for range doc.Nodes() {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("Nodes() continued after break: %d", count)
+	}
+}
+
+func TestDocumentSpans(t *testing.T) {
+	doc := iterTestDoc()
+
+	var paths []Path
+	for p, s := range doc.Spans() {
+		paths = append(paths, p)
+		upper := *s.Text + "!"
+		s.Text = &upper
+	}
+	want := []Path{"[0].children[0]", "[2].children[0]", "[2].children[1]"}
+	if len(paths) != len(want) {
+		t.Fatalf("Spans() yielded %v, want %v", paths, want)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("Spans() path[%d] = %s, want %s", i, paths[i], want[i])
+		}
+	}
+	if doc[0].GetText() != "Title!" {
+		t.Errorf("Spans() mutation not applied: %s", doc[0].GetText())
+	}
+
+	for p, s := range doc.InlineObjects() {
+		if p != "[2].children[2]" || s.Type != "inlineImage" {
+			t.Errorf("InlineObjects() yielded %s %s", p, s.Type)
+		}
+	}
+}
+
+func TestDocumentMarkDefsAndLinks(t *testing.T) {
+	doc := iterTestDoc()
+
+	count := 0
+	for range doc.MarkDefs() {
+		count++
+	}
+	if count != 2 {
+		t.Errorf("MarkDefs() yielded %d, want 2", count)
+	}
+
+	for p, md := range doc.Links() {
+		if p != "[2].markDefs[0]" || md.Raw["href"] != "https://example.com" {
+			t.Errorf("Links() yielded %s %v", p, md.Raw)
+		}
+		ve := &ValidationError{Path: p.String(), Message: "checked"}
+		if ve.Error() != "[2].markDefs[0]: checked" {
+			t.Errorf("Path not usable in ValidationError: %s", ve.Error())
+		}
+	}
+}
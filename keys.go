@@ -0,0 +1,16 @@
+package portabletext
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// NewKey returns a random 12-character hex key in the style Sanity
+// generates, suitable for _key on new nodes, spans and markDefs.
+func NewKey() string {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("portabletext: crypto/rand failed: " + err.Error())
+	}
+	return hex.EncodeToString(b[:])
+}
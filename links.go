@@ -0,0 +1,314 @@
+package portabletext
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Link is a link annotation together with the text it annotates.
+type Link struct {
+	Path    Path     // Path of the markDef, e.g. "[3].markDefs[0]"
+	Index   int      // Index of the owning node in the document
+	MarkDef *MarkDef // Points into the document
+	Href    string   // MarkDef.Raw["href"], or "" if missing
+	Text    string   // Concatenated text of the spans carrying the mark
+	Spans   []Path   // Paths of the spans carrying the mark
+}
+
+// ExtractLinks returns every link markDef in document order.
+func ExtractLinks(doc Document) []Link {
+	var out []Link
+	for i := range doc {
+		n := &doc[i]
+		for j := range n.MarkDefs {
+			md := &n.MarkDefs[j]
+			if md.Type != "link" {
+				continue
+			}
+			l := Link{Path: markDefPath(i, j), Index: i, MarkDef: md}
+			l.Href, _ = md.Raw["href"].(string)
+			if md.Key != "" {
+				l.Text = markedText(n, md.Key)
+				for k := range n.Children {
+					if n.Children[k].HasMark(md.Key) {
+						l.Spans = append(l.Spans, childPath(i, k))
+					}
+				}
+			}
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// LinkPolicy controls ValidateLinks.
+type LinkPolicy struct {
+	// AllowedSchemes lists permitted URL schemes in lower case. Defaults to
+	// http, https, mailto and tel. "javascript", "vbscript" and "data" are
+	// always rejected.
+	AllowedSchemes []string
+
+	// AllowRelative permits hrefs without a scheme, such as "/about" or "#top".
+	AllowRelative bool
+
+	// RequireText reports links that annotate no text.
+	RequireText bool
+}
+
+var dangerousSchemes = map[string]bool{"javascript": true, "vbscript": true, "data": true}
+
+// IsUnsafeHref reports whether href uses a scheme that ValidateLinks
+// always rejects: "javascript", "vbscript" or "data", including when
+// obscured with whitespace, control characters or mixed case. Renderers
+// should not emit such hrefs.
+func IsUnsafeHref(href string) bool {
+	return dangerousSchemes[strings.ToLower(hrefScheme(href))]
+}
+
+// ValidateLinks checks every link against policy and returns one
+// *ValidationError per problem, using the markDef path.
+func ValidateLinks(doc Document, policy LinkPolicy) []error {
+	allowed := map[string]bool{}
+	schemes := policy.AllowedSchemes
+	if schemes == nil {
+		schemes = []string{"http", "https", "mailto", "tel"}
+	}
+	for _, s := range schemes {
+		allowed[strings.ToLower(s)] = true
+	}
+
+	var errs []error
+	for _, l := range ExtractLinks(doc) {
+		fail := func(format string, args ...any) {
+			errs = append(errs, &ValidationError{Path: l.Path.String(), Message: fmt.Sprintf(format, args...), Node: &doc[l.Index]})
+		}
+
+		if policy.RequireText && strings.TrimSpace(l.Text) == "" {
+			fail("link has no text")
+		}
+		href, ok := l.MarkDef.Raw["href"]
+		if !ok || href == nil {
+			fail("link missing href")
+			continue
+		}
+		if _, isString := href.(string); !isString {
+			fail("link href is not a string")
+			continue
+		}
+		if strings.TrimSpace(l.Href) == "" {
+			fail("link href is empty")
+			continue
+		}
+
+		// Browsers ignore embedded whitespace and control characters in
+		// schemes, so "java\tscript:" must be caught before parsing.
+		if scheme := strings.ToLower(hrefScheme(l.Href)); dangerousSchemes[scheme] {
+			fail("link uses forbidden scheme %q", scheme)
+			continue
+		}
+
+		u, err := url.Parse(strings.TrimSpace(l.Href))
+		if err != nil {
+			fail("invalid link URL: %v", err)
+			continue
+		}
+		switch {
+		case u.Scheme == "":
+			if !policy.AllowRelative {
+				fail("relative link %q not allowed", l.Href)
+			}
+		case !allowed[strings.ToLower(u.Scheme)]:
+			fail("link scheme %q not allowed", u.Scheme)
+		case (u.Scheme == "http" || u.Scheme == "https") && u.Host == "":
+			fail("link %q has no host", l.Href)
+		}
+	}
+	return errs
+}
+
+// hrefScheme returns the scheme of href with whitespace and control
+// characters removed, or "" if there is none.
+func hrefScheme(href string) string {
+	var b strings.Builder
+	for _, r := range href {
+		if r == ':' {
+			return b.String()
+		}
+		if r <= ' ' || r == 0x7f {
+			continue
+		}
+		if r == '/' || r == '?' || r == '#' {
+			return ""
+		}
+		b.WriteRune(r)
+	}
+	return ""
+}
+
+// LinkRewriter maps a link to its new href. Returning l.Href leaves the
+// link unchanged.
+type LinkRewriter func(l Link) string
+
+// RewriteLinks returns a copy of doc with every link href passed through fn.
+// Links without a string href are passed with Href "" and only updated if
+// fn returns a non-empty value.
+func RewriteLinks(doc Document, fn LinkRewriter) Document {
+	out := make(Document, len(doc))
+	for i := range doc {
+		out[i] = *doc[i].Clone()
+	}
+	for _, l := range ExtractLinks(out) {
+		href := fn(l)
+		if href == l.Href || (href == "" && l.Href == "") {
+			continue
+		}
+		if l.MarkDef.Raw == nil {
+			l.MarkDef.Raw = map[string]any{}
+		}
+		l.MarkDef.Raw["href"] = href
+	}
+	return out
+}
+
+// ChainRewriters applies rewriters in order, each seeing the previous result.
+func ChainRewriters(rewriters ...LinkRewriter) LinkRewriter {
+	return func(l Link) string {
+		for _, rw := range rewriters {
+			l.Href = rw(l)
+		}
+		return l.Href
+	}
+}
+
+// ResolveRelative makes relative hrefs absolute against base. Absolute and
+// unparsable hrefs are left unchanged.
+func ResolveRelative(base *url.URL) LinkRewriter {
+	return func(l Link) string {
+		u, err := url.Parse(l.Href)
+		if err != nil || u.Scheme != "" || l.Href == "" {
+			return l.Href
+		}
+		return base.ResolveReference(u).String()
+	}
+}
+
+// AddQueryParams sets query parameters (e.g. utm_source) on http(s) links
+// whose host matches one of hosts, or on all http(s) links if hosts is empty.
+func AddQueryParams(params map[string]string, hosts ...string) LinkRewriter {
+	return func(l Link) string {
+		u, err := url.Parse(l.Href)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !hostMatches(u.Hostname(), hosts) {
+			return l.Href
+		}
+		q := u.Query()
+		for k, v := range params {
+			q.Set(k, v)
+		}
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+}
+
+// ReplaceHost rewrites links on host from to host to, keeping path, query
+// and fragment, for domain migrations.
+func ReplaceHost(from, to string) LinkRewriter {
+	return func(l Link) string {
+		u, err := url.Parse(l.Href)
+		if err != nil || !strings.EqualFold(u.Hostname(), from) {
+			return l.Href
+		}
+		if port := u.Port(); port != "" {
+			u.Host = to + ":" + port
+		} else {
+			u.Host = to
+		}
+		return u.String()
+	}
+}
+
+func hostMatches(host string, hosts []string) bool {
+	if len(hosts) == 0 {
+		return true
+	}
+	for _, h := range hosts {
+		if strings.EqualFold(host, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// InternalLinkOptions controls CheckInternalLinks.
+type InternalLinkOptions struct {
+	// Hosts are treated as internal in addition to relative links,
+	// e.g. "example.com" and "www.example.com".
+	Hosts []string
+
+	// BasePath is stripped from link paths before the slug lookup,
+	// e.g. "/blog/" maps "/blog/hello-world" to "hello-world".
+	BasePath string
+
+	// CheckFragments verifies fragment-only links ("#setup") against the
+	// heading slugs of the document, as produced by Outline.
+	CheckFragments bool
+}
+
+// CheckInternalLinks reports internal links whose target is not among the
+// known slugs, without any network access. External links are ignored.
+func CheckInternalLinks(doc Document, slugs []string, opts InternalLinkOptions) []error {
+	known := make(map[string]bool, len(slugs))
+	for _, s := range slugs {
+		known[strings.Trim(s, "/")] = true
+	}
+
+	var anchors map[string]bool
+	if opts.CheckFragments {
+		anchors = map[string]bool{}
+		Outline(doc).Walk(func(s *Section) error {
+			if s.Heading != nil {
+				anchors[s.Slug] = true
+			}
+			return nil
+		})
+	}
+
+	var errs []error
+	for _, l := range ExtractLinks(doc) {
+		u, err := url.Parse(strings.TrimSpace(l.Href))
+		if err != nil || l.Href == "" {
+			continue
+		}
+		if u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" {
+			continue
+		}
+		if u.Host != "" && (len(opts.Hosts) == 0 || !hostMatches(u.Hostname(), opts.Hosts)) {
+			continue // external
+		}
+
+		if u.Path == "" && u.Fragment != "" {
+			if anchors != nil && !anchors[u.Fragment] {
+				errs = append(errs, &ValidationError{Path: l.Path.String(), Message: fmt.Sprintf("broken anchor link %q", l.Href), Node: &doc[l.Index]})
+			}
+			continue
+		}
+
+		p := u.Path
+		if !strings.HasPrefix(p, "/") {
+			p = "/" + p
+		}
+		p = path.Clean(p)
+		if opts.BasePath != "" {
+			base := "/" + strings.Trim(opts.BasePath, "/")
+			if p != base && !strings.HasPrefix(p, base+"/") {
+				continue // outside the checked section of the site
+			}
+			p = strings.TrimPrefix(p, base)
+		}
+		if slug := strings.Trim(p, "/"); !known[slug] {
+			errs = append(errs, &ValidationError{Path: l.Path.String(), Message: fmt.Sprintf("broken internal link %q", l.Href), Node: &doc[l.Index]})
+		}
+	}
+	return errs
+}
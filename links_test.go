@@ -0,0 +1,134 @@
+package portabletext
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func linkTestDoc(hrefs ...any) Document {
+	n := NewBlock("normal")
+	for i, h := range hrefs {
+		key := "l" + string(rune('0'+i))
+		n.AddSpan("link "+key, key)
+		raw := map[string]any{}
+		if h != nil {
+			raw["href"] = h
+		}
+		n.AddMarkDef(key, "link", raw)
+	}
+	return Document{*n}
+}
+
+func errPaths(errs []error) string {
+	var out []string
+	for _, err := range errs {
+		out = append(out, err.(*ValidationError).Path)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestExtractLinks(t *testing.T) {
+	doc := Document{*NewBlock("normal").
+		AddSpan("Read ").
+		AddSpan("the ", "l1").
+		AddSpan("docs", "l1", "strong").
+		AddMarkDef("l1", "link", map[string]any{"href": "https://example.com/docs"}).
+		AddMarkDef("c1", "comment", nil)}
+
+	links := ExtractLinks(doc)
+	if len(links) != 1 {
+		t.Fatalf("ExtractLinks() returned %d links, want 1", len(links))
+	}
+	l := links[0]
+	if l.Path != "[0].markDefs[0]" || l.Href != "https://example.com/docs" || l.Text != "the docs" || len(l.Spans) != 2 {
+		t.Errorf("ExtractLinks() = %+v", l)
+	}
+}
+
+func TestValidateLinks(t *testing.T) {
+	doc := linkTestDoc(
+		"https://example.com",
+		"javascript:alert(1)",
+		"java\tscript:alert(1)",
+		"/relative",
+		"ftp://example.com/file",
+		nil,
+		"http://",
+		"mailto:someone@example.com",
+	)
+
+	got := errPaths(ValidateLinks(doc, LinkPolicy{}))
+	want := "[0].markDefs[1] [0].markDefs[2] [0].markDefs[3] [0].markDefs[4] [0].markDefs[5] [0].markDefs[6]"
+	if got != want {
+		t.Errorf("ValidateLinks() = %s\nwant %s", got, want)
+	}
+
+	got = errPaths(ValidateLinks(doc, LinkPolicy{AllowRelative: true, AllowedSchemes: []string{"https", "ftp", "javascript"}}))
+	want = "[0].markDefs[1] [0].markDefs[2] [0].markDefs[5] [0].markDefs[6] [0].markDefs[7]"
+	if got != want {
+		t.Errorf("ValidateLinks() with policy = %s\nwant %s", got, want)
+	}
+
+	for href, want := range map[string]bool{
+		"javascript:alert(1)":    true,
+		" JavaScript:alert(1)":   true,
+		"java\tscript:alert(1)":  true,
+		"data:text/html,x":       true,
+		"https://example.com":    false,
+		"/a?next=javascript:x":   false,
+		"#javascript:not-scheme": false,
+	} {
+		if got := IsUnsafeHref(href); got != want {
+			t.Errorf("IsUnsafeHref(%q) = %v, want %v", href, got, want)
+		}
+	}
+}
+
+func TestRewriteLinks(t *testing.T) {
+	doc := linkTestDoc("/pricing", "https://old.example.com/a?x=1#top", "https://other.org/", "mailto:a@b.c")
+	base, _ := url.Parse("https://example.com/blog/")
+
+	out := RewriteLinks(doc, ChainRewriters(
+		ResolveRelative(base),
+		ReplaceHost("old.example.com", "example.com"),
+		AddQueryParams(map[string]string{"utm_source": "newsletter"}, "example.com"),
+	))
+
+	want := []string{
+		"https://example.com/pricing?utm_source=newsletter",
+		"https://example.com/a?utm_source=newsletter&x=1#top",
+		"https://other.org/",
+		"mailto:a@b.c",
+	}
+	for i, l := range ExtractLinks(out) {
+		if l.Href != want[i] {
+			t.Errorf("link %d = %s, want %s", i, l.Href, want[i])
+		}
+	}
+	if doc[0].MarkDefs[0].Raw["href"] != "/pricing" {
+		t.Error("RewriteLinks() mutated the input document")
+	}
+}
+
+func TestCheckInternalLinks(t *testing.T) {
+	doc := linkTestDoc(
+		"/blog/hello-world",
+		"https://example.com/blog/missing/",
+		"https://elsewhere.org/blog/missing",
+		"/about",
+		"#setup",
+		"#nope",
+		"mailto:a@b.c",
+	)
+	doc = append(Document{*NewBlock("h2").AddSpan("Setup")}, doc...)
+
+	errs := CheckInternalLinks(doc, []string{"hello-world"}, InternalLinkOptions{
+		Hosts:          []string{"example.com"},
+		BasePath:       "/blog/",
+		CheckFragments: true,
+	})
+	if got := errPaths(errs); got != "[1].markDefs[1] [1].markDefs[5]" {
+		t.Errorf("CheckInternalLinks() = %s", got)
+	}
+}
@@ -0,0 +1,656 @@
+package portabletext
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Severity ranks lint findings.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+// String returns "info", "warning" or "error".
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity parses "info", "warning" (or "warn") and "error".
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(s) {
+	case "info":
+		return SeverityInfo, nil
+	case "warning", "warn":
+		return SeverityWarning, nil
+	case "error":
+		return SeverityError, nil
+	}
+	return 0, fmt.Errorf("unknown severity %q", s)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Severity) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Severity) UnmarshalText(b []byte) error {
+	v, err := ParseSeverity(string(b))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+var (
+	ErrUnknownRule   = errors.New("unknown lint rule")
+	ErrDuplicateRule = errors.New("duplicate lint rule")
+)
+
+// LintTarget is the item a Rule inspects: a top-level node, one of its
+// children, or one of its mark definitions. Span and MarkDef are nil for
+// node targets; for nested targets Node is the owning node.
+type LintTarget struct {
+	Doc     Document // The whole document, for rules that need context
+	Index   int      // Index of Node in Doc
+	Path    Path
+	Node    *Node
+	Span    *Span
+	MarkDef *MarkDef
+
+	// Remove may be set by a Fix to delete the target from the document.
+	Remove bool
+
+	run *lintRun
+}
+
+// IsNode reports whether the target is a top-level node.
+func (t *LintTarget) IsNode() bool { return t.Span == nil && t.MarkDef == nil }
+
+// IsSpan reports whether the target is a block child (span or inline object).
+func (t *LintTarget) IsSpan() bool { return t.Span != nil }
+
+// IsMarkDef reports whether the target is a mark definition.
+func (t *LintTarget) IsMarkDef() bool { return t.MarkDef != nil }
+
+// Rule is a named lint check with an optional automatic fix.
+type Rule struct {
+	Name        string
+	Description string
+	Severity    Severity
+	DefaultOff  bool // Disabled unless enabled explicitly or via config
+
+	// Check is called for every target and returns one message per problem.
+	Check func(t *LintTarget) []string
+
+	// Fix, if set, repairs the problems Check reported by mutating the
+	// target in place or setting t.Remove.
+	Fix func(t *LintTarget)
+
+	// CanFix, if set, reports whether Fix repairs the problems at t. Other
+	// findings are not Fixable and Fix is not called for them. By default
+	// Fix handles every target.
+	CanFix func(t *LintTarget) bool
+}
+
+// LintFinding is a problem reported by a Rule. It embeds a ValidationError
+// so findings carry the same path and message as Validate results.
+type LintFinding struct {
+	ValidationError
+	Rule     string
+	Severity Severity
+	Fixable  bool
+}
+
+func (f *LintFinding) Error() string {
+	return fmt.Sprintf("%s: %s (%s, %s)", f.Path, f.Message, f.Severity, f.Rule)
+}
+
+// LintReport describes the outcome of Linter.Fix. Paths in Fixed refer to
+// the input document; paths in Remaining refer to the fixed document.
+type LintReport struct {
+	Fixed     []*LintFinding
+	Remaining []*LintFinding
+}
+
+// LintConfig enables, disables and re-ranks rules by name. Values are
+// "off", "on" (default severity), "info", "warning" or "error".
+//
+//	{"rules": {"double-space": "off", "missing-key": "error"}}
+type LintConfig struct {
+	Rules map[string]string `json:"rules"`
+}
+
+// LoadLintConfig reads a JSON LintConfig.
+func LoadLintConfig(r io.Reader) (LintConfig, error) {
+	var cfg LintConfig
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return LintConfig{}, wrap("lint", "", err)
+	}
+	return cfg, nil
+}
+
+// Linter runs a registry of rules over documents.
+// Configure a Linter before sharing it; Lint and Fix are then safe for
+// concurrent use.
+type Linter struct {
+	rules []*ruleState
+	index map[string]*ruleState
+}
+
+type ruleState struct {
+	rule     Rule
+	enabled  bool
+	severity Severity
+}
+
+// NewLinter returns a Linter with the built-in rules registered.
+func NewLinter() *Linter {
+	l := NewEmptyLinter()
+	for _, r := range BuiltinRules() {
+		if err := l.Register(r); err != nil {
+			panic(err)
+		}
+	}
+	return l
+}
+
+// NewEmptyLinter returns a Linter without any rules.
+func NewEmptyLinter() *Linter {
+	return &Linter{index: map[string]*ruleState{}}
+}
+
+// Register adds a rule. Names must be unique.
+func (l *Linter) Register(r Rule) error {
+	if r.Name == "" || r.Check == nil {
+		return wrap("lint", "", errors.New("rule requires Name and Check"))
+	}
+	if _, ok := l.index[r.Name]; ok {
+		return wrap("lint", "", fmt.Errorf("%w: %q", ErrDuplicateRule, r.Name))
+	}
+	st := &ruleState{rule: r, enabled: !r.DefaultOff, severity: r.Severity}
+	l.rules = append(l.rules, st)
+	l.index[r.Name] = st
+	return nil
+}
+
+// Rules returns the registered rules in registration order.
+func (l *Linter) Rules() []Rule {
+	out := make([]Rule, len(l.rules))
+	for i, st := range l.rules {
+		out[i] = st.rule
+	}
+	return out
+}
+
+// Enabled reports whether the named rule is enabled.
+func (l *Linter) Enabled(name string) bool {
+	st, ok := l.index[name]
+	return ok && st.enabled
+}
+
+// Enable turns on the named rule.
+func (l *Linter) Enable(name string) error {
+	return l.set(name, func(st *ruleState) { st.enabled = true })
+}
+
+// Disable turns off the named rule.
+func (l *Linter) Disable(name string) error {
+	return l.set(name, func(st *ruleState) { st.enabled = false })
+}
+
+// SetSeverity enables the named rule and overrides its severity.
+func (l *Linter) SetSeverity(name string, sev Severity) error {
+	return l.set(name, func(st *ruleState) {
+		st.enabled = true
+		st.severity = sev
+	})
+}
+
+// Configure applies a LintConfig. Unknown rule names are errors.
+func (l *Linter) Configure(cfg LintConfig) error {
+	for name, v := range cfg.Rules {
+		var err error
+		switch strings.ToLower(v) {
+		case "off":
+			err = l.Disable(name)
+		case "on":
+			err = l.Enable(name)
+		default:
+			sev, perr := ParseSeverity(v)
+			if perr != nil {
+				return wrap("lint", "", fmt.Errorf("rule %q: %w", name, perr))
+			}
+			err = l.SetSeverity(name, sev)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Linter) set(name string, fn func(*ruleState)) error {
+	st, ok := l.index[name]
+	if !ok {
+		return wrap("lint", "", fmt.Errorf("%w: %q", ErrUnknownRule, name))
+	}
+	fn(st)
+	return nil
+}
+
+// Lint runs all enabled rules and returns findings in document order.
+func (l *Linter) Lint(doc Document) []*LintFinding {
+	run := &lintRun{}
+	var out []*LintFinding
+	for i := range doc {
+		n := &doc[i]
+		out = append(out, l.check(&LintTarget{Doc: doc, Index: i, Path: nodePath(i), Node: n, run: run})...)
+		for j := range n.Children {
+			out = append(out, l.check(&LintTarget{Doc: doc, Index: i, Path: childPath(i, j), Node: n, Span: &n.Children[j], run: run})...)
+		}
+		for j := range n.MarkDefs {
+			out = append(out, l.check(&LintTarget{Doc: doc, Index: i, Path: markDefPath(i, j), Node: n, MarkDef: &n.MarkDefs[j], run: run})...)
+		}
+	}
+	return out
+}
+
+// Fix applies the fixes of all enabled rules to a copy of doc and returns
+// it with a report. Within each node, children are fixed first, then mark
+// definitions, then the node itself, so node-level rules see the results.
+func (l *Linter) Fix(doc Document) (Document, LintReport) {
+	work := make(Document, len(doc))
+	for i := range doc {
+		work[i] = *doc[i].Clone()
+	}
+
+	var report LintReport
+	run := &lintRun{}
+	out := make(Document, 0, len(work))
+	for i := range work {
+		n := &work[i]
+
+		if n.Children != nil {
+			kept := make([]Span, 0, len(n.Children))
+			for j := range n.Children {
+				t := &LintTarget{Doc: work, Index: i, Path: childPath(i, j), Node: n, Span: &n.Children[j], run: run}
+				report.Fixed = append(report.Fixed, l.fix(t)...)
+				if !t.Remove {
+					kept = append(kept, n.Children[j])
+				}
+			}
+			n.Children = kept
+		}
+
+		if n.MarkDefs != nil {
+			kept := make([]MarkDef, 0, len(n.MarkDefs))
+			for j := range n.MarkDefs {
+				t := &LintTarget{Doc: work, Index: i, Path: markDefPath(i, j), Node: n, MarkDef: &n.MarkDefs[j], run: run}
+				report.Fixed = append(report.Fixed, l.fix(t)...)
+				if !t.Remove {
+					kept = append(kept, n.MarkDefs[j])
+				}
+			}
+			n.MarkDefs = kept
+		}
+
+		t := &LintTarget{Doc: work, Index: i, Path: nodePath(i), Node: n, run: run}
+		report.Fixed = append(report.Fixed, l.fix(t)...)
+		if !t.Remove {
+			out = append(out, *n)
+		}
+	}
+
+	report.Remaining = l.Lint(out)
+	return out, report
+}
+
+func (l *Linter) check(t *LintTarget) []*LintFinding {
+	var out []*LintFinding
+	for _, st := range l.rules {
+		if st.enabled {
+			out = append(out, st.findings(t)...)
+		}
+	}
+	return out
+}
+
+// fix checks t against each enabled fixable rule and applies fixes,
+// returning the findings that were fixed.
+func (l *Linter) fix(t *LintTarget) []*LintFinding {
+	var fixed []*LintFinding
+	for _, st := range l.rules {
+		if !st.enabled || st.rule.Fix == nil {
+			continue
+		}
+		fs := st.findings(t)
+		if len(fs) == 0 || !fs[0].Fixable {
+			continue
+		}
+		st.rule.Fix(t)
+		fixed = append(fixed, fs...)
+		if t.Remove {
+			break
+		}
+	}
+	return fixed
+}
+
+func (st *ruleState) findings(t *LintTarget) []*LintFinding {
+	msgs := st.rule.Check(t)
+	out := make([]*LintFinding, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, &LintFinding{
+			ValidationError: ValidationError{Path: t.Path.String(), Message: m, Node: t.Node},
+			Rule:            st.rule.Name,
+			Severity:        st.severity,
+			Fixable:         st.rule.Fix != nil && (st.rule.CanFix == nil || st.rule.CanFix(t)),
+		})
+	}
+	return out
+}
+
+// lintRun holds lazily computed, document-wide state for built-in rules.
+type lintRun struct {
+	firstKey    map[string]int // node _key -> index of first node using it
+	prevHeading []int          // level of the closest heading before each node
+	h1          int            // index of the first h1, or -1
+}
+
+func (r *lintRun) firstIndexOfKey(doc Document, key string) int {
+	if r.firstKey == nil {
+		r.firstKey = map[string]int{}
+		for i := range doc {
+			if k := doc[i].Key; k != "" {
+				if _, ok := r.firstKey[k]; !ok {
+					r.firstKey[k] = i
+				}
+			}
+		}
+	}
+	if i, ok := r.firstKey[key]; ok {
+		return i
+	}
+	return -1
+}
+
+//
+// Built-in rules
+//
+
+// standardDecorators are the default Portable Text decorator marks, which
+// are not expected to have a markDef.
+var standardDecorators = map[string]bool{
+	"strong": true, "em": true, "code": true, "underline": true, "strike-through": true,
+}
+
+// BuiltinRules returns the rules registered by NewLinter. The first group
+// mirrors ValidateWithOptions; missing-key and undefined-mark are off by
+// default, as RequireKeys and CheckMarkDefRefs are.
+func BuiltinRules() []Rule {
+	return []Rule{
+		{
+			Name:        "missing-type",
+			Description: "nodes, children and markDefs must have a _type",
+			Severity:    SeverityError,
+			Check: func(t *LintTarget) []string {
+				switch {
+				case t.IsNode() && t.Node.Type == "":
+					return []string{"missing _type"}
+				case t.IsSpan() && t.Span.Type == "":
+					return []string{"missing _type"}
+				case t.IsMarkDef() && t.MarkDef.Type == "":
+					return []string{"markDef missing _type"}
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "markdef-missing-key",
+			Description: "markDefs must have a _key",
+			Severity:    SeverityError,
+			Check: func(t *LintTarget) []string {
+				if t.IsMarkDef() && t.MarkDef.Key == "" {
+					return []string{"markDef missing _key"}
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "span-missing-text",
+			Description: "spans must have a text field",
+			Severity:    SeverityError,
+			Check: func(t *LintTarget) []string {
+				if t.IsSpan() && t.Span.Type == "span" && t.Span.Text == nil {
+					return []string{"span missing text"}
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "empty-text",
+			Description: "spans should not have empty text",
+			Severity:    SeverityWarning,
+			Check: func(t *LintTarget) []string {
+				if isSpanTarget(t) && *t.Span.Text == "" {
+					return []string{"span has empty text"}
+				}
+				return nil
+			},
+			Fix: func(t *LintTarget) { t.Remove = true },
+		},
+		{
+			Name:        "missing-key",
+			Description: "top-level nodes must have a _key",
+			Severity:    SeverityError,
+			DefaultOff:  true,
+			Check: func(t *LintTarget) []string {
+				if t.IsNode() && t.Node.Key == "" {
+					return []string{"missing _key"}
+				}
+				return nil
+			},
+			Fix: func(t *LintTarget) { t.Node.Key = NewKey() },
+		},
+		{
+			Name:        "undefined-mark",
+			Description: "non-decorator marks must reference a markDef",
+			Severity:    SeverityError,
+			DefaultOff:  true,
+			Check: func(t *LintTarget) []string {
+				if !t.IsSpan() {
+					return nil
+				}
+				var msgs []string
+				for _, m := range undefinedMarks(t.Node, t.Span) {
+					msgs = append(msgs, fmt.Sprintf("mark '%s' not found in markDefs", m))
+				}
+				return msgs
+			},
+			Fix: func(t *LintTarget) {
+				bad := map[string]bool{}
+				for _, m := range undefinedMarks(t.Node, t.Span) {
+					bad[m] = true
+				}
+				kept := t.Span.Marks[:0:0]
+				for _, m := range t.Span.Marks {
+					if !bad[m] {
+						kept = append(kept, m)
+					}
+				}
+				t.Span.Marks = kept
+			},
+		},
+		{
+			Name:        "duplicate-key",
+			Description: "_key values must be unique among top-level nodes and within a node's markDefs",
+			Severity:    SeverityError,
+			Check: func(t *LintTarget) []string {
+				switch {
+				case t.IsNode() && t.Node.Key != "":
+					if first := t.run.firstIndexOfKey(t.Doc, t.Node.Key); first >= 0 && first != t.Index {
+						return []string{fmt.Sprintf("duplicate _key %q (first used at %s)", t.Node.Key, nodePath(first))}
+					}
+				case t.IsMarkDef() && t.MarkDef.Key != "":
+					for j := range t.Node.MarkDefs {
+						md := &t.Node.MarkDefs[j]
+						if md == t.MarkDef {
+							break
+						}
+						if md.Key == t.MarkDef.Key {
+							return []string{fmt.Sprintf("duplicate markDef _key %q", md.Key)}
+						}
+					}
+				}
+				return nil
+			},
+			// Spans cannot tell duplicate markDefs apart, so only node keys
+			// are replaced.
+			CanFix: func(t *LintTarget) bool { return t.IsNode() },
+			Fix: func(t *LintTarget) {
+				k := NewKey()
+				for t.run.firstIndexOfKey(t.Doc, k) >= 0 {
+					k = NewKey()
+				}
+				t.Node.Key = k
+				t.run.firstKey[k] = t.Index
+			},
+		},
+		{
+			Name:        "orphaned-markdef",
+			Description: "markDefs should be referenced by at least one span",
+			Severity:    SeverityWarning,
+			Check: func(t *LintTarget) []string {
+				if !t.IsMarkDef() || t.MarkDef.Key == "" {
+					return nil
+				}
+				for i := range t.Node.Children {
+					if t.Node.Children[i].HasMark(t.MarkDef.Key) {
+						return nil
+					}
+				}
+				return []string{fmt.Sprintf("markDef %q is not used by any span", t.MarkDef.Key)}
+			},
+			Fix: func(t *LintTarget) { t.Remove = true },
+		},
+		{
+			Name:        "whitespace-only-span",
+			Description: "whitespace-only spans should not carry marks",
+			Severity:    SeverityWarning,
+			Check: func(t *LintTarget) []string {
+				if isSpanTarget(t) && *t.Span.Text != "" && strings.TrimSpace(*t.Span.Text) == "" && len(t.Span.Marks) > 0 {
+					return []string{"whitespace-only span has marks"}
+				}
+				return nil
+			},
+			Fix: func(t *LintTarget) { t.Span.Marks = []string{} },
+		},
+		{
+			Name:        "double-space",
+			Description: "span text should not contain consecutive spaces",
+			Severity:    SeverityInfo,
+			Check: func(t *LintTarget) []string {
+				if isSpanTarget(t) && strings.Contains(*t.Span.Text, "  ") {
+					return []string{"span contains double spaces"}
+				}
+				return nil
+			},
+			Fix: func(t *LintTarget) {
+				s := *t.Span.Text
+				for strings.Contains(s, "  ") {
+					s = strings.ReplaceAll(s, "  ", " ")
+				}
+				t.Span.Text = &s
+			},
+		},
+		{
+			Name:        "trailing-space",
+			Description: "block text should not end with whitespace",
+			Severity:    SeverityInfo,
+			Check: func(t *LintTarget) []string {
+				if !t.IsNode() || !t.Node.IsBlock() {
+					return nil
+				}
+				text := t.Node.GetText()
+				if text != strings.TrimRightFunc(text, unicode.IsSpace) && strings.TrimSpace(text) != "" {
+					return []string{"block has trailing whitespace"}
+				}
+				return nil
+			},
+			Fix: func(t *LintTarget) {
+				for j := len(t.Node.Children) - 1; j >= 0; j-- {
+					c := &t.Node.Children[j]
+					if c.Type != "span" || c.Text == nil {
+						return // inline object ends the trailing text
+					}
+					s := strings.TrimRightFunc(*c.Text, unicode.IsSpace)
+					if s == "" && j > 0 {
+						t.Node.Children = t.Node.Children[:j]
+						continue
+					}
+					c.Text = &s
+					return
+				}
+			},
+		},
+		{
+			Name:        "empty-block",
+			Description: "blocks should contain text or inline objects",
+			Severity:    SeverityWarning,
+			Check: func(t *LintTarget) []string {
+				if !t.IsNode() || !t.Node.IsBlock() {
+					return nil
+				}
+				for _, c := range t.Node.Children {
+					if c.Type != "span" || (c.Text != nil && strings.TrimSpace(*c.Text) != "") {
+						return nil
+					}
+				}
+				return []string{"block is empty"}
+			},
+			Fix: func(t *LintTarget) { t.Remove = true },
+		},
+	}
+}
+
+func isSpanTarget(t *LintTarget) bool {
+	return t.IsSpan() && t.Span.Type == "span" && t.Span.Text != nil
+}
+
+func undefinedMarks(n *Node, s *Span) []string {
+	var out []string
+	for _, m := range s.Marks {
+		if standardDecorators[m] {
+			continue
+		}
+		found := false
+		for _, md := range n.MarkDefs {
+			if md.Key == m {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, m)
+		}
+	}
+	return out
+}
@@ -0,0 +1,154 @@
+package portabletext
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func lintRules(findings []*LintFinding) string {
+	var names []string
+	for _, f := range findings {
+		names = append(names, f.Path+" "+f.Rule)
+	}
+	return strings.Join(names, "|")
+}
+
+func TestLinterMirrorsValidate(t *testing.T) {
+	doc := Document{Node{
+		Type: "block",
+		Children: []Span{
+			{Type: "span", Text: nil},
+			{Type: "span", Text: stringPtr("")},
+		},
+		MarkDefs: []MarkDef{{Type: "", Key: ""}},
+	}}
+
+	var validateMsgs, lintMsgs []string
+	for _, err := range Validate(doc) {
+		validateMsgs = append(validateMsgs, err.(*ValidationError).Path+": "+err.(*ValidationError).Message)
+	}
+	l := NewLinter()
+	l.Disable("orphaned-markdef")
+	l.Disable("empty-block")
+	for _, f := range l.Lint(doc) {
+		lintMsgs = append(lintMsgs, f.ValidationError.Error())
+	}
+
+	want := strings.Join(validateMsgs, "|")
+	if got := strings.Join(lintMsgs, "|"); got != want {
+		t.Errorf("Lint() = %s\nwant %s", got, want)
+	}
+}
+
+func TestLinterNewRules(t *testing.T) {
+	dup := NewBlock("normal").AddSpan("Double  space ")
+	dup.Key = "a"
+	dup2 := NewBlock("normal").AddSpan(" ", "strong").AddSpan("x")
+	dup2.Key = "a"
+	dup2.AddMarkDef("l1", "link", nil)
+
+	doc := Document{*dup, *dup2, *NewBlock("normal")}
+	got := lintRules(NewLinter().Lint(doc))
+	want := "[0] trailing-space|[0].children[0] double-space|[1] duplicate-key|[1].children[0] whitespace-only-span|[1].markDefs[0] orphaned-markdef|[2] empty-block"
+	if got != want {
+		t.Errorf("Lint() = %s\nwant %s", got, want)
+	}
+}
+
+func TestLinterFix(t *testing.T) {
+	b := NewBlock("normal").AddSpan("Hello  world", "strong").AddSpan("").AddSpan("  ")
+	b.Key = "k"
+	b.AddMarkDef("unused", "link", nil)
+	dupe := NewBlock("normal").AddSpan("Again")
+	dupe.Key = "k"
+
+	doc := Document{*b, *NewBlock("normal").AddSpan(""), *dupe}
+	fixed, report := NewLinter().Fix(doc)
+
+	if len(report.Remaining) != 0 {
+		t.Errorf("Remaining = %s", lintRules(report.Remaining))
+	}
+	if len(report.Fixed) == 0 {
+		t.Fatal("Fix() reported nothing fixed")
+	}
+	if len(fixed) != 2 {
+		t.Fatalf("Fix() kept %d nodes, want 2 (empty block removed)", len(fixed))
+	}
+	if fixed[0].GetText() != "Hello world" || len(fixed[0].MarkDefs) != 0 {
+		t.Errorf("fixed[0] = %q with %d markDefs", fixed[0].GetText(), len(fixed[0].MarkDefs))
+	}
+	if fixed[1].Key == "k" || fixed[1].Key == "" {
+		t.Errorf("duplicate key not replaced: %q", fixed[1].Key)
+	}
+	if doc[0].GetText() != "Hello  world  " {
+		t.Error("Fix() mutated the input document")
+	}
+}
+
+func TestLinterFixOnlyFixable(t *testing.T) {
+	b := NewBlock("normal").AddSpan("link", "l1").AddMarkDef("l1", "link", nil).AddMarkDef("l1", "link", nil)
+	b.Key = "k"
+	l := NewEmptyLinter()
+	for _, r := range BuiltinRules() {
+		if r.Name == "duplicate-key" {
+			l.Register(r)
+		}
+	}
+
+	findings := l.Lint(Document{*b})
+	if len(findings) != 1 || findings[0].Fixable {
+		t.Fatalf("Lint() = %v, want one unfixable finding", findings)
+	}
+	_, report := l.Fix(Document{*b})
+	if len(report.Fixed) != 0 || lintRules(report.Remaining) != "[0].markDefs[1] duplicate-key" {
+		t.Errorf("Fixed = %s, Remaining = %s", lintRules(report.Fixed), lintRules(report.Remaining))
+	}
+}
+
+func TestLinterRegistryAndConfig(t *testing.T) {
+	l := NewLinter()
+	if l.Enabled("missing-key") {
+		t.Error("missing-key should be off by default")
+	}
+
+	err := l.Register(Rule{
+		Name:     "no-h1",
+		Severity: SeverityWarning,
+		Check: func(t *LintTarget) []string {
+			if t.IsNode() && t.Node.GetStyle() == "h1" {
+				return []string{"h1 is reserved for the page title"}
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := l.Register(Rule{Name: "no-h1", Check: func(*LintTarget) []string { return nil }}); !errors.Is(err, ErrDuplicateRule) {
+		t.Errorf("Register() duplicate error = %v", err)
+	}
+
+	cfg, err := LoadLintConfig(strings.NewReader(`{"rules":{"no-h1":"error","missing-key":"on","trailing-space":"off"}}`))
+	if err != nil {
+		t.Fatalf("LoadLintConfig() error = %v", err)
+	}
+	if err := l.Configure(cfg); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	findings := l.Lint(Document{*NewBlock("h1").AddSpan("Title ")})
+	if got := lintRules(findings); got != "[0] missing-key|[0] no-h1" {
+		t.Errorf("Lint() = %s", got)
+	}
+	if findings[1].Severity != SeverityError {
+		t.Errorf("no-h1 severity = %s, want error", findings[1].Severity)
+	}
+
+	if err := l.Configure(LintConfig{Rules: map[string]string{"bogus": "off"}}); !errors.Is(err, ErrUnknownRule) {
+		t.Errorf("Configure() unknown rule error = %v", err)
+	}
+	if err := l.Configure(LintConfig{Rules: map[string]string{"no-h1": "fatal"}}); err == nil {
+		t.Error("Configure() accepted invalid severity")
+	}
+}
@@ -0,0 +1,114 @@
+package portabletext
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotWritable is returned by Located.WriteBack for a Portable Text
+// array that is the root value itself and so has no parent to update.
+var ErrNotWritable = errors.New("portable text value has no parent")
+
+// Located is a Portable Text array found inside a larger JSON value.
+type Located struct {
+	Path string // field path from the root, e.g. "body" or "sections[2].content"; "" for the root
+	Doc  Document
+
+	set func(any)
+}
+
+// WriteBack stores l.Doc in the JSON value it was found in, replacing the
+// original array. The value can then be re-encoded with encoding/json.
+func (l *Located) WriteBack() error {
+	if l.set == nil {
+		return wrap("locate", l.Path, ErrNotWritable)
+	}
+	v, err := documentValue(l.Doc)
+	if err != nil {
+		return wrap("locate", l.Path, err)
+	}
+	l.set(v)
+	return nil
+}
+
+// FindPortableText walks v, a value decoded from JSON into maps and
+// slices, and returns every array that looks like Portable Text, in
+// field-name order. An array qualifies when it is non-empty, each element
+// is an object with a string _type, at least one element looks like a
+// block (_type "block", a markDefs array, or children containing a span)
+// and the whole array decodes. Found arrays are not searched further:
+// Portable Text nested inside a custom block stays in its Node.Raw and can
+// be found by calling FindPortableText on that.
+func FindPortableText(v any) []Located {
+	var out []Located
+	if arr, ok := v.([]any); ok {
+		if doc, ok := asPortableText(arr, ""); ok {
+			return []Located{{Doc: doc}}
+		}
+	}
+	findPortableText(v, "", func(l Located) { out = append(out, l) })
+	return out
+}
+
+func findPortableText(v any, p string, fn func(Located)) {
+	switch x := v.(type) {
+	case map[string]any:
+		for _, k := range sortedKeys(x) {
+			cp := joinFieldPath(p, k)
+			if arr, ok := x[k].([]any); ok {
+				if doc, ok := asPortableText(arr, cp); ok {
+					fn(Located{Path: cp, Doc: doc, set: func(nv any) { x[k] = nv }})
+					continue
+				}
+			}
+			findPortableText(x[k], cp, fn)
+		}
+	case []any:
+		for i, e := range x {
+			cp := fmt.Sprintf("%s[%d]", p, i)
+			if arr, ok := e.([]any); ok {
+				if doc, ok := asPortableText(arr, cp); ok {
+					fn(Located{Path: cp, Doc: doc, set: func(nv any) { x[i] = nv }})
+					continue
+				}
+			}
+			findPortableText(e, cp, fn)
+		}
+	}
+}
+
+// asPortableText applies the detection heuristics and decodes arr.
+func asPortableText(arr []any, p string) (Document, bool) {
+	if len(arr) == 0 {
+		return nil, false
+	}
+	blockLike := false
+	for _, e := range arr {
+		m, ok := e.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		t, ok := m["_type"].(string)
+		if !ok || t == "" {
+			return nil, false
+		}
+		if _, defs := m["markDefs"].([]any); t == "block" || defs || hasSpan(m["children"]) {
+			blockLike = true
+		}
+	}
+	if !blockLike {
+		return nil, false
+	}
+	doc, err := decodeArray(arr, p)
+	return doc, err == nil
+}
+
+func hasSpan(v any) bool {
+	arr, _ := v.([]any)
+	for _, e := range arr {
+		if m, ok := e.(map[string]any); ok && m["_type"] == "span" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package portabletext
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func decodeAny(t *testing.T, s string) any {
+	t.Helper()
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestFindPortableText(t *testing.T) {
+	v := decodeAny(t, `{
+		"_id": "page-1",
+		"title": "Home",
+		"tags": [{"_type": "tag", "name": "x"}],
+		"menu": [{"_type": "item", "children": [{"_type": "item", "title": "Sub"}]}],
+		"hero": {"text": [{"_type": "block", "children": [{"_type": "span", "text": "Hero"}], "markDefs": []}]},
+		"sections": [
+			{"_type": "textSection", "content": [
+				{"_type": "callout", "body": [{"_type": "block", "children": [{"_type": "span", "text": "Inner"}]}]},
+				{"_type": "block", "children": [{"_type": "span", "text": "Section"}]}
+			]},
+			[{"_type": "custom", "children": [{"_type": "span", "text": "Custom"}]}]
+		],
+		"broken": [{"_type": "block", "children": [{"text": "no type"}]}],
+		"empty": []
+	}`)
+
+	found := FindPortableText(v)
+	var got []string
+	for _, l := range found {
+		got = append(got, l.Path+"="+l.Doc[len(l.Doc)-1].GetText())
+	}
+	want := "hero.text=Hero sections[0].content=Section sections[1]=Custom"
+	if strings.Join(got, " ") != want {
+		t.Errorf("FindPortableText() = %v\nwant %s", got, want)
+	}
+
+	inner := FindPortableText(found[1].Doc[0].Raw)
+	if len(inner) != 1 || inner[0].Path != "body" || inner[0].Doc[0].GetText() != "Inner" {
+		t.Errorf("FindPortableText(custom block Raw) = %+v", inner)
+	}
+}
+
+func TestLocatedWriteBack(t *testing.T) {
+	v := decodeAny(t, `{"body":[{"_type":"block","children":[{"_type":"span","text":"Old"}]}],
+		"list":[[{"_type":"block","children":[{"_type":"span","text":"A"}]}]]}`)
+
+	found := FindPortableText(v)
+	for i := range found {
+		found[i].Doc = Document{*NewBlock("h1").AddSpan("New")}
+		if err := found[i].WriteBack(); err != nil {
+			t.Fatalf("WriteBack(%s) error = %v", found[i].Path, err)
+		}
+	}
+
+	b, _ := json.Marshal(v)
+	again := FindPortableText(decodeAny(t, string(b)))
+	if len(again) != 2 {
+		t.Fatalf("after WriteBack found %d fields, want 2", len(again))
+	}
+	for _, l := range again {
+		if l.Doc[0].GetText() != "New" || l.Doc[0].GetStyle() != "h1" {
+			t.Errorf("%s = %q (%s), want New (h1)", l.Path, l.Doc[0].GetText(), l.Doc[0].GetStyle())
+		}
+	}
+
+	root := FindPortableText(decodeAny(t, `[{"_type":"block","children":[]}]`))
+	if len(root) != 1 || root[0].Path != "" {
+		t.Fatalf("FindPortableText(root array) = %+v", root)
+	}
+	if err := root[0].WriteBack(); !errors.Is(err, ErrNotWritable) {
+		t.Errorf("WriteBack(root) error = %v, want ErrNotWritable", err)
+	}
+}
@@ -0,0 +1,110 @@
+package portabletext
+
+// Section is a node in a document outline. The root section returned by
+// Outline has no heading and holds any content before the first heading.
+type Section struct {
+	Heading  *Node      // Heading block, nil for the root
+	Index    int        // Index of the heading in the document, -1 for the root
+	Level    int        // 1–6 for h1–h6, 0 for the root
+	Slug     string     // Anchor slug, unique within the outline
+	Body     []*Node    // Non-heading nodes up to the next heading
+	Parent   *Section   // nil for the root
+	Children []*Section // Subsections in document order
+}
+
+// Outline groups doc into a tree of sections by heading style (h1–h6).
+//
+// Each heading becomes the child of the nearest preceding heading with a
+// lower level, so skipped levels (h1 followed by h3) nest directly and a
+// document that starts at h2 simply places those sections under the root.
+// Node pointers refer into doc. Slugs come from a fresh default Slugger.
+func Outline(doc Document) *Section {
+	return OutlineWithSlugger(doc, NewSlugger(SlugOptions{}))
+}
+
+// OutlineWithSlugger is like Outline but assigns slugs with s, so the same
+// anchors (and de-duplication state) can be shared with a renderer.
+func OutlineWithSlugger(doc Document, s *Slugger) *Section {
+	s.ReserveAnchors(doc)
+	root := &Section{Index: -1}
+	cur := root
+
+	for i := range doc {
+		n := &doc[i]
+		lvl := headingLevel(n)
+		if lvl == 0 {
+			cur.Body = append(cur.Body, n)
+			continue
+		}
+
+		parent := cur
+		for parent.Level >= lvl {
+			parent = parent.Parent
+		}
+		sec := &Section{
+			Heading: n,
+			Index:   i,
+			Level:   lvl,
+			Slug:    s.Slug(n),
+			Parent:  parent,
+		}
+		parent.Children = append(parent.Children, sec)
+		cur = sec
+	}
+	return root
+}
+
+// Title returns the heading text, or "" for the root.
+func (s *Section) Title() string {
+	if s.Heading == nil {
+		return ""
+	}
+	return s.Heading.GetText()
+}
+
+// Walk visits s and all of its descendants depth-first in document order;
+// stops early on fn error.
+func (s *Section) Walk(fn func(*Section) error) error {
+	if err := fn(s); err != nil {
+		return err
+	}
+	for _, c := range s.Children {
+		if err := c.Walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Find returns the first section with the given slug, or nil.
+func (s *Section) Find(slug string) *Section {
+	if s.Slug == slug && s.Heading != nil {
+		return s
+	}
+	for _, c := range s.Children {
+		if f := c.Find(slug); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// Document returns the section, including its heading and all
+// subsections, as a new standalone document of cloned nodes.
+func (s *Section) Document() Document {
+	var out Document
+	s.appendTo(&out)
+	return out
+}
+
+func (s *Section) appendTo(out *Document) {
+	if s.Heading != nil {
+		*out = append(*out, *s.Heading.Clone())
+	}
+	for _, n := range s.Body {
+		*out = append(*out, *n.Clone())
+	}
+	for _, c := range s.Children {
+		c.appendTo(out)
+	}
+}
@@ -0,0 +1,85 @@
+package portabletext
+
+import (
+	"strings"
+	"testing"
+)
+
+func outlineTestDoc() Document {
+	return Document{
+		*NewBlock("normal").AddSpan("Preface"),
+		*NewBlock("h1").AddSpan("Getting Started"),
+		*NewBlock("normal").AddSpan("Intro"),
+		*NewBlock("h3").AddSpan("Details"), // skipped h2
+		*NewNode("image"),
+		*NewBlock("h2").AddSpan("Install"),
+		*NewBlock("h1").AddSpan("Getting started!"),
+	}
+}
+
+func TestOutline(t *testing.T) {
+	root := Outline(outlineTestDoc())
+
+	if len(root.Body) != 1 || root.Body[0].GetText() != "Preface" {
+		t.Errorf("root body = %v, want Preface", root.Body)
+	}
+	if len(root.Children) != 2 {
+		t.Fatalf("root has %d children, want 2", len(root.Children))
+	}
+
+	gs := root.Children[0]
+	if gs.Slug != "getting-started" || gs.Index != 1 || len(gs.Body) != 1 {
+		t.Errorf("first section = %+v", gs)
+	}
+	if len(gs.Children) != 2 || gs.Children[0].Title() != "Details" || gs.Children[1].Title() != "Install" {
+		t.Fatalf("skipped level not nested under h1: %d children", len(gs.Children))
+	}
+	if gs.Children[0].Level != 3 || gs.Children[0].Parent != gs {
+		t.Errorf("Details level/parent wrong: %d", gs.Children[0].Level)
+	}
+	if root.Children[1].Slug != "getting-started-1" {
+		t.Errorf("duplicate slug = %s, want getting-started-1", root.Children[1].Slug)
+	}
+}
+
+func TestOutlineStartsBelowH1(t *testing.T) {
+	root := Outline(Document{
+		*NewBlock("h2").AddSpan("A"),
+		*NewBlock("h3").AddSpan("B"),
+		*NewBlock("h1").AddSpan("C"),
+	})
+	if len(root.Children) != 2 || root.Children[0].Children[0].Title() != "B" {
+		t.Errorf("unexpected outline shape")
+	}
+}
+
+func TestSectionDocumentAndFind(t *testing.T) {
+	doc := outlineTestDoc()
+	root := Outline(doc)
+
+	s := root.Find("getting-started")
+	if s == nil {
+		t.Fatal("Find() returned nil")
+	}
+	sub := s.Document()
+
+	var texts []string
+	for _, n := range sub {
+		texts = append(texts, n.Type+":"+n.GetText())
+	}
+	want := "block:Getting Started|block:Intro|block:Details|image:|block:Install"
+	if got := strings.Join(texts, "|"); got != want {
+		t.Errorf("Document() = %s, want %s", got, want)
+	}
+
+	sub[0].Key = "changed"
+	if doc[1].Key != "" {
+		t.Error("Document() did not clone nodes")
+	}
+
+	count := 0
+	root.Walk(func(*Section) error { count++; return nil })
+	if count != 5 {
+		t.Errorf("Walk() visited %d sections, want 5", count)
+	}
+}
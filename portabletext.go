@@ -73,6 +73,13 @@ type WalkContext struct {
 	BlockCount int
 }
 
+// Path identifies a location within a Document using the same notation as
+// Error.Path and ValidationError.Path, e.g. "[2].children[1]".
+type Path string
+
+// String returns the path in its textual form.
+func (p Path) String() string { return string(p) }
+
 // Decode parses JSON Portable Text into a Document.
 // - Requires _type on all nodes and child spans/markDefs where present
 // - Captures unknown fields into Raw (including explicit nulls)
@@ -420,10 +427,11 @@ var (
 	ErrInvalidMarks    = errors.New("marks must be an array of strings")
 	ErrInvalidNumber   = errors.New("invalid number")
 	ErrUnexpectedToken = errors.New("unexpected JSON token")
+	ErrInvalidQuery    = errors.New("invalid query")
 )
 
 type Error struct {
-	Op   string // "decode", "node", "span", "markDef"
+	Op   string // "decode", "node", "span", "markDef", or a feature such as "query" or "export"
 	Path string // e.g. "[3].children[1].marks"
 	Err  error
 }
@@ -437,6 +445,12 @@ func (e *Error) Error() string {
 
 func (e *Error) Unwrap() error { return e.Err }
 
+func nodePath(i int) Path { return Path(fmt.Sprintf("[%d]", i)) }
+
+func childPath(i, j int) Path { return Path(fmt.Sprintf("[%d].children[%d]", i, j)) }
+
+func markDefPath(i, j int) Path { return Path(fmt.Sprintf("[%d].markDefs[%d]", i, j)) }
+
 func wrap(op, path string, err error) error {
 	if err == nil {
 		return nil
@@ -0,0 +1,598 @@
+package portabletext
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Query is a compiled selector that can be reused across many documents.
+//
+// The selector syntax is modeled on CSS. A compound selector names a _type
+// (or * for any type) followed by optional attribute filters and kind
+// pseudo-classes; compound selectors are joined by combinators, and several
+// selectors may be separated by commas:
+//
+//	block[style=h2] > span[marks~=strong]
+//	image[asset._ref^=image-]
+//	block link:markDef[href*=example.com], code[language=go]
+//
+// Attribute filters support [name] (defined and not null), [name=value],
+// [name!=value], [name~=value] (array or whitespace list contains),
+// [name^=value], [name$=value] and [name*=value]. Names may be dotted paths
+// into Raw, e.g. [asset._ref]. Values may be bare words or quoted strings.
+//
+// Top-level nodes have no parent; their children and markDefs have the
+// owning node as parent. Because the tree is only two levels deep, the
+// descendant combinator (whitespace) and the child combinator (>) select
+// the same items. The pseudo-classes :node, :child and :markDef restrict a
+// compound selector to top-level nodes, block children or mark definitions.
+//
+// A Query is immutable and safe for concurrent use.
+type Query struct {
+	src       string
+	selectors [][]compound
+}
+
+// Match is a single item selected by a Query. Exactly one of Span or
+// MarkDef is set for nested items; both are nil when the match is the
+// top-level Node itself. Pointers refer into the queried document.
+type Match struct {
+	Path    Path
+	Node    *Node // the matched node, or the node owning the span/markDef
+	Span    *Span
+	MarkDef *MarkDef
+}
+
+// Compile parses a selector into a reusable Query.
+func Compile(selector string) (*Query, error) {
+	p := &queryParser{src: selector}
+	sels, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Query{src: selector, selectors: sels}, nil
+}
+
+// MustCompile is like Compile but panics if the selector cannot be parsed.
+func MustCompile(selector string) *Query {
+	q, err := Compile(selector)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// Select compiles selector and returns all matches in document order.
+func Select(doc Document, selector string) ([]Match, error) {
+	q, err := Compile(selector)
+	if err != nil {
+		return nil, err
+	}
+	return q.Select(doc), nil
+}
+
+// String returns the source selector.
+func (q *Query) String() string { return q.src }
+
+// Select returns all matches in document order: each node is followed by
+// its matching children and then its matching markDefs.
+func (q *Query) Select(doc Document) []Match {
+	var out []Match
+	for i := range doc {
+		n := &doc[i]
+		if q.matches(queryItem{kind: kindNode, node: n}) {
+			out = append(out, Match{Path: nodePath(i), Node: n})
+		}
+		for j := range n.Children {
+			if q.matches(queryItem{kind: kindChild, node: n, span: &n.Children[j]}) {
+				out = append(out, Match{Path: childPath(i, j), Node: n, Span: &n.Children[j]})
+			}
+		}
+		for j := range n.MarkDefs {
+			if q.matches(queryItem{kind: kindMarkDef, node: n, markDef: &n.MarkDefs[j]}) {
+				out = append(out, Match{Path: markDefPath(i, j), Node: n, MarkDef: &n.MarkDefs[j]})
+			}
+		}
+	}
+	return out
+}
+
+// First returns the first match, if any.
+func (q *Query) First(doc Document) (Match, bool) {
+	m := q.Select(doc)
+	if len(m) == 0 {
+		return Match{}, false
+	}
+	return m[0], true
+}
+
+// Type returns the _type of the matched item.
+func (m Match) Type() string {
+	return m.item().typ()
+}
+
+// Value looks up a field on the matched item. Known fields (_type, _key,
+// style, listItem, level, text, marks) are read from the struct; anything
+// else is resolved as a dotted path into Raw, e.g. "asset._ref".
+// For blocks, "text" is the concatenated span text.
+func (m Match) Value(field string) (any, bool) {
+	return m.item().value(field)
+}
+
+// Project returns the requested fields of the matched item keyed by the
+// field path as given. Fields that are not present are omitted.
+func (m Match) Project(fields ...string) map[string]any {
+	out := make(map[string]any, len(fields))
+	it := m.item()
+	for _, f := range fields {
+		if v, ok := it.value(f); ok {
+			out[f] = v
+		}
+	}
+	return out
+}
+
+func (m Match) item() queryItem {
+	switch {
+	case m.Span != nil:
+		return queryItem{kind: kindChild, node: m.Node, span: m.Span}
+	case m.MarkDef != nil:
+		return queryItem{kind: kindMarkDef, node: m.Node, markDef: m.MarkDef}
+	default:
+		return queryItem{kind: kindNode, node: m.Node}
+	}
+}
+
+//
+// Matching
+//
+
+type itemKind int
+
+const (
+	kindAny itemKind = iota
+	kindNode
+	kindChild
+	kindMarkDef
+)
+
+type queryItem struct {
+	kind    itemKind
+	node    *Node
+	span    *Span
+	markDef *MarkDef
+}
+
+func (it queryItem) parent() (queryItem, bool) {
+	if it.kind == kindNode {
+		return queryItem{}, false
+	}
+	return queryItem{kind: kindNode, node: it.node}, true
+}
+
+func (it queryItem) typ() string {
+	switch it.kind {
+	case kindChild:
+		return it.span.Type
+	case kindMarkDef:
+		return it.markDef.Type
+	default:
+		return it.node.Type
+	}
+}
+
+func (it queryItem) value(field string) (any, bool) {
+	switch it.kind {
+	case kindChild:
+		s := it.span
+		switch field {
+		case "_type":
+			return s.Type, true
+		case "text":
+			if s.Text == nil {
+				break
+			}
+			return *s.Text, true
+		case "marks":
+			if s.Marks == nil {
+				break
+			}
+			return s.Marks, true
+		}
+		return lookupRaw(s.Raw, field)
+	case kindMarkDef:
+		md := it.markDef
+		switch field {
+		case "_type":
+			return md.Type, true
+		case "_key":
+			if md.Key == "" {
+				break
+			}
+			return md.Key, true
+		}
+		return lookupRaw(md.Raw, field)
+	default:
+		n := it.node
+		switch field {
+		case "_type":
+			return n.Type, true
+		case "_key":
+			if n.Key == "" {
+				break
+			}
+			return n.Key, true
+		case "style":
+			if n.Style == nil {
+				break
+			}
+			return *n.Style, true
+		case "listItem":
+			if n.ListItem == nil {
+				break
+			}
+			return *n.ListItem, true
+		case "level":
+			if n.Level == nil {
+				break
+			}
+			return *n.Level, true
+		case "text":
+			if n.Children == nil {
+				break
+			}
+			return n.GetText(), true
+		}
+		return lookupRaw(n.Raw, field)
+	}
+}
+
+// lookupRaw resolves a dotted path such as "asset._ref" or "items.0.name".
+func lookupRaw(raw map[string]any, path string) (any, bool) {
+	var cur any = raw
+	for _, seg := range strings.Split(path, ".") {
+		switch x := cur.(type) {
+		case map[string]any:
+			v, ok := x[seg]
+			if !ok {
+				return nil, false
+			}
+			cur = v
+		case []any:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(x) {
+				return nil, false
+			}
+			cur = x[i]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func (q *Query) matches(it queryItem) bool {
+	for _, sel := range q.selectors {
+		if matchSelector(sel, len(sel)-1, it) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSelector(sel []compound, i int, it queryItem) bool {
+	if !sel[i].matches(it) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	// Both combinators reduce to "parent matches" in a two-level tree.
+	p, ok := it.parent()
+	if !ok {
+		return false
+	}
+	return matchSelector(sel, i-1, p)
+}
+
+type compound struct {
+	typ   string // "" or "*" matches any type
+	kind  itemKind
+	attrs []attrFilter
+}
+
+func (c compound) matches(it queryItem) bool {
+	if c.kind != kindAny && c.kind != it.kind {
+		return false
+	}
+	if c.typ != "" && c.typ != "*" && c.typ != it.typ() {
+		return false
+	}
+	for _, a := range c.attrs {
+		if !a.matches(it) {
+			return false
+		}
+	}
+	return true
+}
+
+type attrFilter struct {
+	name  string
+	op    string // "" for existence
+	value string
+}
+
+func (a attrFilter) matches(it queryItem) bool {
+	v, ok := it.value(a.name)
+	if a.op == "!=" {
+		return !ok || !valueEquals(v, a.value)
+	}
+	if !ok || v == nil {
+		return false
+	}
+	switch a.op {
+	case "":
+		return true
+	case "=":
+		return valueEquals(v, a.value)
+	case "~=":
+		return valueContains(v, a.value)
+	}
+	s, ok := scalarString(v)
+	if !ok {
+		return false
+	}
+	switch a.op {
+	case "^=":
+		return strings.HasPrefix(s, a.value)
+	case "$=":
+		return strings.HasSuffix(s, a.value)
+	case "*=":
+		return strings.Contains(s, a.value)
+	}
+	return false
+}
+
+func valueEquals(v any, want string) bool {
+	s, ok := scalarString(v)
+	return ok && s == want
+}
+
+func valueContains(v any, want string) bool {
+	switch x := v.(type) {
+	case []string:
+		for _, s := range x {
+			if s == want {
+				return true
+			}
+		}
+	case []any:
+		for _, e := range x {
+			if valueEquals(e, want) {
+				return true
+			}
+		}
+	case string:
+		for _, f := range strings.Fields(x) {
+			if f == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func scalarString(v any) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case json.Number:
+		return x.String(), true
+	case bool:
+		return strconv.FormatBool(x), true
+	case int:
+		return strconv.Itoa(x), true
+	case int64:
+		return strconv.FormatInt(x, 10), true
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), true
+	case nil:
+		return "null", true
+	}
+	return "", false
+}
+
+//
+// Parsing
+//
+
+type queryParser struct {
+	src string
+	pos int
+}
+
+func (p *queryParser) errorf(format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	return wrap("query", "", fmt.Errorf("%w: %s at offset %d in %q", ErrInvalidQuery, msg, p.pos, p.src))
+}
+
+func (p *queryParser) parse() ([][]compound, error) {
+	var sels [][]compound
+	for {
+		sel, err := p.parseSelector()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return sels, nil
+		}
+		if p.src[p.pos] != ',' {
+			return nil, p.errorf("unexpected %q", p.src[p.pos])
+		}
+		p.pos++
+	}
+}
+
+func (p *queryParser) parseSelector() ([]compound, error) {
+	var sel []compound
+	for {
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == '>' {
+			if len(sel) == 0 {
+				return nil, p.errorf("combinator without left-hand selector")
+			}
+			p.pos++
+			p.skipSpace()
+		}
+		c, err := p.parseCompound()
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, c)
+
+		p.skipSpace()
+		if p.pos >= len(p.src) || p.src[p.pos] == ',' {
+			return sel, nil
+		}
+	}
+}
+
+func (p *queryParser) parseCompound() (compound, error) {
+	var c compound
+	start := p.pos
+	if p.pos < len(p.src) && p.src[p.pos] == '*' {
+		c.typ = "*"
+		p.pos++
+	} else {
+		c.typ = p.ident()
+	}
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '[':
+			a, err := p.parseAttr()
+			if err != nil {
+				return compound{}, err
+			}
+			c.attrs = append(c.attrs, a)
+		case ':':
+			p.pos++
+			name := p.ident()
+			switch name {
+			case "node":
+				c.kind = kindNode
+			case "child":
+				c.kind = kindChild
+			case "markDef":
+				c.kind = kindMarkDef
+			default:
+				return compound{}, p.errorf("unknown pseudo-class %q", name)
+			}
+		default:
+			if p.pos == start {
+				return compound{}, p.errorf("unexpected %q", p.src[p.pos])
+			}
+			return c, nil
+		}
+	}
+	if p.pos == start {
+		return compound{}, p.errorf("expected selector")
+	}
+	return c, nil
+}
+
+func (p *queryParser) parseAttr() (attrFilter, error) {
+	p.pos++ // '['
+	p.skipSpace()
+	var a attrFilter
+	a.name = p.attrName()
+	if a.name == "" {
+		return a, p.errorf("expected attribute name")
+	}
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return a, p.errorf("unterminated attribute filter")
+	}
+	if p.src[p.pos] == ']' {
+		p.pos++
+		return a, nil
+	}
+	for _, op := range []string{"!=", "~=", "^=", "$=", "*=", "="} {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			a.op = op
+			p.pos += len(op)
+			break
+		}
+	}
+	if a.op == "" {
+		return a, p.errorf("expected operator")
+	}
+	p.skipSpace()
+	v, err := p.attrValue()
+	if err != nil {
+		return a, err
+	}
+	a.value = v
+	p.skipSpace()
+	if p.pos >= len(p.src) || p.src[p.pos] != ']' {
+		return a, p.errorf("expected ']'")
+	}
+	p.pos++
+	return a, nil
+}
+
+func (p *queryParser) attrValue() (string, error) {
+	if p.pos < len(p.src) && (p.src[p.pos] == '"' || p.src[p.pos] == '\'') {
+		quote := p.src[p.pos]
+		var buf strings.Builder
+		for i := p.pos + 1; i < len(p.src); i++ {
+			switch p.src[i] {
+			case '\\':
+				if i+1 < len(p.src) {
+					i++
+					buf.WriteByte(p.src[i])
+				}
+			case quote:
+				p.pos = i + 1
+				return buf.String(), nil
+			default:
+				buf.WriteByte(p.src[i])
+			}
+		}
+		return "", p.errorf("unterminated string")
+	}
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] != ']' && p.src[p.pos] != ' ' {
+		p.pos++
+	}
+	return p.src[start:p.pos], nil
+}
+
+func (p *queryParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.src) && isIdentByte(p.src[p.pos]) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *queryParser) attrName() string {
+	start := p.pos
+	for p.pos < len(p.src) && (isIdentByte(p.src[p.pos]) || p.src[p.pos] == '.') {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *queryParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+func isIdentByte(b byte) bool {
+	return b == '_' || b == '-' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b >= 0x80
+}
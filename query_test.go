@@ -0,0 +1,103 @@
+package portabletext
+
+import (
+	"errors"
+	"testing"
+)
+
+const queryTestDoc = `[
+	{"_type":"block","_key":"b1","style":"h2","children":[
+		{"_type":"span","text":"Bold","marks":["strong"]},
+		{"_type":"span","text":" plain","marks":[]}
+	],"markDefs":[]},
+	{"_type":"block","_key":"b2","style":"normal","children":[
+		{"_type":"span","text":"Link","marks":["l1","strong"]}
+	],"markDefs":[{"_type":"link","_key":"l1","href":"https://example.com/a"}]},
+	{"_type":"image","_key":"img","asset":{"_ref":"image-abc-10x10-png","_type":"reference"},"alt":"Logo"},
+	{"_type":"image","_key":"img2","alt":null}
+]`
+
+func TestSelect(t *testing.T) {
+	doc, err := DecodeString(queryTestDoc)
+	if err != nil {
+		t.Fatalf("DecodeString() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		selector  string
+		wantPaths []Path
+	}{
+		{"type", "image", []Path{"[2]", "[3]"}},
+		{"child combinator", "block[style=h2] > span[marks~=strong]", []Path{"[0].children[0]"}},
+		{"descendant combinator", "block span[marks~=strong]", []Path{"[0].children[0]", "[1].children[0]"}},
+		{"raw dotted path", "image[asset._ref^=image-]", []Path{"[2]"}},
+		{"existence skips null", "image[alt]", []Path{"[2]"}},
+		{"not equal", "block[style!=h2]", []Path{"[1]"}},
+		{"markDef pseudo", "link:markDef[href*=example.com]", []Path{"[1].markDefs[0]"}},
+		{"quoted value", `span[text=" plain"]`, []Path{"[0].children[1]"}},
+		{"selector list", "image[_key=img2], block[_key=b1]", []Path{"[0]", "[3]"}},
+		{"universal node", "*:node[_key$=2]", []Path{"[1]", "[3]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := Select(doc, tt.selector)
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			if len(matches) != len(tt.wantPaths) {
+				t.Fatalf("Select() got %d matches, want %d: %v", len(matches), len(tt.wantPaths), matches)
+			}
+			for i, m := range matches {
+				if m.Path != tt.wantPaths[i] {
+					t.Errorf("match[%d].Path = %s, want %s", i, m.Path, tt.wantPaths[i])
+				}
+			}
+		})
+	}
+}
+
+func TestQueryReuseAndProject(t *testing.T) {
+	q := MustCompile("image")
+	doc, err := DecodeString(queryTestDoc)
+	if err != nil {
+		t.Fatalf("DecodeString() error = %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		m, ok := q.First(doc)
+		if !ok {
+			t.Fatal("First() found no match")
+		}
+		p := m.Project("_key", "asset._ref", "missing")
+		if p["_key"] != "img" || p["asset._ref"] != "image-abc-10x10-png" {
+			t.Errorf("Project() = %v", p)
+		}
+		if _, ok := p["missing"]; ok {
+			t.Error("Project() should omit missing fields")
+		}
+	}
+}
+
+func TestQueryMatchMutation(t *testing.T) {
+	doc := Document{*NewBlock("h1").AddSpan("Title")}
+	for _, m := range MustCompile("span").Select(doc) {
+		text := "Changed"
+		m.Span.Text = &text
+	}
+	if doc[0].GetText() != "Changed" {
+		t.Errorf("GetText() = %s, want Changed", doc[0].GetText())
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, sel := range []string{"", "block[", "block[style", "block[style?x]", "> span", "block:bogus", `span[text="x]`} {
+		t.Run(sel, func(t *testing.T) {
+			_, err := Compile(sel)
+			if !errors.Is(err, ErrInvalidQuery) {
+				t.Errorf("Compile(%q) error = %v, want ErrInvalidQuery", sel, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package portabletext
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Reference is a Sanity reference object ({"_type":"reference","_ref":...})
+// found somewhere in a document's Raw fields.
+type Reference struct {
+	Path      Path   // Location of the reference object, e.g. "[1].asset"
+	Ref       string // Referenced document or asset ID
+	Type      string // _type of the reference object, usually "reference"
+	Weak      bool   // _weak flag; weak references do not block deletion
+	OwnerType string // _type of the node, span or markDef holding it
+}
+
+// References returns every reference in doc, searching Node.Raw, Span.Raw
+// and MarkDef.Raw recursively, in document order. Any object with a string
+// _ref counts, so asset and custom reference types are included.
+func References(doc Document) []Reference {
+	var out []Reference
+	walkReferences(doc, func(r Reference, _ map[string]any) {
+		out = append(out, r)
+	})
+	return out
+}
+
+// ReplaceReferences returns a copy of doc in which every _ref found in
+// mapping is replaced by its new value, e.g. for ID migrations.
+func ReplaceReferences(doc Document, mapping map[string]string) Document {
+	out := make(Document, len(doc))
+	for i := range doc {
+		out[i] = *doc[i].Clone()
+	}
+	walkReferences(out, func(r Reference, obj map[string]any) {
+		if to, ok := mapping[r.Ref]; ok {
+			obj["_ref"] = to
+		}
+	})
+	return out
+}
+
+// walkReferences calls fn with each reference and the map holding it.
+func walkReferences(doc Document, fn func(Reference, map[string]any)) {
+	for i := range doc {
+		n := &doc[i]
+		walkRawReferences(n.Raw, nodePath(i), n.Type, fn)
+		for j := range n.Children {
+			walkRawReferences(n.Children[j].Raw, childPath(i, j), n.Children[j].Type, fn)
+		}
+		for j := range n.MarkDefs {
+			walkRawReferences(n.MarkDefs[j].Raw, markDefPath(i, j), n.MarkDefs[j].Type, fn)
+		}
+	}
+}
+
+func walkRawReferences(raw map[string]any, path Path, owner string, fn func(Reference, map[string]any)) {
+	for _, k := range sortedKeys(raw) {
+		walkValueReferences(raw[k], Path(fmt.Sprintf("%s.%s", path, k)), owner, fn)
+	}
+}
+
+func walkValueReferences(v any, path Path, owner string, fn func(Reference, map[string]any)) {
+	switch x := v.(type) {
+	case map[string]any:
+		if ref, ok := x["_ref"].(string); ok {
+			r := Reference{Path: path, Ref: ref, OwnerType: owner}
+			r.Type, _ = x["_type"].(string)
+			r.Weak, _ = x["_weak"].(bool)
+			fn(r, x)
+		}
+		for _, k := range sortedKeys(x) {
+			walkValueReferences(x[k], Path(fmt.Sprintf("%s.%s", path, k)), owner, fn)
+		}
+	case []any:
+		for i, e := range x {
+			walkValueReferences(e, Path(fmt.Sprintf("%s[%d]", path, i)), owner, fn)
+		}
+	}
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// RefGraph is a dependency graph between documents of a corpus, built from
+// the references in their Portable Text. It answers which documents break
+// when a document or asset is deleted. A RefGraph is not safe for
+// concurrent modification.
+type RefGraph struct {
+	deps       map[string]map[string]bool // id -> ref -> strong
+	dependents map[string]map[string]bool // ref -> id -> strong
+}
+
+// NewRefGraph returns an empty graph.
+func NewRefGraph() *RefGraph {
+	return &RefGraph{
+		deps:       map[string]map[string]bool{},
+		dependents: map[string]map[string]bool{},
+	}
+}
+
+// Add records the references in doc as dependencies of the document id.
+// Adding the same id again replaces its previous dependencies.
+func (g *RefGraph) Add(id string, doc Document) {
+	g.Remove(id)
+	deps := map[string]bool{}
+	for _, r := range References(doc) {
+		deps[r.Ref] = deps[r.Ref] || !r.Weak
+	}
+	g.deps[id] = deps
+	for ref, strong := range deps {
+		if g.dependents[ref] == nil {
+			g.dependents[ref] = map[string]bool{}
+		}
+		g.dependents[ref][id] = strong
+	}
+}
+
+// Remove forgets the dependencies of the document id.
+func (g *RefGraph) Remove(id string) {
+	for ref := range g.deps[id] {
+		delete(g.dependents[ref], id)
+		if len(g.dependents[ref]) == 0 {
+			delete(g.dependents, ref)
+		}
+	}
+	delete(g.deps, id)
+}
+
+// Dependencies returns the IDs id references, sorted.
+func (g *RefGraph) Dependencies(id string) []string {
+	return sortedSet(g.deps[id])
+}
+
+// Dependents returns the IDs of documents that reference ref directly,
+// including weak references, sorted.
+func (g *RefGraph) Dependents(ref string) []string {
+	return sortedSet(g.dependents[ref])
+}
+
+// Impacted returns the documents that would be left with a broken strong
+// reference if ref were deleted: those referencing it directly and not
+// weakly, sorted.
+func (g *RefGraph) Impacted(ref string) []string {
+	broken := map[string]bool{}
+	for id, strong := range g.dependents[ref] {
+		if strong && id != ref {
+			broken[id] = true
+		}
+	}
+	return sortedSet(broken)
+}
+
+// IndirectlyImpacted returns the documents that depend on ref only through
+// chains of strong references, such as a post embedding a block that uses
+// a deleted asset, sorted. Their own references stay valid, but content
+// they embed breaks. Documents returned by Impacted are not included.
+func (g *RefGraph) IndirectlyImpacted(ref string) []string {
+	direct := g.Impacted(ref)
+	seen := map[string]bool{ref: true}
+	for _, id := range direct {
+		seen[id] = true
+	}
+	indirect := map[string]bool{}
+	queue := direct
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for id, strong := range g.dependents[cur] {
+			if strong && !seen[id] {
+				seen[id] = true
+				indirect[id] = true
+				queue = append(queue, id)
+			}
+		}
+	}
+	return sortedSet(indirect)
+}
+
+func sortedSet(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
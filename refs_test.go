@@ -0,0 +1,101 @@
+package portabletext
+
+import (
+	"strings"
+	"testing"
+)
+
+const refsTestDoc = `[
+	{"_type":"image","_key":"hero","asset":{"_ref":"image-abc123","_type":"reference"}},
+	{"_type":"block","children":[
+		{"_type":"span","text":"See "},
+		{"_type":"productCard","product":{"_ref":"product-1","_type":"reference","_weak":true}}
+	],"markDefs":[
+		{"_type":"internalLink","_key":"l1","reference":{"_ref":"post-2","_type":"reference"}}
+	]},
+	{"_type":"gallery","images":[{"asset":{"_ref":"image-a"}},{"asset":{"_ref":"image-b"}}]}
+]`
+
+func TestReferences(t *testing.T) {
+	doc, err := DecodeString(refsTestDoc)
+	if err != nil {
+		t.Fatalf("DecodeString() error = %v", err)
+	}
+
+	var got []string
+	for _, r := range References(doc) {
+		got = append(got, string(r.Path)+"="+r.Ref+"/"+r.OwnerType)
+	}
+	want := []string{
+		"[0].asset=image-abc123/image",
+		"[1].children[1].product=product-1/productCard",
+		"[1].markDefs[0].reference=post-2/internalLink",
+		"[2].images[0].asset=image-a/gallery",
+		"[2].images[1].asset=image-b/gallery",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("References() = %v\nwant %v", got, want)
+	}
+
+	refs := References(doc)
+	if !refs[1].Weak || refs[0].Weak || refs[0].Type != "reference" {
+		t.Errorf("weak/type flags wrong: %+v %+v", refs[0], refs[1])
+	}
+}
+
+func TestReplaceReferences(t *testing.T) {
+	doc, err := DecodeString(refsTestDoc)
+	if err != nil {
+		t.Fatalf("DecodeString() error = %v", err)
+	}
+
+	out := ReplaceReferences(doc, map[string]string{"image-a": "image-z", "post-2": "post-20"})
+
+	refs := References(out)
+	if refs[2].Ref != "post-20" || refs[3].Ref != "image-z" || refs[4].Ref != "image-b" {
+		t.Errorf("ReplaceReferences() refs = %+v", refs)
+	}
+	if References(doc)[3].Ref != "image-a" {
+		t.Error("ReplaceReferences() mutated the input document")
+	}
+}
+
+func TestRefGraph(t *testing.T) {
+	mustDecode := func(s string) Document {
+		doc, err := DecodeString(s)
+		if err != nil {
+			t.Fatalf("DecodeString() error = %v", err)
+		}
+		return doc
+	}
+
+	g := NewRefGraph()
+	g.Add("post-1", mustDecode(`[{"_type":"image","asset":{"_ref":"image-x"}}]`))
+	g.Add("post-2", mustDecode(`[{"_type":"embed","post":{"_ref":"post-1"}},{"_type":"image","asset":{"_ref":"image-y"}}]`))
+	g.Add("post-3", mustDecode(`[{"_type":"related","post":{"_ref":"post-1","_weak":true}}]`))
+	g.Add("post-4", mustDecode(`[{"_type":"embed","post":{"_ref":"post-2"}}]`))
+
+	if got := strings.Join(g.Dependents("post-1"), ","); got != "post-2,post-3" {
+		t.Errorf("Dependents(post-1) = %s", got)
+	}
+	if got := strings.Join(g.Dependencies("post-2"), ","); got != "image-y,post-1" {
+		t.Errorf("Dependencies(post-2) = %s", got)
+	}
+	if got := strings.Join(g.Impacted("image-x"), ","); got != "post-1" {
+		t.Errorf("Impacted(image-x) = %s", got)
+	}
+	if got := strings.Join(g.IndirectlyImpacted("image-x"), ","); got != "post-2,post-4" {
+		t.Errorf("IndirectlyImpacted(image-x) = %s", got)
+	}
+	if got := strings.Join(g.Impacted("post-1"), ","); got != "post-2" {
+		t.Errorf("Impacted(post-1) = %s", got)
+	}
+
+	g.Add("post-2", mustDecode(`[]`))
+	if got := strings.Join(g.Impacted("image-x"), ","); got != "post-1" {
+		t.Errorf("Impacted(image-x) after update = %s", got)
+	}
+	if got := g.IndirectlyImpacted("image-x"); len(got) != 0 {
+		t.Errorf("IndirectlyImpacted(image-x) after update = %v", got)
+	}
+}
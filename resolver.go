@@ -0,0 +1,249 @@
+package portabletext
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrNotFound is returned by resolvers when a referenced ID does not exist.
+var ErrNotFound = errors.New("reference not found")
+
+// Resolver looks up referenced documents or assets by ID. Implementations
+// return ErrNotFound (possibly wrapped) for unknown IDs. Returned maps are
+// shared and must not be modified by callers.
+type Resolver interface {
+	Resolve(ctx context.Context, ref string) (map[string]any, error)
+}
+
+// BatchResolver is a Resolver that can fetch many IDs in one round trip.
+// IDs that do not exist are omitted from the result rather than reported
+// as errors.
+type BatchResolver interface {
+	Resolver
+	ResolveMany(ctx context.Context, refs []string) (map[string]map[string]any, error)
+}
+
+// MapResolver is an in-memory Resolver keyed by document _id, for tests and
+// offline builds.
+type MapResolver map[string]map[string]any
+
+// Resolve implements Resolver.
+func (m MapResolver) Resolve(ctx context.Context, ref string) (map[string]any, error) {
+	if v, ok := m[ref]; ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrNotFound, ref)
+}
+
+// ResolveMany implements BatchResolver.
+func (m MapResolver) ResolveMany(ctx context.Context, refs []string) (map[string]map[string]any, error) {
+	out := make(map[string]map[string]any, len(refs))
+	for _, ref := range refs {
+		if v, ok := m[ref]; ok {
+			out[ref] = v
+		}
+	}
+	return out, nil
+}
+
+// LoadNDJSONResolver reads newline-delimited JSON documents, such as a
+// Sanity dataset export, into a MapResolver keyed by _id. Lines without an
+// _id are skipped. Numbers are kept as json.Number, as in Decode.
+func LoadNDJSONResolver(r io.Reader) (MapResolver, error) {
+	m := MapResolver{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		b := bytes.TrimSpace(sc.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		obj, err := decodeObjectUseNumber(b)
+		if err != nil {
+			return nil, wrap("resolve", fmt.Sprintf("line %d", line), err)
+		}
+		if id, ok := obj["_id"].(string); ok && id != "" {
+			m[id] = obj
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, wrap("resolve", "", err)
+	}
+	return m, nil
+}
+
+// MemoResolver caches the results of another Resolver, including
+// not-found results. Other errors are not cached, so transient failures
+// are retried. It is safe for concurrent use.
+type MemoResolver struct {
+	next Resolver
+
+	mu      sync.Mutex
+	found   map[string]map[string]any
+	missing map[string]bool
+}
+
+// Memoize wraps r with a cache. If r is a BatchResolver, ResolveMany only
+// forwards IDs that are not cached yet.
+func Memoize(r Resolver) *MemoResolver {
+	return &MemoResolver{next: r, found: map[string]map[string]any{}, missing: map[string]bool{}}
+}
+
+// Resolve implements Resolver.
+func (m *MemoResolver) Resolve(ctx context.Context, ref string) (map[string]any, error) {
+	m.mu.Lock()
+	v, ok := m.found[ref]
+	miss := m.missing[ref]
+	m.mu.Unlock()
+	if ok {
+		return v, nil
+	}
+	if miss {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, ref)
+	}
+
+	v, err := m.next.Resolve(ctx, ref)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case err == nil:
+		m.found[ref] = v
+	case errors.Is(err, ErrNotFound):
+		m.missing[ref] = true
+	}
+	return v, err
+}
+
+// ResolveMany implements BatchResolver.
+func (m *MemoResolver) ResolveMany(ctx context.Context, refs []string) (map[string]map[string]any, error) {
+	out := make(map[string]map[string]any, len(refs))
+	var todo []string
+	m.mu.Lock()
+	for _, ref := range refs {
+		if v, ok := m.found[ref]; ok {
+			out[ref] = v
+		} else if !m.missing[ref] {
+			todo = append(todo, ref)
+		}
+	}
+	m.mu.Unlock()
+	if len(todo) == 0 {
+		return out, nil
+	}
+
+	fetched, err := resolveAll(ctx, m.next, todo)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, ref := range todo {
+		if v, ok := fetched[ref]; ok {
+			m.found[ref] = v
+			out[ref] = v
+		} else {
+			m.missing[ref] = true
+		}
+	}
+	return out, nil
+}
+
+// resolveAll fetches refs in one batch if r supports it, otherwise one by
+// one. Missing IDs are omitted from the result.
+func resolveAll(ctx context.Context, r Resolver, refs []string) (map[string]map[string]any, error) {
+	if br, ok := r.(BatchResolver); ok {
+		return br.ResolveMany(ctx, refs)
+	}
+	out := make(map[string]map[string]any, len(refs))
+	for _, ref := range refs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		v, err := r.Resolve(ctx, ref)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out[ref] = v
+	}
+	return out, nil
+}
+
+// DereferenceOptions controls Dereference.
+type DereferenceOptions struct {
+	// IgnoreMissing leaves unresolvable strong references in place instead
+	// of failing. Missing weak references are always left in place.
+	IgnoreMissing bool
+
+	// KeepRef keeps the original _ref (and _weak) fields next to the
+	// resolved fields, so the result can still be traced to its source.
+	KeepRef bool
+}
+
+// Dereference returns a copy of doc in which every reference object, in
+// Node.Raw, Span.Raw or MarkDef.Raw, is replaced by a copy of the document
+// it points to, like GROQ's -> operator. All IDs are resolved in a single
+// batch. A missing strong reference fails with an *Error at its path
+// wrapping ErrNotFound, unless opts.IgnoreMissing is set.
+func Dereference(ctx context.Context, doc Document, r Resolver, opts DereferenceOptions) (Document, error) {
+	out := make(Document, len(doc))
+	for i := range doc {
+		out[i] = *doc[i].Clone()
+	}
+
+	refs := References(out)
+	if len(refs) == 0 {
+		return out, nil
+	}
+	ids := make([]string, 0, len(refs))
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		if !seen[ref.Ref] {
+			seen[ref.Ref] = true
+			ids = append(ids, ref.Ref)
+		}
+	}
+
+	resolved, err := resolveAll(ctx, r, ids)
+	if err != nil {
+		return nil, wrap("dereference", "", err)
+	}
+
+	// Collect first and replace afterwards, so references inside resolved
+	// documents are left as they are rather than walked into.
+	type site struct {
+		ref Reference
+		obj map[string]any
+	}
+	var sites []site
+	walkReferences(out, func(ref Reference, obj map[string]any) {
+		sites = append(sites, site{ref, obj})
+	})
+	for _, s := range sites {
+		target, ok := resolved[s.ref.Ref]
+		if !ok {
+			if !s.ref.Weak && !opts.IgnoreMissing {
+				return nil, wrap("dereference", s.ref.Path.String(), fmt.Errorf("%w: %q", ErrNotFound, s.ref.Ref))
+			}
+			continue
+		}
+		for k := range s.obj {
+			if !opts.KeepRef || (k != "_ref" && k != "_weak") {
+				delete(s.obj, k)
+			}
+		}
+		for k, v := range target {
+			s.obj[k] = deepCopyAny(v)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,140 @@
+package portabletext
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type countingResolver struct {
+	MapResolver
+	calls int
+}
+
+func (c *countingResolver) Resolve(ctx context.Context, ref string) (map[string]any, error) {
+	c.calls++
+	return c.MapResolver.Resolve(ctx, ref)
+}
+
+func TestLoadNDJSONResolver(t *testing.T) {
+	in := `{"_id":"author-1","_type":"author","name":"Ada"}
+
+{"_type":"noid"}
+{"_id":"image-abc-10x10-png","_type":"sanity.imageAsset","url":"https://cdn.example/abc.png"}
+`
+	r, err := LoadNDJSONResolver(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("LoadNDJSONResolver() error = %v", err)
+	}
+	if len(r) != 2 {
+		t.Errorf("LoadNDJSONResolver() loaded %d docs, want 2", len(r))
+	}
+	got, err := r.Resolve(context.Background(), "author-1")
+	if err != nil || got["name"] != "Ada" {
+		t.Errorf("Resolve(author-1) = %v, %v", got, err)
+	}
+	if _, err := r.Resolve(context.Background(), "nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Resolve(nope) error = %v, want ErrNotFound", err)
+	}
+
+	_, err = LoadNDJSONResolver(strings.NewReader("{\"_id\":\"a\"}\n{broken\n"))
+	var pe *Error
+	if !errors.As(err, &pe) || pe.Path != "line 2" {
+		t.Errorf("LoadNDJSONResolver(broken) error = %v, want *Error at line 2", err)
+	}
+}
+
+func TestMemoize(t *testing.T) {
+	inner := &countingResolver{MapResolver: MapResolver{"a": {"_id": "a"}}}
+	m := Memoize(inner)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := m.Resolve(ctx, "a"); err != nil {
+			t.Fatalf("Resolve(a) error = %v", err)
+		}
+		if _, err := m.Resolve(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Resolve(missing) error = %v, want ErrNotFound", err)
+		}
+	}
+	if inner.calls != 2 {
+		t.Errorf("inner resolver called %d times, want 2", inner.calls)
+	}
+
+	got, err := m.ResolveMany(ctx, []string{"a", "missing"})
+	if err != nil || len(got) != 1 || got["a"] == nil {
+		t.Errorf("ResolveMany() = %v, %v", got, err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("ResolveMany() hit the inner resolver for cached IDs")
+	}
+}
+
+func TestDereference(t *testing.T) {
+	img := NewBlock("normal")
+	img.Type = "image"
+	img.Children = nil
+	img.Raw["asset"] = map[string]any{"_type": "reference", "_ref": "image-1"}
+
+	link := NewBlock("normal").AddSpan("Ada", "m1").
+		AddMarkDef("m1", "internalLink", map[string]any{
+			"reference": map[string]any{"_type": "reference", "_ref": "author-1"},
+			"draft":     map[string]any{"_type": "reference", "_ref": "gone", "_weak": true},
+		})
+	doc := Document{*img, *link}
+
+	r := MapResolver{
+		"image-1":  {"_id": "image-1", "url": "https://cdn.example/1.png"},
+		"author-1": {"_id": "author-1", "name": "Ada", "avatar": map[string]any{"_ref": "image-1"}},
+	}
+	out, err := Dereference(context.Background(), doc, r, DereferenceOptions{})
+	if err != nil {
+		t.Fatalf("Dereference() error = %v", err)
+	}
+
+	asset := out[0].Raw["asset"].(map[string]any)
+	if asset["url"] != "https://cdn.example/1.png" || asset["_ref"] != nil {
+		t.Errorf("asset = %v", asset)
+	}
+	author := out[1].MarkDefs[0].Raw["reference"].(map[string]any)
+	if author["name"] != "Ada" {
+		t.Errorf("reference = %v", author)
+	}
+	if _, ok := author["avatar"].(map[string]any)["url"]; ok {
+		t.Error("Dereference() should not follow references inside resolved documents")
+	}
+	if out[1].MarkDefs[0].Raw["draft"].(map[string]any)["_ref"] != "gone" {
+		t.Error("missing weak reference should be left in place")
+	}
+	if doc[0].Raw["asset"].(map[string]any)["_ref"] != "image-1" {
+		t.Error("Dereference() mutated the input document")
+	}
+
+	author["name"] = "changed"
+	if r["author-1"]["name"] != "Ada" {
+		t.Error("Dereference() shares maps with the resolver")
+	}
+
+	kept, _ := Dereference(context.Background(), doc, r, DereferenceOptions{KeepRef: true})
+	if kept[0].Raw["asset"].(map[string]any)["_ref"] != "image-1" {
+		t.Error("KeepRef should keep _ref")
+	}
+}
+
+func TestDereferenceMissing(t *testing.T) {
+	n := NewBlock("normal")
+	n.Raw["author"] = map[string]any{"_type": "reference", "_ref": "nobody"}
+	doc := Document{*NewBlock("normal"), *n}
+
+	_, err := Dereference(context.Background(), doc, MapResolver{}, DereferenceOptions{})
+	var pe *Error
+	if !errors.As(err, &pe) || pe.Path != "[1].author" || !errors.Is(err, ErrNotFound) {
+		t.Errorf("Dereference() error = %v, want ErrNotFound at [1].author", err)
+	}
+
+	out, err := Dereference(context.Background(), doc, MapResolver{}, DereferenceOptions{IgnoreMissing: true})
+	if err != nil || out[1].Raw["author"].(map[string]any)["_ref"] != "nobody" {
+		t.Errorf("Dereference(IgnoreMissing) = %v, %v", out, err)
+	}
+}
@@ -0,0 +1,209 @@
+package portabletext
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrInvalidSelection is returned for selection JSON that does not have
+// the Portable Text Editor's shape.
+var ErrInvalidSelection = errors.New("invalid selection")
+
+// SelectionPoint is one end of a Selection. In a text block, Offset counts
+// UTF-16 code units into the text of the span with _key SpanKey, as the
+// Portable Text Editor does; with SpanKey "", Offset is relative to the
+// whole block text. A point on a custom node has only a BlockKey.
+//
+// In JSON a point is {"path": [{"_key": b}, "children", {"_key": s}],
+// "offset": n}, with a one-element path when SpanKey is "".
+type SelectionPoint struct {
+	BlockKey string
+	SpanKey  string
+	Offset   int
+}
+
+// Selection is a cursor or range in a document. Anchor is where the
+// selection started and Focus where it ends, so Focus comes before Anchor
+// in a backward selection; Backward records which, as in the editor.
+type Selection struct {
+	Anchor   SelectionPoint `json:"anchor"`
+	Focus    SelectionPoint `json:"focus"`
+	Backward bool           `json:"backward,omitempty"`
+}
+
+// Caret returns a collapsed selection at p.
+func Caret(p SelectionPoint) Selection { return Selection{Anchor: p, Focus: p} }
+
+// IsCollapsed reports whether the anchor and focus are the same point as
+// written. Points written differently can still be at the same place,
+// such as the end of one span and the start of the next.
+func (s Selection) IsCollapsed() bool { return s.Anchor == s.Focus }
+
+// PointAt returns the point at a block-relative offset, counted in unit
+// u, in the node with _key blockKey.
+func PointAt(doc Document, blockKey string, offset int, u OffsetUnit) (SelectionPoint, error) {
+	i, err := findKey(doc, blockKey)
+	if err != nil {
+		return SelectionPoint{}, err
+	}
+	if !doc[i].IsBlock() {
+		return SelectionPoint{BlockKey: blockKey}, nil
+	}
+	r, err := NewTextIndex(&doc[i]).toRune(offset, u)
+	if err != nil {
+		return SelectionPoint{}, wrap("edit", nodePath(i).String(), err)
+	}
+	return pointFor(doc, textPoint{block: i, off: r}), nil
+}
+
+// BlockOffset returns the offset of p from the start of its block's text,
+// counted in unit u. A point on an inline object is at the object's place
+// in the text.
+func (p SelectionPoint) BlockOffset(doc Document, u OffsetUnit) (int, error) {
+	t, err := resolvePoint(doc, p)
+	if err != nil {
+		return 0, err
+	}
+	if !doc[t.block].IsBlock() {
+		return 0, nil
+	}
+	off, err := NewTextIndex(&doc[t.block]).fromRune(t.off, u)
+	if err != nil {
+		return 0, wrap("edit", nodePath(t.block).String(), err)
+	}
+	return off, nil
+}
+
+type keySegment struct {
+	Key string `json:"_key"`
+}
+
+func (p SelectionPoint) MarshalJSON() ([]byte, error) {
+	path := []any{keySegment{p.BlockKey}}
+	if p.SpanKey != "" {
+		path = append(path, "children", keySegment{p.SpanKey})
+	}
+	return json.Marshal(struct {
+		Path   []any `json:"path"`
+		Offset int   `json:"offset"`
+	}{path, p.Offset})
+}
+
+func (p *SelectionPoint) UnmarshalJSON(b []byte) error {
+	var v struct {
+		Path   []json.RawMessage `json:"path"`
+		Offset int               `json:"offset"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return wrap("select", "", fmt.Errorf("%w: %v", ErrInvalidSelection, err))
+	}
+	key := func(raw json.RawMessage) (string, bool) {
+		var k keySegment
+		return k.Key, json.Unmarshal(raw, &k) == nil && k.Key != ""
+	}
+	var field string
+	switch len(v.Path) {
+	case 1:
+		if bk, ok := key(v.Path[0]); ok {
+			*p = SelectionPoint{BlockKey: bk, Offset: v.Offset}
+			return nil
+		}
+	case 3:
+		bk, ok1 := key(v.Path[0])
+		sk, ok2 := key(v.Path[2])
+		if ok1 && ok2 && json.Unmarshal(v.Path[1], &field) == nil && field == "children" {
+			*p = SelectionPoint{BlockKey: bk, SpanKey: sk, Offset: v.Offset}
+			return nil
+		}
+	}
+	return wrap("select", "", fmt.Errorf("%w: unsupported path %s", ErrInvalidSelection, b))
+}
+
+// textPoint is a resolved selection point: the index of a node and, in a
+// text block, a rune offset into its text. A point on an inline object
+// also records which one, as obj = child index + 1; otherwise obj is 0.
+type textPoint struct {
+	block, off int
+	obj        int
+}
+
+func (p textPoint) before(q textPoint) bool {
+	return p.block < q.block || p.block == q.block && p.off < q.off
+}
+
+// resolvePoint finds the node and rune offset of p in doc. A SpanKey
+// naming an inline object resolves to where the object sits.
+func resolvePoint(doc Document, p SelectionPoint) (textPoint, error) {
+	i, err := findKey(doc, p.BlockKey)
+	if err != nil {
+		return textPoint{}, err
+	}
+	n := &doc[i]
+	if !n.IsBlock() {
+		return textPoint{block: i}, nil
+	}
+	x := NewTextIndex(n)
+	off, path := p.Offset, nodePath(i)
+	if p.SpanKey != "" {
+		j := slices.IndexFunc(n.Children, func(c Span) bool { return c.Raw["_key"] == p.SpanKey })
+		if j < 0 {
+			return textPoint{}, wrap("edit", path.String(), fmt.Errorf("%w: span %q", ErrKeyNotFound, p.SpanKey))
+		}
+		path = childPath(i, j)
+		if n.Children[j].Type != "span" {
+			// The editor selects inline objects by key with offset 0;
+			// the point is where the object sits in the text.
+			return textPoint{block: i, off: x.objectStart(j), obj: j + 1}, nil
+		}
+		if off, err = x.blockOffset(j, off, UnitUTF16); err != nil {
+			return textPoint{}, wrap("edit", path.String(), err)
+		}
+	}
+	r, err := x.toRune(off, UnitUTF16)
+	if err != nil {
+		return textPoint{}, wrap("edit", path.String(), err)
+	}
+	return textPoint{block: i, off: r}, nil
+}
+
+// resolveSelection resolves the anchor and focus of sel.
+func resolveSelection(doc Document, sel Selection) (anchor, focus textPoint, err error) {
+	if anchor, err = resolvePoint(doc, sel.Anchor); err != nil {
+		return
+	}
+	focus, err = resolvePoint(doc, sel.Focus)
+	return
+}
+
+// ordered returns a and b in document order.
+func ordered(a, b textPoint) (start, end textPoint) {
+	if b.before(a) {
+		return b, a
+	}
+	return a, b
+}
+
+// pointFor returns the SelectionPoint for t, naming the span that
+// TextIndex.Locate finds there, or the block alone when that span has no
+// _key.
+func pointFor(doc Document, t textPoint) SelectionPoint {
+	n := &doc[t.block]
+	if !n.IsBlock() {
+		return SelectionPoint{BlockKey: n.Key}
+	}
+	x := NewTextIndex(n)
+	u16, _ := x.fromRune(t.off, UnitUTF16)
+	if pos, err := x.Locate(u16, UnitUTF16); err == nil {
+		if k, ok := n.Children[pos.Child].Raw["_key"].(string); ok && k != "" {
+			return SelectionPoint{BlockKey: n.Key, SpanKey: k, Offset: pos.Offset}
+		}
+	}
+	return SelectionPoint{BlockKey: n.Key, Offset: u16}
+}
+
+// selectionFor returns the Selection from anchor to focus.
+func selectionFor(doc Document, anchor, focus textPoint) Selection {
+	return Selection{Anchor: pointFor(doc, anchor), Focus: pointFor(doc, focus), Backward: focus.before(anchor)}
+}
@@ -0,0 +1,111 @@
+package portabletext
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSelectionJSON(t *testing.T) {
+	in := `{"anchor":{"path":[{"_key":"b1"},"children",{"_key":"s1"}],"offset":3},` +
+		`"focus":{"path":[{"_key":"img"}],"offset":0},"backward":true}`
+	var sel Selection
+	if err := json.Unmarshal([]byte(in), &sel); err != nil {
+		t.Fatal(err)
+	}
+	want := Selection{
+		Anchor:   SelectionPoint{BlockKey: "b1", SpanKey: "s1", Offset: 3},
+		Focus:    SelectionPoint{BlockKey: "img"},
+		Backward: true,
+	}
+	if sel != want {
+		t.Errorf("Unmarshal = %+v, want %+v", sel, want)
+	}
+	out, err := json.Marshal(sel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != in {
+		t.Errorf("Marshal = %s", out)
+	}
+	if sel.IsCollapsed() || !Caret(want.Anchor).IsCollapsed() {
+		t.Errorf("IsCollapsed")
+	}
+
+	for _, bad := range []string{
+		`{"path":[],"offset":0}`,
+		`{"path":[{"_key":"b1"},"markDefs",{"_key":"s1"}],"offset":0}`,
+		`{"path":[0],"offset":0}`,
+		`{"path":"b1"}`,
+	} {
+		var p SelectionPoint
+		if err := json.Unmarshal([]byte(bad), &p); !errors.Is(err, ErrInvalidSelection) {
+			t.Errorf("Unmarshal(%s) error = %v, want ErrInvalidSelection", bad, err)
+		}
+	}
+}
+
+func TestSelectionPoints(t *testing.T) {
+	doc := Document{
+		*withKeys(NewBlock("normal").AddSpan("ab👍").AddSpan("cd", "strong"), "b1", "s1", "s2"),
+		*withKeys(NewBlock("normal").AddSpan("x"), "b2"),
+	}
+	doc[1].Children[0].Raw = map[string]any{} // a span without a key
+
+	tests := []struct {
+		key    string
+		offset int
+		unit   OffsetUnit
+		want   SelectionPoint
+	}{
+		{"b1", 1, UnitRune, SelectionPoint{"b1", "s1", 1}},
+		{"b1", 3, UnitRune, SelectionPoint{"b1", "s2", 0}},
+		{"b1", 4, UnitUTF16, SelectionPoint{"b1", "s2", 0}},
+		{"b1", 3, UnitGrapheme, SelectionPoint{"b1", "s2", 0}},
+		{"b1", 5, UnitRune, SelectionPoint{"b1", "s2", 2}},
+		{"b2", 1, UnitRune, SelectionPoint{"b2", "", 1}},
+	}
+	for _, tt := range tests {
+		p, err := PointAt(doc, tt.key, tt.offset, tt.unit)
+		if err != nil || p != tt.want {
+			t.Errorf("PointAt(%q, %d, %v) = %+v, %v, want %+v", tt.key, tt.offset, tt.unit, p, err, tt.want)
+			continue
+		}
+		if off, err := p.BlockOffset(doc, tt.unit); err != nil || off != tt.offset {
+			t.Errorf("%+v.BlockOffset(%v) = %d, %v, want %d", p, tt.unit, off, err, tt.offset)
+		}
+	}
+
+	// The editor names inline objects in selections; they sit between spans.
+	n := NewBlock("normal").AddSpan("ab")
+	n.Children = append(n.Children, Span{Type: "mention", Raw: map[string]any{"_key": "m1"}})
+	withObject := Document{*withKeys(n.AddSpan("👍cd"), "b3")}
+	for _, p := range []SelectionPoint{{"b3", "m1", 0}, {"b3", "m1", 1}} {
+		if off, err := p.BlockOffset(withObject, UnitUTF16); err != nil || off != 2 {
+			t.Errorf("%+v.BlockOffset = %d, %v, want 2", p, off, err)
+		}
+	}
+	out, sel, err := InsertText(withObject, Caret(SelectionPoint{"b3", "m1", 0}), "!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := spanTexts(&out[0]); !slices.Equal(got, []string{"ab!", "<mention>", "👍cd"}) {
+		t.Errorf("InsertText at an inline object = %q", got)
+	}
+	if off, _ := sel.Focus.BlockOffset(out, UnitRune); off != 3 {
+		t.Errorf("caret after insert = %d, want 3", off)
+	}
+
+	if _, err := PointAt(doc, "b1", 3, UnitUTF16); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("PointAt inside a surrogate pair: error = %v", err)
+	}
+	for _, p := range []SelectionPoint{{"nope", "", 0}, {"b1", "nope", 0}} {
+		if _, err := p.BlockOffset(doc, UnitRune); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("%+v: error = %v, want ErrKeyNotFound", p, err)
+		}
+	}
+	if _, err := (SelectionPoint{"b1", "s1", 3}).BlockOffset(doc, UnitRune); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("offset inside a surrogate pair: error = %v", err)
+	}
+}
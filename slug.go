@@ -0,0 +1,221 @@
+package portabletext
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultAnchorField is the Raw key commonly used to persist heading slugs.
+const DefaultAnchorField = "anchor"
+
+// LatinTransliterations maps common accented and ligature Latin letters to
+// ASCII. Use it (or a copy extended with your own rules) as
+// SlugOptions.Transliterate together with ASCIIOnly for URL-safe slugs.
+var LatinTransliterations = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ę': "e", 'ě': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'ı': "i",
+	'ł': "l", 'ľ': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss", 'ť': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// SlugOptions controls how a Slugger derives slugs.
+type SlugOptions struct {
+	// Separator joins words; defaults to "-".
+	Separator string
+
+	// Transliterate replaces individual (lowercased) runes before slugging,
+	// e.g. LatinTransliterations or {'ü': "ue"} for German conventions.
+	Transliterate map[rune]string
+
+	// ASCIIOnly drops letters and digits outside ASCII that remain after
+	// transliteration. By default Unicode letters are kept as-is.
+	ASCIIOnly bool
+
+	// MaxLength truncates slugs to at most this many runes; 0 means no limit.
+	MaxLength int
+
+	// AnchorField, when set, names a Raw field holding a persisted slug.
+	// An existing string value there is reused instead of deriving one.
+	AnchorField string
+
+	// Persist writes derived slugs into Raw[AnchorField] so they survive
+	// encoding and decoding. Requires AnchorField.
+	Persist bool
+}
+
+// Slugger derives unique anchor slugs for heading nodes. Repeated slugs are
+// de-duplicated with numeric suffixes: intro, intro-1, intro-2, ...
+// A Slugger is not safe for concurrent use.
+type Slugger struct {
+	opts     SlugOptions
+	seen     map[string]int
+	reserved map[string]bool // persisted anchors held for their headings
+}
+
+// NewSlugger returns a Slugger with the given options.
+func NewSlugger(opts SlugOptions) *Slugger {
+	if opts.Separator == "" {
+		opts.Separator = "-"
+	}
+	return &Slugger{opts: opts, seen: map[string]int{}, reserved: map[string]bool{}}
+}
+
+// Slugify returns the default slug for a node's text, without
+// de-duplication: lowercase, with runs of letters and digits joined by "-".
+func Slugify(n *Node) string {
+	return NewSlugger(SlugOptions{}).base(n.GetText())
+}
+
+// Slug returns a slug for n that is unique among all slugs this Slugger has
+// returned. A slug persisted in Raw[AnchorField] is reused unless it was
+// already returned, in which case it gets a numeric suffix like a derived
+// slug. Call ReserveAnchors first so that persisted slugs keep priority
+// over derived slugs of earlier headings.
+func (s *Slugger) Slug(n *Node) string {
+	var slug string
+	if s.opts.AnchorField != "" {
+		if a, ok := n.Raw[s.opts.AnchorField].(string); ok && a != "" {
+			if s.reserved[a] {
+				delete(s.reserved, a)
+				return a
+			}
+			if _, taken := s.seen[a]; !taken {
+				s.seen[a] = 1
+				return a
+			}
+			slug = s.unique(a)
+		}
+	}
+
+	if slug == "" {
+		slug = s.unique(s.base(n.GetText()))
+	}
+	if s.opts.Persist && s.opts.AnchorField != "" {
+		if n.Raw == nil {
+			n.Raw = map[string]any{}
+		}
+		n.Raw[s.opts.AnchorField] = slug
+	}
+	return slug
+}
+
+// SlugText returns a unique slug for arbitrary text.
+func (s *Slugger) SlugText(text string) string {
+	return s.unique(s.base(text))
+}
+
+// ReserveAnchors holds the slugs persisted in Raw[AnchorField] of doc's
+// headings, so that Slug derives other slugs around them and returns each
+// to the first heading that carries it. It does nothing without an
+// AnchorField.
+func (s *Slugger) ReserveAnchors(doc Document) {
+	if s.opts.AnchorField == "" {
+		return
+	}
+	for i := range doc {
+		if headingLevel(&doc[i]) == 0 {
+			continue
+		}
+		if a, ok := doc[i].Raw[s.opts.AnchorField].(string); ok && a != "" {
+			if _, taken := s.seen[a]; !taken {
+				s.seen[a] = 1
+				s.reserved[a] = true
+			}
+		}
+	}
+}
+
+// Reset forgets all slugs handed out or reserved so far.
+func (s *Slugger) Reset() {
+	s.seen = map[string]int{}
+	s.reserved = map[string]bool{}
+}
+
+// AddAnchors returns a copy of doc in which every heading (h1–h6) carries
+// its slug in Raw[opts.AnchorField], defaulting to DefaultAnchorField.
+// Headings that already have an anchor keep it.
+func AddAnchors(doc Document, opts SlugOptions) Document {
+	if opts.AnchorField == "" {
+		opts.AnchorField = DefaultAnchorField
+	}
+	opts.Persist = true
+	s := NewSlugger(opts)
+	s.ReserveAnchors(doc)
+
+	out := make(Document, len(doc))
+	for i := range doc {
+		out[i] = *doc[i].Clone()
+		if headingLevel(&out[i]) > 0 {
+			s.Slug(&out[i])
+		}
+	}
+	return out
+}
+
+func (s *Slugger) base(text string) string {
+	var b strings.Builder
+	pending := false
+	runes := 0
+	write := func(r rune) {
+		if pending && b.Len() > 0 {
+			b.WriteString(s.opts.Separator)
+			runes += utf8.RuneCountInString(s.opts.Separator)
+		}
+		pending = false
+		b.WriteRune(r)
+		runes++
+	}
+
+	for _, r := range strings.ToLower(text) {
+		if s.opts.MaxLength > 0 && runes >= s.opts.MaxLength {
+			break
+		}
+		if t, ok := s.opts.Transliterate[r]; ok {
+			for _, tr := range t {
+				write(tr)
+			}
+			continue
+		}
+		if (unicode.IsLetter(r) || unicode.IsDigit(r)) && (!s.opts.ASCIIOnly || r < utf8.RuneSelf) {
+			write(r)
+		} else if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			pending = true
+		}
+	}
+
+	out := b.String()
+	if s.opts.MaxLength > 0 {
+		if r := []rune(out); len(r) > s.opts.MaxLength {
+			out = string(r[:s.opts.MaxLength])
+		}
+		out = strings.TrimSuffix(out, s.opts.Separator)
+	}
+	return out
+}
+
+func (s *Slugger) unique(slug string) string {
+	if slug == "" {
+		slug = "section"
+	}
+	n, ok := s.seen[slug]
+	if !ok {
+		s.seen[slug] = 1
+		return slug
+	}
+	for {
+		candidate := slug + s.opts.Separator + strconv.Itoa(n)
+		n++
+		if _, taken := s.seen[candidate]; !taken {
+			s.seen[slug] = n
+			s.seen[candidate] = 1
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package portabletext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Hello, World!":   "hello-world",
+		"  Über  Straße ": "über-straße",
+		"Go 1.23":         "go-1-23",
+		"日本語 ガイド":         "日本語-ガイド",
+		"!!!":             "",
+	}
+	for in, want := range tests {
+		if got := Slugify(NewBlock("h2").AddSpan(in)); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSluggerDeduplicates(t *testing.T) {
+	s := NewSlugger(SlugOptions{})
+	var got []string
+	for _, text := range []string{"Intro", "Intro", "Intro 1", "Intro", "", ""} {
+		got = append(got, s.Slug(NewBlock("h2").AddSpan(text)))
+	}
+	want := []string{"intro", "intro-1", "intro-1-1", "intro-2", "section", "section-1"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Slug() #%d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	s.Reset()
+	if slug := s.SlugText("Intro"); slug != "intro" {
+		t.Errorf("SlugText() after Reset = %q, want intro", slug)
+	}
+}
+
+func TestSluggerTransliteration(t *testing.T) {
+	german := map[rune]string{'ü': "ue", 'ß': "ss"}
+	tests := []struct {
+		name string
+		opts SlugOptions
+		want string
+	}{
+		{"latin ascii", SlugOptions{Transliterate: LatinTransliterations, ASCIIOnly: true}, "uber-strasse-cafe"},
+		{"german rules", SlugOptions{Transliterate: german, ASCIIOnly: true}, "ueber-strasse-caf"},
+		{"separator", SlugOptions{Separator: "_", Transliterate: LatinTransliterations}, "uber_strasse_cafe"},
+		{"max length", SlugOptions{Transliterate: LatinTransliterations, MaxLength: 6}, "uber-s"},
+		{"max length trims separator", SlugOptions{Transliterate: LatinTransliterations, MaxLength: 5}, "uber"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewSlugger(tt.opts).SlugText("Über Straße Café")
+			if got != tt.want {
+				t.Errorf("SlugText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSluggerPersist(t *testing.T) {
+	s := NewSlugger(SlugOptions{AnchorField: DefaultAnchorField, Persist: true})
+
+	n := NewBlock("h2").AddSpan("Setup")
+	if slug := s.Slug(n); slug != "setup" || n.Raw["anchor"] != "setup" {
+		t.Errorf("Slug() = %q, Raw[anchor] = %v", slug, n.Raw["anchor"])
+	}
+
+	// A persisted anchor is reused even if the text changes.
+	pinned := NewBlock("h2").AddSpan("Renamed")
+	pinned.Raw["anchor"] = "original"
+	if slug := s.Slug(pinned); slug != "original" {
+		t.Errorf("Slug() = %q, want persisted anchor", slug)
+	}
+	if slug := s.SlugText("Original"); slug != "original-1" {
+		t.Errorf("SlugText() = %q, want original-1", slug)
+	}
+}
+
+func TestAddAnchorsRoundTrip(t *testing.T) {
+	doc := Document{
+		*NewBlock("h1").AddSpan("Intro"),
+		*NewBlock("normal").AddSpan("Body"),
+		*NewBlock("h2").AddSpan("Intro"),
+	}
+	anchored := AddAnchors(doc, SlugOptions{})
+
+	if _, ok := doc[0].Raw["anchor"]; ok {
+		t.Error("AddAnchors() mutated the input document")
+	}
+	if _, ok := anchored[1].Raw["anchor"]; ok {
+		t.Error("AddAnchors() anchored a non-heading")
+	}
+
+	out, err := EncodeString(anchored)
+	if err != nil {
+		t.Fatalf("EncodeString() error = %v", err)
+	}
+	decoded, err := DecodeString(out)
+	if err != nil {
+		t.Fatalf("DecodeString() error = %v", err)
+	}
+
+	root := OutlineWithSlugger(decoded, NewSlugger(SlugOptions{AnchorField: DefaultAnchorField}))
+	if root.Children[0].Slug != "intro" || root.Children[0].Children[0].Slug != "intro-1" {
+		t.Errorf("outline slugs = %q, %q", root.Children[0].Slug, root.Children[0].Children[0].Slug)
+	}
+}
+
+func TestSluggerPersistedAndDerived(t *testing.T) {
+	// Three "Intro" headings, only the last with a persisted anchor.
+	doc := Document{
+		*NewBlock("h2").AddSpan("Intro"),
+		*NewBlock("h2").AddSpan("Intro"),
+		*NewBlock("h2").AddSpan("Intro"),
+	}
+	doc[2].Raw["anchor"] = "intro"
+
+	// Without ReserveAnchors, the persisted slug is de-duplicated.
+	s := NewSlugger(SlugOptions{AnchorField: DefaultAnchorField})
+	var got []string
+	for i := range doc {
+		got = append(got, s.Slug(&doc[i]))
+	}
+	if strings.Join(got, " ") != "intro intro-1 intro-2" {
+		t.Errorf("slugs = %q", got)
+	}
+
+	// With it, the persisted slug keeps its heading.
+	s.Reset()
+	s.ReserveAnchors(doc)
+	got = nil
+	for i := range doc {
+		got = append(got, s.Slug(&doc[i]))
+	}
+	if strings.Join(got, " ") != "intro-1 intro-2 intro" {
+		t.Errorf("slugs with reserved anchors = %q", got)
+	}
+
+	// Two headings persisting the same anchor do not share it.
+	doc[1].Raw["anchor"] = "intro"
+	anchored := AddAnchors(doc, SlugOptions{})
+	var anchors []string
+	for _, n := range anchored {
+		anchors = append(anchors, n.Raw["anchor"].(string))
+	}
+	if strings.Join(anchors, " ") != "intro-1 intro intro-2" {
+		t.Errorf("AddAnchors() = %q", anchors)
+	}
+}
@@ -0,0 +1,231 @@
+package portabletext
+
+import (
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ReadingWordsPerMinute is the reading speed used for DocStats.ReadingTime.
+const ReadingWordsPerMinute = 200
+
+// DocStats summarizes a document for dashboards and editorial checks. Text
+// measures are computed from GetText() of every block.
+type DocStats struct {
+	Nodes         int            `json:"nodes"`         // top-level nodes
+	Blocks        int            `json:"blocks"`        // nodes of _type "block"
+	ListItems     int            `json:"listItems"`     // blocks with a listItem
+	Headings      int            `json:"headings"`      // blocks styled h1–h6
+	CustomNodes   int            `json:"customNodes"`   // top-level nodes that are not blocks
+	Spans         int            `json:"spans"`         // children of _type "span"
+	InlineObjects int            `json:"inlineObjects"` // children that are not spans
+	Types         map[string]int `json:"types"`         // top-level nodes by _type
+	Styles        map[string]int `json:"styles"`        // blocks by style
+
+	Words      int `json:"words"`      // Unicode-aware; each CJK character counts as a word
+	Characters int `json:"characters"` // runes, including spaces
+	Sentences  int `json:"sentences"`
+	Syllables  int `json:"syllables"` // English heuristic
+
+	Links  int            `json:"links"`  // link annotations
+	Images int            `json:"images"` // image nodes and inline images
+	Marks  map[string]int `json:"marks"`  // spans per decorator, or per annotation _type
+
+	ReadingTime time.Duration `json:"readingTime"` // at ReadingWordsPerMinute; nanoseconds in JSON
+
+	// Readability scores; both are 0 for documents without words.
+	FleschReadingEase  float64 `json:"fleschReadingEase"`
+	FleschKincaidGrade float64 `json:"fleschKincaidGrade"`
+}
+
+// Stats computes statistics for doc.
+func Stats(doc Document) DocStats {
+	st := DocStats{
+		Nodes:  len(doc),
+		Types:  map[string]int{},
+		Styles: map[string]int{},
+		Marks:  map[string]int{},
+	}
+	for i := range doc {
+		n := &doc[i]
+		st.Types[n.Type]++
+		if n.Type == "image" {
+			st.Images++
+		}
+		if !n.IsBlock() {
+			st.CustomNodes++
+			continue
+		}
+
+		st.Blocks++
+		st.Styles[n.GetStyle()]++
+		if n.ListItem != nil {
+			st.ListItems++
+		}
+		if headingLevel(n) > 0 {
+			st.Headings++
+		}
+
+		annotations := map[string]string{}
+		for _, md := range n.MarkDefs {
+			annotations[md.Key] = md.Type
+			if md.Type == "link" {
+				st.Links++
+			}
+		}
+		for _, c := range n.Children {
+			if c.Type != "span" {
+				st.InlineObjects++
+				if c.Type == "image" {
+					st.Images++
+				}
+				continue
+			}
+			st.Spans++
+			for _, m := range c.Marks {
+				if t, ok := annotations[m]; ok {
+					m = t
+				}
+				st.Marks[m]++
+			}
+		}
+
+		text := n.GetText()
+		st.Characters += utf8.RuneCountInString(text)
+		words := textWords(text)
+		st.Words += len(words)
+		for _, w := range words {
+			st.Syllables += syllables(w)
+		}
+		if len(words) > 0 {
+			st.Sentences += max(1, countSentences(text))
+		}
+	}
+
+	st.ReadingTime = st.ReadingTimeAt(ReadingWordsPerMinute)
+	if st.Words > 0 {
+		wps := float64(st.Words) / float64(st.Sentences)
+		spw := float64(st.Syllables) / float64(st.Words)
+		st.FleschReadingEase = 206.835 - 1.015*wps - 84.6*spw
+		st.FleschKincaidGrade = 0.39*wps + 11.8*spw - 15.59
+	}
+	return st
+}
+
+// ReadingTimeAt returns the estimated reading time at wpm words per
+// minute, rounded to the second.
+func (st DocStats) ReadingTimeAt(wpm int) time.Duration {
+	if wpm <= 0 || st.Words == 0 {
+		return 0
+	}
+	d := time.Duration(float64(st.Words) / float64(wpm) * float64(time.Minute))
+	return d.Round(time.Second)
+}
+
+// Stats computes statistics for the section, including its heading and
+// all subsections. Walk the outline for a per-section breakdown.
+func (s *Section) Stats() DocStats {
+	var doc Document
+	s.appendTo(&doc)
+	return Stats(doc)
+}
+
+// textWords splits s into words: runs of letters, digits and combining
+// marks, joined by inner apostrophes or hyphens ("don't", "e-mail"). Han,
+// Hiragana and Katakana characters, which are written without spaces,
+// count as one word each.
+func textWords(s string) []string {
+	runes := []rune(s)
+	var words []string
+	for _, b := range wordBounds(runes) {
+		words = append(words, string(runes[b[0]:b[1]]))
+	}
+	return words
+}
+
+// wordBounds returns the [start, end) rune offsets of the words in runes,
+// as split by textWords.
+func wordBounds(runes []rune) [][2]int {
+	var bounds [][2]int
+	start := -1
+	end := func(i int) {
+		if start >= 0 {
+			bounds = append(bounds, [2]int{start, i})
+			start = -1
+		}
+	}
+	for i, r := range runes {
+		switch {
+		case isIdeograph(r):
+			end(i)
+			bounds = append(bounds, [2]int{i, i + 1})
+		case isWordRune(r):
+			if start < 0 {
+				start = i
+			}
+		case start >= 0 && strings.ContainsRune("'’-", r) &&
+			i+1 < len(runes) && isWordRune(runes[i+1]) && !isIdeograph(runes[i+1]):
+			// inner joiner; keep going
+		default:
+			end(i)
+		}
+	}
+	end(len(runes))
+	return bounds
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+func isIdeograph(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+// countSentences counts runs of sentence-ending punctuation that end the
+// text or are followed by a space. Text without a terminator counts as
+// zero; callers treat any block with words as at least one sentence.
+func countSentences(s string) int {
+	return len(sentenceEnds([]rune(s)))
+}
+
+// sentenceEnds returns the rune offsets just past each sentence-ending
+// run of punctuation counted by countSentences.
+func sentenceEnds(runes []rune) []int {
+	var ends []int
+	for i := 0; i < len(runes); i++ {
+		if !strings.ContainsRune(".!?…。！？", runes[i]) {
+			continue
+		}
+		j := i
+		for j+1 < len(runes) && strings.ContainsRune(".!?…。！？", runes[j+1]) {
+			j++
+		}
+		if j+1 == len(runes) || unicode.IsSpace(runes[j+1]) || strings.ContainsRune("。！？", runes[j]) {
+			ends = append(ends, j+1)
+		}
+		i = j
+	}
+	return ends
+}
+
+// syllables estimates the syllables in an English word by counting vowel
+// groups, ignoring a silent final "e". Words without Latin vowels, such as
+// numbers or non-Latin scripts, count as one.
+func syllables(word string) int {
+	w := strings.ToLower(word)
+	n := 0
+	prevVowel := false
+	for _, r := range w {
+		v := strings.ContainsRune("aeiouy", r)
+		if v && !prevVowel {
+			n++
+		}
+		prevVowel = v
+	}
+	if n > 1 && strings.HasSuffix(w, "e") && !strings.HasSuffix(w, "le") && !strings.HasSuffix(w, "ee") {
+		n--
+	}
+	return max(1, n)
+}
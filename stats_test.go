@@ -0,0 +1,132 @@
+package portabletext
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	item := *NewBlock("normal").AddSpan("Don't panic.")
+	bullet, level := "bullet", 1
+	item.ListItem, item.Level = &bullet, &level
+
+	link := NewBlock("normal").
+		AddMarkDef("l1", "link", map[string]any{"href": "https://example.com"}).
+		AddSpan("Read the ", "strong").
+		AddSpan("guide", "strong", "l1").
+		AddSpan(" today!")
+	link.Children = append(link.Children, Span{Type: "image", Raw: map[string]any{}})
+
+	doc := Document{
+		*NewBlock("h1").AddSpan("Hello world"),
+		*link,
+		item,
+		*NewNode("image"),
+		*NewNode("code"),
+	}
+	st := Stats(doc)
+
+	ints := []struct {
+		name      string
+		got, want int
+	}{
+		{"Nodes", st.Nodes, 5},
+		{"Blocks", st.Blocks, 3},
+		{"ListItems", st.ListItems, 1},
+		{"Headings", st.Headings, 1},
+		{"CustomNodes", st.CustomNodes, 2},
+		{"Spans", st.Spans, 5},
+		{"InlineObjects", st.InlineObjects, 1},
+		{"Words", st.Words, 8},
+		{"Characters", st.Characters, 11 + 21 + 12},
+		{"Sentences", st.Sentences, 3},
+		{"Links", st.Links, 1},
+		{"Images", st.Images, 2},
+		{"Types[block]", st.Types["block"], 3},
+		{"Styles[normal]", st.Styles["normal"], 2},
+		{"Marks[strong]", st.Marks["strong"], 2},
+		{"Marks[link]", st.Marks["link"], 1},
+	}
+	for _, c := range ints {
+		if c.got != c.want {
+			t.Errorf("%s = %d, want %d", c.name, c.got, c.want)
+		}
+	}
+	if st.ReadingTime != 2*time.Second {
+		t.Errorf("ReadingTime = %v, want 2s", st.ReadingTime)
+	}
+	if st.FleschReadingEase <= 60 {
+		t.Errorf("FleschReadingEase = %.1f, want > 60 for short words", st.FleschReadingEase)
+	}
+}
+
+func TestStatsEmpty(t *testing.T) {
+	st := Stats(Document{*NewNode("image")})
+	if st.Words != 0 || st.ReadingTime != 0 || st.FleschReadingEase != 0 || st.FleschKincaidGrade != 0 {
+		t.Errorf("Stats of wordless doc = %+v", st)
+	}
+}
+
+func TestStatsReadability(t *testing.T) {
+	// 2 sentences, 11 words, 13 syllables.
+	doc := Document{*NewBlock("normal").AddSpan("The cat sat on the mat. A dog ran away quickly.")}
+	st := Stats(doc)
+	if st.Words != 11 || st.Sentences != 2 || st.Syllables != 13 {
+		t.Fatalf("words/sentences/syllables = %d/%d/%d, want 11/2/13", st.Words, st.Sentences, st.Syllables)
+	}
+	ease := 206.835 - 1.015*11/2 - 84.6*13/11
+	grade := 0.39*11/2 + 11.8*13/11 - 15.59
+	if math.Abs(st.FleschReadingEase-ease) > 1e-9 || math.Abs(st.FleschKincaidGrade-grade) > 1e-9 {
+		t.Errorf("scores = %.2f/%.2f, want %.2f/%.2f", st.FleschReadingEase, st.FleschKincaidGrade, ease, grade)
+	}
+	if got := st.ReadingTimeAt(110); got != 6*time.Second {
+		t.Errorf("ReadingTimeAt(110) = %v, want 6s", got)
+	}
+}
+
+func TestTextWords(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"Hello, world!", []string{"Hello", "world"}},
+		{"don't re-use e-mail", []string{"don't", "re-use", "e-mail"}},
+		{"a - b", []string{"a", "b"}},
+		{"café naïve 42", []string{"café", "naïve", "42"}},
+		{"日本語です", []string{"日", "本", "語", "で", "す"}},
+		{"Go言語", []string{"Go", "言", "語"}},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		if got := textWords(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("textWords(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSyllables(t *testing.T) {
+	tests := map[string]int{
+		"cat": 1, "table": 2, "make": 1, "reading": 2, "beautiful": 3,
+		"free": 1, "rhythm": 1, "42": 1, "Quickly": 2,
+	}
+	for w, want := range tests {
+		if got := syllables(w); got != want {
+			t.Errorf("syllables(%q) = %d, want %d", w, got, want)
+		}
+	}
+}
+
+func TestSectionStats(t *testing.T) {
+	root := Outline(outlineTestDoc())
+	gs := root.Children[0]
+	st := gs.Stats()
+	// "Getting Started", "Intro", "Details", image, "Install"
+	if st.Nodes != 5 || st.Headings != 3 || st.Words != 5 || st.Images != 1 {
+		t.Errorf("section stats = %+v", st)
+	}
+	if root.Stats().Nodes != len(outlineTestDoc()) {
+		t.Errorf("root stats nodes = %d, want %d", root.Stats().Nodes, len(outlineTestDoc()))
+	}
+}
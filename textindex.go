@@ -0,0 +1,262 @@
+package portabletext
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// ErrInvalidOffset is returned for text offsets that are out of range or
+// fall inside a character in the requested unit.
+var ErrInvalidOffset = errors.New("invalid text offset")
+
+// OffsetUnit is the unit in which a text offset is counted.
+type OffsetUnit int
+
+const (
+	UnitRune     OffsetUnit = iota // Unicode code points, as in []rune (the default)
+	UnitByte                       // UTF-8 bytes, as in Go string indexes
+	UnitUTF16                      // UTF-16 code units, as in JavaScript and the Portable Text Editor
+	UnitGrapheme                   // user-perceived characters (extended grapheme clusters)
+)
+
+func (u OffsetUnit) String() string {
+	switch u {
+	case UnitRune:
+		return "rune"
+	case UnitByte:
+		return "byte"
+	case UnitUTF16:
+		return "utf16"
+	case UnitGrapheme:
+		return "grapheme"
+	}
+	return fmt.Sprintf("OffsetUnit(%d)", int(u))
+}
+
+// TextIndex maps text offsets within a block between units, and between
+// block-relative offsets (into the text of its spans) and span-relative
+// offsets. Inline objects have no width, even if they have a text field.
+// Invalid UTF-8 is indexed as U+FFFD, so byte offsets match the spans'
+// text only when it is valid UTF-8. A TextIndex is a snapshot: build a new
+// one after the block's children change.
+type TextIndex struct {
+	text      string
+	bytes     []int // byte offset of each rune, then len(text)
+	utf16     []int // UTF-16 offset of each rune, then the total
+	graphemes []int // rune index of each cluster start, then the rune count
+	spans     []indexedSpan
+}
+
+// indexedSpan is a span child and its rune range in the block text.
+type indexedSpan struct {
+	child      int
+	start, end int
+}
+
+// TextPosition is a location in a block found by TextIndex.Locate.
+type TextPosition struct {
+	Child  int    // index in Node.Children of the span
+	Offset int    // offset within the span's text, in the unit asked for
+	Char   string // the grapheme cluster at the offset; "" at the end of the text
+}
+
+// NewTextIndex indexes the text of block n.
+func NewTextIndex(n *Node) *TextIndex {
+	x := &TextIndex{}
+	var runes []rune
+	for j, c := range n.Children {
+		if c.Type != "span" {
+			continue
+		}
+		start := len(runes)
+		if c.Text != nil {
+			runes = append(runes, []rune(*c.Text)...)
+		}
+		x.spans = append(x.spans, indexedSpan{child: j, start: start, end: len(runes)})
+	}
+	x.text = string(runes)
+
+	x.bytes = make([]int, len(runes)+1)
+	x.utf16 = make([]int, len(runes)+1)
+	b, u := 0, 0
+	for i, r := range runes {
+		x.bytes[i], x.utf16[i] = b, u
+		b += utf8.RuneLen(r)
+		if r >= 0x10000 {
+			u += 2
+		} else {
+			u++
+		}
+	}
+	x.bytes[len(runes)], x.utf16[len(runes)] = b, u
+	x.graphemes = graphemeStarts(runes)
+	return x
+}
+
+// Text returns the indexed text: the block's spans joined, for valid
+// UTF-8. It differs from GetText(), which also includes the text field of
+// inline objects.
+func (x *TextIndex) Text() string { return x.text }
+
+// Len returns the length of the text in unit u.
+func (x *TextIndex) Len(u OffsetUnit) int {
+	n := len(x.bytes) - 1
+	v, _ := x.fromRune(n, u)
+	return v
+}
+
+// Convert converts a block-relative offset between units.
+func (x *TextIndex) Convert(off int, from, to OffsetUnit) (int, error) {
+	r, err := x.toRune(off, from)
+	if err == nil {
+		off, err = x.fromRune(r, to)
+	}
+	if err != nil {
+		return 0, wrap("offset", "", err)
+	}
+	return off, nil
+}
+
+// BlockOffset converts an offset within the text of the span at
+// Node.Children[child] to a block-relative offset in the same unit.
+func (x *TextIndex) BlockOffset(child, off int, u OffsetUnit) (int, error) {
+	b, err := x.blockOffset(child, off, u)
+	if err != nil {
+		return 0, wrap("offset", fmt.Sprintf("children[%d]", child), err)
+	}
+	return b, nil
+}
+
+func (x *TextIndex) blockOffset(child, off int, u OffsetUnit) (int, error) {
+	s, ok := x.span(child)
+	if !ok {
+		return 0, fmt.Errorf("%w: not a span", ErrInvalidOffset)
+	}
+	start, err := x.fromRune(s.start, u)
+	if err != nil {
+		return 0, err
+	}
+	end, _ := x.fromRune(s.end, u)
+	if off < 0 || start+off > end {
+		return 0, fmt.Errorf("%w: %d is outside the span's %d %ss", ErrInvalidOffset, off, end-start, u)
+	}
+	if _, err := x.toRune(start+off, u); err != nil {
+		return 0, err
+	}
+	return start + off, nil
+}
+
+// Locate returns the span and character at a block-relative offset. An
+// offset on the boundary between two spans belongs to the later one,
+// except at the end of the text. Empty spans are only returned when the
+// whole block is empty.
+func (x *TextIndex) Locate(off int, u OffsetUnit) (TextPosition, error) {
+	r, err := x.toRune(off, u)
+	if err != nil {
+		return TextPosition{}, wrap("offset", "", err)
+	}
+	if len(x.spans) == 0 {
+		return TextPosition{}, wrap("offset", "", fmt.Errorf("%w: block has no spans", ErrInvalidOffset))
+	}
+
+	s, found := indexedSpan{}, false
+	for _, sp := range x.spans {
+		if sp.start <= r && r < sp.end {
+			s, found = sp, true
+			break
+		}
+	}
+	if !found {
+		// The end of the text: the last non-empty span.
+		s = x.spans[0]
+		for _, sp := range x.spans {
+			if sp.start < sp.end {
+				s = sp
+			}
+		}
+	}
+
+	start, err := x.fromRune(s.start, u)
+	if err != nil {
+		return TextPosition{}, wrap("offset", "", err)
+	}
+	pos := TextPosition{Child: s.child, Offset: off - start}
+	if g := sort.SearchInts(x.graphemes, r+1) - 1; g >= 0 && g < len(x.graphemes)-1 {
+		pos.Char = x.text[x.bytes[x.graphemes[g]]:x.bytes[x.graphemes[g+1]]]
+	}
+	return pos, nil
+}
+
+func (x *TextIndex) span(child int) (indexedSpan, bool) {
+	for _, s := range x.spans {
+		if s.child == child {
+			return s, true
+		}
+	}
+	return indexedSpan{}, false
+}
+
+// objectStart returns the rune index at which the non-span child sits:
+// the end of the spans before it. Inline objects have no width, so this is
+// both their start and their end.
+func (x *TextIndex) objectStart(child int) int {
+	r := 0
+	for _, s := range x.spans {
+		if s.child > child {
+			break
+		}
+		r = s.end
+	}
+	return r
+}
+
+// toRune converts a block-relative offset in unit u to a rune index. Like
+// fromRune, it returns errors unwrapped so that callers can add a path.
+func (x *TextIndex) toRune(off int, u OffsetUnit) (int, error) {
+	var table []int
+	switch u {
+	case UnitRune:
+		if off >= 0 && off < len(x.bytes) {
+			return off, nil
+		}
+	case UnitByte:
+		table = x.bytes
+	case UnitUTF16:
+		table = x.utf16
+	case UnitGrapheme:
+		if off >= 0 && off < len(x.graphemes) {
+			return x.graphemes[off], nil
+		}
+	default:
+		return 0, fmt.Errorf("%w: unknown unit %v", ErrInvalidOffset, u)
+	}
+	if table != nil {
+		if i := sort.SearchInts(table, off); i < len(table) && table[i] == off {
+			return i, nil
+		}
+		if off >= 0 && off <= table[len(table)-1] {
+			return 0, fmt.Errorf("%w: %s offset %d splits a character", ErrInvalidOffset, u, off)
+		}
+	}
+	return 0, fmt.Errorf("%w: %s offset %d out of range [0, %d]", ErrInvalidOffset, u, off, x.Len(u))
+}
+
+// fromRune converts a rune index to an offset in unit u.
+func (x *TextIndex) fromRune(r int, u OffsetUnit) (int, error) {
+	switch u {
+	case UnitRune:
+		return r, nil
+	case UnitByte:
+		return x.bytes[r], nil
+	case UnitUTF16:
+		return x.utf16[r], nil
+	case UnitGrapheme:
+		if g := sort.SearchInts(x.graphemes, r); g < len(x.graphemes) && x.graphemes[g] == r {
+			return g, nil
+		}
+		return 0, fmt.Errorf("%w: rune offset %d is inside a grapheme cluster", ErrInvalidOffset, r)
+	}
+	return 0, fmt.Errorf("%w: unknown unit %v", ErrInvalidOffset, u)
+}
@@ -0,0 +1,145 @@
+package portabletext
+
+import (
+	"errors"
+	"testing"
+)
+
+// textIndexTestBlock has the text "Hi 👋🏽日本é🇩🇪!" across four spans and
+// an inline object.
+func textIndexTestBlock() *Node {
+	n := NewBlock("normal").AddSpan("Hi 👋🏽").AddSpan("日本", "strong").AddSpan("é")
+	n.Children = append(n.Children, Span{Type: "emoji", Raw: map[string]any{}})
+	return n.AddSpan("🇩🇪!")
+}
+
+func TestTextIndexLen(t *testing.T) {
+	x := NewTextIndex(textIndexTestBlock())
+	if x.Text() != textIndexTestBlock().GetText() {
+		t.Errorf("Text() = %q", x.Text())
+	}
+	want := map[OffsetUnit]int{UnitByte: 29, UnitRune: 12, UnitUTF16: 16, UnitGrapheme: 9}
+	for u, n := range want {
+		if got := x.Len(u); got != n {
+			t.Errorf("Len(%v) = %d, want %d", u, got, n)
+		}
+	}
+}
+
+func TestTextIndexConvert(t *testing.T) {
+	x := NewTextIndex(textIndexTestBlock())
+	tests := []struct {
+		off      int
+		from, to OffsetUnit
+		want     int
+		wantErr  bool
+	}{
+		{5, UnitRune, UnitByte, 11, false},
+		{5, UnitRune, UnitUTF16, 7, false},
+		{5, UnitRune, UnitGrapheme, 4, false},
+		{7, UnitUTF16, UnitRune, 5, false},
+		{9, UnitGrapheme, UnitUTF16, 16, false},
+		{3, UnitGrapheme, UnitByte, 3, false},
+		{6, UnitGrapheme, UnitRune, 7, false},
+		{0, UnitByte, UnitGrapheme, 0, false},
+		{4, UnitUTF16, UnitRune, 0, true},    // inside a surrogate pair
+		{4, UnitByte, UnitRune, 0, true},     // inside 👋
+		{4, UnitRune, UnitGrapheme, 0, true}, // between 👋 and its modifier
+		{13, UnitRune, UnitByte, 0, true},
+		{-1, UnitUTF16, UnitRune, 0, true},
+		{10, UnitGrapheme, UnitRune, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := x.Convert(tt.off, tt.from, tt.to)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Convert(%d, %v, %v) = %d, %v, want %d (error %v)", tt.off, tt.from, tt.to, got, err, tt.want, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidOffset) {
+			t.Errorf("Convert(%d, %v, %v) error = %v, want ErrInvalidOffset", tt.off, tt.from, tt.to, err)
+		}
+	}
+}
+
+func TestTextIndexLocate(t *testing.T) {
+	x := NewTextIndex(textIndexTestBlock())
+	tests := []struct {
+		off  int
+		unit OffsetUnit
+		want TextPosition
+	}{
+		{0, UnitRune, TextPosition{Child: 0, Offset: 0, Char: "H"}},
+		{3, UnitGrapheme, TextPosition{Child: 0, Offset: 3, Char: "👋🏽"}},
+		{7, UnitUTF16, TextPosition{Child: 1, Offset: 0, Char: "日"}},
+		{8, UnitRune, TextPosition{Child: 2, Offset: 1, Char: "é"}},
+		{7, UnitGrapheme, TextPosition{Child: 4, Offset: 0, Char: "🇩🇪"}},
+		{28, UnitByte, TextPosition{Child: 4, Offset: 8, Char: "!"}},
+		{16, UnitUTF16, TextPosition{Child: 4, Offset: 5, Char: ""}},
+	}
+	for _, tt := range tests {
+		got, err := x.Locate(tt.off, tt.unit)
+		if err != nil || got != tt.want {
+			t.Errorf("Locate(%d, %v) = %+v, %v, want %+v", tt.off, tt.unit, got, err, tt.want)
+		}
+	}
+
+	if _, err := NewTextIndex(NewBlock("normal")).Locate(0, UnitRune); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("Locate on a block without spans: error = %v", err)
+	}
+	empty := NewBlock("normal").AddSpan("").AddSpan("")
+	if got, err := NewTextIndex(empty).Locate(0, UnitRune); err != nil || got.Child != 0 {
+		t.Errorf("Locate on an empty block = %+v, %v", got, err)
+	}
+}
+
+func TestTextIndexBlockOffset(t *testing.T) {
+	x := NewTextIndex(textIndexTestBlock())
+	tests := []struct {
+		child, off int
+		unit       OffsetUnit
+		want       int
+		wantErr    bool
+	}{
+		{4, 2, UnitUTF16, 13, false},
+		{4, 1, UnitGrapheme, 8, false},
+		{1, 3, UnitByte, 14, false},
+		{1, 1, UnitByte, 0, true}, // inside 日
+		{2, 2, UnitRune, 9, false},
+		{1, 3, UnitRune, 0, true},  // past the end of the span
+		{3, 0, UnitRune, 0, true},  // inline object
+		{0, 4, UnitUTF16, 0, true}, // inside 👋
+	}
+	for _, tt := range tests {
+		got, err := x.BlockOffset(tt.child, tt.off, tt.unit)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("BlockOffset(%d, %d, %v) = %d, %v, want %d", tt.child, tt.off, tt.unit, got, err, tt.want)
+		}
+	}
+}
+
+func TestGraphemeStarts(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"é", 1},
+		{"äb", 2},
+		{"\r\n", 1},
+		{"\n\r", 2},
+		{"👨‍👩‍👧", 1},
+		{"🏳️‍🌈", 1},
+		{"👍🏿", 1},
+		{"🇩🇪🇫🇷", 2},
+		{"🇩🇪🇫", 2},
+		{"한국어", 3},
+		{"각", 1},
+		{"a‍b", 2},
+		{"🏴\U000E0067\U000E0062\U000E0073\U000E0063\U000E0074\U000E007F", 1},
+	}
+	for _, tt := range tests {
+		if got := len(graphemeStarts([]rune(tt.in))) - 1; got != tt.want {
+			t.Errorf("graphemes(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package portabletext
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// NodeTransformFunc rewrites a single node. The node passed in is a deep
+// copy and may be modified freely. Returning an empty slice removes the
+// node; returning several nodes expands it in place.
+type NodeTransformFunc func(ctx context.Context, n *Node) ([]Node, error)
+
+// TransformE applies fn to each node in order and returns a new document.
+// It stops at the first error, which is returned wrapped in an *Error
+// carrying the path of the failing node, or when ctx is cancelled.
+func TransformE(ctx context.Context, doc Document, fn NodeTransformFunc) (Document, error) {
+	result := make(Document, 0, len(doc))
+	for i := range doc {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		out, err := fn(ctx, doc[i].Clone())
+		if err != nil {
+			return nil, wrap("transform", nodePath(i).String(), err)
+		}
+		result = append(result, out...)
+	}
+	return result, nil
+}
+
+// TransformConcurrent is like TransformE but runs fn on up to workers nodes
+// at a time, which suits expensive per-node work such as resolving assets.
+// Output order matches input order. If workers <= 0, GOMAXPROCS is used.
+// The first error cancels the context passed to the remaining calls.
+func TransformConcurrent(ctx context.Context, doc Document, workers int, fn NodeTransformFunc) (Document, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]Node, len(doc))
+	jobs := make(chan int)
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				out, err := fn(ctx, doc[i].Clone())
+				if err != nil {
+					fail(wrap("transform", nodePath(i).String(), err))
+					continue
+				}
+				results[i] = out
+			}
+		}()
+	}
+
+feed:
+	for i := range doc {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make(Document, 0, len(doc))
+	for _, out := range results {
+		result = append(result, out...)
+	}
+	return result, nil
+}
+
+// TransformSpans applies fn to every child of every node and returns a new
+// document. fn receives copies of the owning node and the span; it may edit
+// Text, Marks or Raw, change an inline object, or add mark definitions to
+// the owning node. The returned spans replace the original one, so
+// returning nil removes it and returning several splits it.
+func TransformSpans(doc Document, fn func(parent *Node, s *Span) []Span) Document {
+	result := make(Document, 0, len(doc))
+	for i := range doc {
+		n := doc[i].Clone()
+		if n.Children != nil {
+			children := make([]Span, 0, len(n.Children))
+			for j := range n.Children {
+				children = append(children, fn(n, &n.Children[j])...)
+			}
+			n.Children = children
+		}
+		result = append(result, *n)
+	}
+	return result
+}
@@ -0,0 +1,156 @@
+package portabletext
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTransformEExpand(t *testing.T) {
+	doc := Document{
+		*NewBlock("h1").AddSpan("Title"),
+		*NewNode("divider"),
+		*NewBlock("normal").AddSpan("Body"),
+	}
+
+	out, err := TransformE(context.Background(), doc, func(ctx context.Context, n *Node) ([]Node, error) {
+		switch {
+		case n.Type == "divider":
+			return nil, nil
+		case n.GetStyle() == "h1":
+			return []Node{*n, *NewBlock("normal").AddSpan("Subtitle")}, nil
+		}
+		return []Node{*n}, nil
+	})
+	if err != nil {
+		t.Fatalf("TransformE() error = %v", err)
+	}
+
+	var texts []string
+	for _, n := range out {
+		texts = append(texts, n.GetText())
+	}
+	if got := strings.Join(texts, "|"); got != "Title|Subtitle|Body" {
+		t.Errorf("TransformE() = %s, want Title|Subtitle|Body", got)
+	}
+}
+
+func TestTransformEError(t *testing.T) {
+	doc := Document{*NewBlock("normal"), *NewNode("image")}
+	boom := errors.New("boom")
+
+	_, err := TransformE(context.Background(), doc, func(ctx context.Context, n *Node) ([]Node, error) {
+		if n.Type == "image" {
+			return nil, boom
+		}
+		return []Node{*n}, nil
+	})
+
+	var pErr *Error
+	if !errors.As(err, &pErr) || pErr.Path != "[1]" || !errors.Is(err, boom) {
+		t.Errorf("TransformE() error = %v, want boom at [1]", err)
+	}
+}
+
+func TestTransformECancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := TransformE(ctx, Document{*NewBlock("normal")}, func(ctx context.Context, n *Node) ([]Node, error) {
+		return []Node{*n}, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("TransformE() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestTransformConcurrent(t *testing.T) {
+	var doc Document
+	for i := 0; i < 50; i++ {
+		doc = append(doc, *NewBlock("normal").AddSpan(strings.Repeat("x", i)))
+	}
+
+	var inFlight, maxInFlight int32
+	out, err := TransformConcurrent(context.Background(), doc, 4, func(ctx context.Context, n *Node) ([]Node, error) {
+		cur := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			old := atomic.LoadInt32(&maxInFlight)
+			if cur <= old || atomic.CompareAndSwapInt32(&maxInFlight, old, cur) {
+				break
+			}
+		}
+		n.Key = n.GetText()
+		return []Node{*n}, nil
+	})
+	if err != nil {
+		t.Fatalf("TransformConcurrent() error = %v", err)
+	}
+	if len(out) != len(doc) {
+		t.Fatalf("TransformConcurrent() returned %d nodes, want %d", len(out), len(doc))
+	}
+	for i := range out {
+		if len(out[i].Key) != i {
+			t.Fatalf("TransformConcurrent() changed order at %d", i)
+		}
+	}
+	if maxInFlight > 4 {
+		t.Errorf("TransformConcurrent() ran %d workers, limit 4", maxInFlight)
+	}
+	if doc[0].Key != "" {
+		t.Error("TransformConcurrent() mutated the input document")
+	}
+}
+
+func TestTransformConcurrentError(t *testing.T) {
+	doc := Document{*NewBlock("normal"), *NewNode("image"), *NewBlock("normal")}
+	boom := errors.New("boom")
+
+	_, err := TransformConcurrent(context.Background(), doc, 2, func(ctx context.Context, n *Node) ([]Node, error) {
+		if n.Type == "image" {
+			return nil, boom
+		}
+		return []Node{*n}, nil
+	})
+	if !errors.Is(err, boom) {
+		t.Errorf("TransformConcurrent() error = %v, want boom", err)
+	}
+}
+
+func TestTransformSpans(t *testing.T) {
+	doc := Document{*NewBlock("normal").AddSpan("Hello world", "em"), *NewNode("image")}
+
+	out := TransformSpans(doc, func(parent *Node, s *Span) []Span {
+		words := strings.Fields(*s.Text)
+		spans := make([]Span, 0, len(words))
+		for i, w := range words {
+			if i > 0 {
+				w = " " + w
+			}
+			c := *s
+			c.Text = &w
+			c.Marks = append([]string{"strong"}, s.Marks...)
+			spans = append(spans, c)
+		}
+		parent.Raw["split"] = true
+		return spans
+	})
+
+	if len(out[0].Children) != 2 {
+		t.Fatalf("TransformSpans() produced %d spans, want 2", len(out[0].Children))
+	}
+	if out[0].GetText() != "Hello world" || !out[0].Children[1].HasMark("strong") {
+		t.Errorf("TransformSpans() produced %q with marks %v", out[0].GetText(), out[0].Children[1].Marks)
+	}
+	if out[0].Raw["split"] != true {
+		t.Error("TransformSpans() discarded parent changes")
+	}
+	if len(doc[0].Children) != 1 || doc[0].Children[0].HasMark("strong") {
+		t.Error("TransformSpans() mutated the input document")
+	}
+	if out[1].Children != nil {
+		t.Error("TransformSpans() added children to a custom node")
+	}
+}
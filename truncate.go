@@ -0,0 +1,196 @@
+package portabletext
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// TruncateBoundary selects where Truncate may cut a block's text.
+type TruncateBoundary int
+
+const (
+	TruncateAtWord     TruncateBoundary = iota // after a whole word (the default)
+	TruncateAtSentence                         // after a sentence, else at a block boundary
+	TruncateAtChar                             // at any character
+)
+
+// TruncateOptions controls Truncate. Zero limits mean no limit.
+type TruncateOptions struct {
+	MaxChars  int // Characters (runes) of block text
+	MaxWords  int // Words, counted as by Stats
+	MaxBlocks int // Top-level nodes kept
+
+	Boundary TruncateBoundary
+
+	// Ellipsis is appended as an unmarked span to the last kept block when
+	// anything was cut. Defaults to "…"; set NoEllipsis to omit it.
+	Ellipsis   string
+	NoEllipsis bool
+
+	// SkipCustom drops nodes other than blocks, such as images and code.
+	// Skipped nodes count toward no limit and do not make the result
+	// truncated.
+	SkipCustom bool
+
+	// KeyFunc generates the ellipsis span's _key. Defaults to NewKey.
+	KeyFunc func() string
+}
+
+// Truncate returns the beginning of doc within the limits in opts, for
+// teasers and excerpts, and reports whether anything was cut.
+//
+// Whole nodes are kept while they fit. The first block that does not fit
+// is cut at the chosen boundary: surviving spans keep their marks and the
+// block keeps the markDefs they still reference. If the very first text
+// would be cut to nothing at a sentence or word boundary, Truncate falls
+// back to the next finer boundary so the excerpt is never empty.
+func Truncate(doc Document, opts TruncateOptions) (Document, bool) {
+	if opts.Ellipsis == "" {
+		opts.Ellipsis = "…"
+	}
+	if opts.KeyFunc == nil {
+		opts.KeyFunc = NewKey
+	}
+	// Remaining budgets; negative means unlimited.
+	chars, words := -1, -1
+	if opts.MaxChars > 0 {
+		chars = opts.MaxChars
+	}
+	if opts.MaxWords > 0 {
+		words = opts.MaxWords
+	}
+
+	out := Document{}
+	truncated := false
+	hasText := false
+	for i := range doc {
+		n := &doc[i]
+		if !n.IsBlock() && opts.SkipCustom {
+			continue
+		}
+		if chars == 0 || words == 0 || (opts.MaxBlocks > 0 && len(out) == opts.MaxBlocks) {
+			truncated = true
+			break
+		}
+		if !n.IsBlock() {
+			out = append(out, *n.Clone())
+			continue
+		}
+
+		runes := []rune(n.GetText())
+		cut := truncatePoint(runes, chars, words, opts.Boundary, !hasText)
+		if cut == len(runes) {
+			out = append(out, *n.Clone())
+			if chars > 0 {
+				chars -= len(runes)
+			}
+			if words > 0 {
+				words -= len(wordBounds(runes))
+			}
+			hasText = hasText || len(runes) > 0
+			continue
+		}
+		truncated = true
+		if cut > 0 {
+			out = append(out, *truncateBlock(n, cut))
+		}
+		break
+	}
+
+	if truncated && !opts.NoEllipsis && len(out) > 0 && out[len(out)-1].IsBlock() {
+		last := &out[len(out)-1]
+		last.AddSpan(opts.Ellipsis)
+		last.Children[len(last.Children)-1].Raw["_key"] = opts.KeyFunc()
+	}
+	return out, truncated
+}
+
+// truncatePoint returns how many runes of a block's text fit the
+// remaining budgets (negative means unlimited), moved back to a boundary.
+// first allows falling back to a finer boundary rather than returning 0.
+func truncatePoint(runes []rune, chars, words int, boundary TruncateBoundary, first bool) int {
+	limit := len(runes)
+	if chars >= 0 {
+		limit = min(limit, chars)
+	}
+	bounds := wordBounds(runes)
+	if words >= 0 && len(bounds) > words {
+		if words > 0 {
+			limit = min(limit, bounds[words-1][1])
+		} else {
+			limit = 0
+		}
+	}
+	if limit == len(runes) {
+		return limit
+	}
+
+	cut := 0
+	if boundary == TruncateAtSentence {
+		for _, e := range sentenceEnds(runes) {
+			if e <= limit {
+				cut = e
+			}
+		}
+		if cut > 0 || !first {
+			return cut
+		}
+	}
+	if boundary != TruncateAtChar {
+		for _, b := range bounds {
+			if b[1] <= limit {
+				cut = b[1]
+			}
+		}
+		if cut > 0 || !first {
+			return cut
+		}
+	}
+	return limit
+}
+
+// truncateBlock returns a copy of n keeping the first cut runes of its
+// text, with trailing whitespace, empty spans and unreferenced markDefs
+// removed.
+func truncateBlock(n *Node, cut int) *Node {
+	c := n.Clone()
+	children := c.Children[:0]
+	pos := 0
+	for _, s := range c.Children {
+		if s.Text == nil {
+			if pos < cut {
+				children = append(children, s)
+			}
+			continue
+		}
+		if pos >= cut {
+			break
+		}
+		r := []rune(*s.Text)
+		if pos+len(r) > cut {
+			t := string(r[:cut-pos])
+			s.Text = &t
+		}
+		pos += len(r)
+		children = append(children, s)
+	}
+
+	for len(children) > 0 {
+		last := &children[len(children)-1]
+		if last.Text == nil {
+			break
+		}
+		t := strings.TrimRightFunc(*last.Text, unicode.IsSpace)
+		if t != "" {
+			last.Text = &t
+			break
+		}
+		children = children[:len(children)-1]
+	}
+	c.Children = slices.DeleteFunc(children, func(s Span) bool { return s.Text != nil && *s.Text == "" })
+
+	used := usedMarks(c.Children)
+	c.MarkDefs = slices.DeleteFunc(c.MarkDefs, func(md MarkDef) bool { return !used[md.Key] })
+	return c
+}
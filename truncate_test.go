@@ -0,0 +1,111 @@
+package portabletext
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func truncateTestDoc() Document {
+	first := NewBlock("normal").
+		AddMarkDef("l1", "link", map[string]any{"href": "https://example.com"}).
+		AddMarkDef("l2", "link", map[string]any{"href": "https://example.org"}).
+		AddSpan("The ").
+		AddSpan("quick brown", "strong", "l1").
+		AddSpan(" fox jumps. Over the ").
+		AddSpan("lazy dog", "l2").
+		AddSpan(".")
+	return Document{
+		*first,
+		*NewNode("image"),
+		*NewBlock("normal").AddSpan("Second paragraph here."),
+	}
+}
+
+func texts(doc Document) []string {
+	var out []string
+	for i := range doc {
+		if doc[i].IsBlock() {
+			out = append(out, doc[i].GetText())
+		} else {
+			out = append(out, "<"+doc[i].Type+">")
+		}
+	}
+	return out
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      TruncateOptions
+		want      []string
+		truncated bool
+	}{
+		{"no limits", TruncateOptions{}, []string{"The quick brown fox jumps. Over the lazy dog.", "<image>", "Second paragraph here."}, false},
+		{"word boundary", TruncateOptions{MaxChars: 12}, []string{"The quick…"}, true},
+		{"char boundary", TruncateOptions{MaxChars: 12, Boundary: TruncateAtChar}, []string{"The quick br…"}, true},
+		{"char boundary trims space", TruncateOptions{MaxChars: 10, Boundary: TruncateAtChar}, []string{"The quick…"}, true},
+		{"sentence boundary", TruncateOptions{MaxChars: 40, Boundary: TruncateAtSentence}, []string{"The quick brown fox jumps.…"}, true},
+		{"words", TruncateOptions{MaxWords: 3}, []string{"The quick brown…"}, true},
+		{"words across blocks", TruncateOptions{MaxWords: 11}, []string{"The quick brown fox jumps. Over the lazy dog.", "<image>", "Second paragraph…"}, true},
+		{"blocks", TruncateOptions{MaxBlocks: 1}, []string{"The quick brown fox jumps. Over the lazy dog.…"}, true},
+		{"skip custom", TruncateOptions{MaxBlocks: 2, SkipCustom: true}, []string{"The quick brown fox jumps. Over the lazy dog.", "Second paragraph here."}, false},
+		{"exact fit", TruncateOptions{MaxWords: 12, SkipCustom: true}, []string{"The quick brown fox jumps. Over the lazy dog.", "Second paragraph here."}, false},
+		{"no ellipsis", TruncateOptions{MaxWords: 2, NoEllipsis: true}, []string{"The quick"}, true},
+		{"custom ellipsis", TruncateOptions{MaxWords: 2, Ellipsis: " [more]"}, []string{"The quick [more]"}, true},
+		{"sentence drops later block", TruncateOptions{MaxChars: 55, Boundary: TruncateAtSentence, SkipCustom: true}, []string{"The quick brown fox jumps. Over the lazy dog.…"}, true},
+		{"sentence falls back to words", TruncateOptions{MaxChars: 15, Boundary: TruncateAtSentence}, []string{"The quick brown…"}, true},
+		{"word falls back to chars", TruncateOptions{MaxChars: 2}, []string{"Th…"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := Truncate(truncateTestDoc(), tt.opts)
+			if !slices.Equal(texts(got), tt.want) || truncated != tt.truncated {
+				t.Errorf("Truncate() = %q, %v, want %q, %v", texts(got), truncated, tt.want, tt.truncated)
+			}
+		})
+	}
+}
+
+func TestTruncateMarks(t *testing.T) {
+	doc := truncateTestDoc()
+	got, _ := Truncate(doc, TruncateOptions{MaxChars: 12, KeyFunc: func() string { return "k" }})
+
+	n := got[0]
+	if len(n.Children) != 3 {
+		t.Fatalf("children = %d, want 3", len(n.Children))
+	}
+	if s := n.Children[1]; *s.Text != "quick" || !slices.Equal(s.Marks, []string{"strong", "l1"}) {
+		t.Errorf("cut span = %q %v, want \"quick\" [strong l1]", *s.Text, s.Marks)
+	}
+	if len(n.MarkDefs) != 1 || n.MarkDefs[0].Key != "l1" {
+		t.Errorf("markDefs = %v, want only l1", n.MarkDefs)
+	}
+	if e := n.Children[2]; *e.Text != "…" || len(e.Marks) != 0 || e.Raw["_key"] != "k" {
+		t.Errorf("ellipsis span = %+v", e)
+	}
+	if len(doc[0].MarkDefs) != 2 || len(doc[0].Children) != 5 {
+		t.Error("Truncate modified its input")
+	}
+}
+
+func TestTruncateInlineObjects(t *testing.T) {
+	b := NewBlock("normal").AddSpan("Hello ")
+	b.Children = append(b.Children, Span{Type: "emoji", Raw: map[string]any{}})
+	b.AddSpan("world again")
+	doc := Document{*b}
+
+	got, _ := Truncate(doc, TruncateOptions{MaxWords: 2, NoEllipsis: true})
+	var types []string
+	for _, c := range got[0].Children {
+		types = append(types, c.Type)
+	}
+	if strings.Join(types, ",") != "span,emoji,span" || got[0].GetText() != "Hello world" {
+		t.Errorf("children = %v, text %q", types, got[0].GetText())
+	}
+
+	got, _ = Truncate(doc, TruncateOptions{MaxWords: 1, NoEllipsis: true})
+	if len(got[0].Children) != 1 || got[0].GetText() != "Hello" {
+		t.Errorf("inline object after the cut kept: %+v", got[0].Children)
+	}
+}
@@ -0,0 +1,313 @@
+package portabletext
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrMissingKey is returned by ExportXLIFF for a text block without a
+// _key, which is needed to match its translation unit on import.
+var ErrMissingKey = errors.New("missing _key")
+
+// xliffDecorators maps decorators to XLIFF 2.0 formatting subtypes.
+var xliffDecorators = map[string]string{
+	"strong":    "xlf:b",
+	"em":        "xlf:i",
+	"underline": "xlf:u",
+}
+
+// ExportXLIFF encodes the text of doc as an XLIFF 2.0 file for
+// translation. Each block with text becomes a unit whose id is the
+// block's _key. Marks become <pc> elements, nested so that the longest
+// running mark is outermost, and inline objects become <ph> elements.
+// Placeholder ids are numbered per unit in document order. Custom nodes
+// are not exported. tgtLang may be empty.
+func ExportXLIFF(doc Document, srcLang, tgtLang string) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	fmt.Fprintf(&b, `<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="2.0" srcLang="%s"`, xmlAttr(srcLang))
+	if tgtLang != "" {
+		fmt.Fprintf(&b, ` trgLang="%s"`, xmlAttr(tgtLang))
+	}
+	b.WriteString(">\n  <file id=\"f1\">\n")
+
+	seen := map[string]bool{}
+	for i := range doc {
+		n := &doc[i]
+		if !n.IsBlock() || strings.TrimSpace(n.GetText()) == "" {
+			continue
+		}
+		if n.Key == "" {
+			return nil, wrap("xliff", nodePath(i).String(), ErrMissingKey)
+		}
+		if seen[n.Key] {
+			return nil, wrap("xliff", nodePath(i).String(), fmt.Errorf("duplicate _key %q", n.Key))
+		}
+		seen[n.Key] = true
+
+		src := xliffSource(n)
+		fmt.Fprintf(&b, "    <unit id=\"%s\">\n", xmlAttr(n.Key))
+		b.WriteString("      <segment>\n")
+		fmt.Fprintf(&b, "        <source xml:space=\"preserve\">%s</source>\n", src)
+		b.WriteString("      </segment>\n")
+		b.WriteString("    </unit>\n")
+	}
+	b.WriteString("  </file>\n</xliff>\n")
+	return b.Bytes(), nil
+}
+
+// xliffSource returns the inline XLIFF content for a block: marks as
+// <pc> and inline objects as <ph>, with id k+1 for code k.
+func xliffSource(n *Node) string {
+	annotations := map[string]string{}
+	for _, md := range n.MarkDefs {
+		annotations[md.Key] = md.Type
+	}
+
+	parts, codes := inlineParts(n)
+	var sb strings.Builder
+	for _, p := range parts {
+		switch p.kind {
+		case partText:
+			xml.EscapeText(&sb, []byte(p.text))
+		case partClose:
+			sb.WriteString("</pc>")
+		case partPlaceholder:
+			typ := "other"
+			if n.Children[codes[p.code].child].Type == "image" {
+				typ = "image"
+			}
+			fmt.Fprintf(&sb, `<ph id="%d" type="%s"/>`, p.code+1, typ)
+		case partOpen:
+			m := codes[p.code].mark
+			fmt.Fprintf(&sb, `<pc id="%d"`, p.code+1)
+			if t, ok := annotations[m]; ok {
+				if t == "link" {
+					sb.WriteString(` type="link"`)
+				} else {
+					sb.WriteString(` type="other"`)
+				}
+			} else if sub, ok := xliffDecorators[m]; ok {
+				fmt.Fprintf(&sb, ` type="fmt" subType="%s"`, sub)
+			} else {
+				sb.WriteString(` type="fmt"`)
+			}
+			sb.WriteString(">")
+		}
+	}
+	return sb.String()
+}
+
+func xmlAttr(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// xliffUnit is the translated content of one unit.
+type xliffUnit struct {
+	id     string
+	target []xml.Token
+	done   bool // at least one segment has a target
+}
+
+// ImportXLIFF applies the translations in an XLIFF 2.0 file produced by
+// ExportXLIFF to a copy of doc, the document that was exported.
+//
+// Each unit's target replaces the children of the block with the same
+// _key. Text inside <pc> elements gets the corresponding marks, so the
+// block's MarkDefs are reused as they are, and each <ph> is replaced by a
+// copy of the original inline object. Span keys are reused in order.
+// Units without a target are left untranslated; a segment without a
+// target keeps its source text.
+//
+// Placeholders lost, duplicated or invented by translators are reported
+// together as *Error values wrapping ErrPlaceholderMismatch, with the path
+// of the affected span or block; no document is returned then.
+func ImportXLIFF(doc Document, r io.Reader) (Document, error) {
+	units, err := parseXLIFF(r)
+	if err != nil {
+		return nil, wrap("xliff", "", err)
+	}
+
+	index := map[string]int{}
+	for i := range doc {
+		if doc[i].IsBlock() && doc[i].Key != "" {
+			index[doc[i].Key] = i
+		}
+	}
+
+	out := make(Document, len(doc))
+	for i := range doc {
+		out[i] = *doc[i].Clone()
+	}
+	var errs []error
+	for _, u := range units {
+		i, ok := index[u.id]
+		if !ok {
+			errs = append(errs, wrap("xliff", "", fmt.Errorf("unit %q: no block with that _key", u.id)))
+			continue
+		}
+		if !u.done {
+			continue
+		}
+		children, uerrs := xliffChildren(&doc[i], i, u.target)
+		errs = append(errs, uerrs...)
+		out[i].Children = children
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return out, nil
+}
+
+// parseXLIFF reads the units of an XLIFF 2.0 document.
+func parseXLIFF(r io.Reader) ([]xliffUnit, error) {
+	dec := xml.NewDecoder(r)
+	var units []xliffUnit
+	var unit *xliffUnit
+	var source, target []xml.Token // of the current segment or ignorable
+	var hasTarget bool
+	var collect *[]xml.Token // where inline content goes
+	depth := 0               // element depth inside source or target
+	root := true
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if collect != nil {
+			switch tok.(type) {
+			case xml.StartElement:
+				depth++
+			case xml.EndElement:
+				if depth == 0 {
+					collect = nil
+					continue
+				}
+				depth--
+			case xml.CharData:
+			default:
+				continue // comments and processing instructions
+			}
+			*collect = append(*collect, xml.CopyToken(tok))
+			continue
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if root {
+				if t.Name.Local != "xliff" {
+					return nil, fmt.Errorf("not an XLIFF document: <%s>", t.Name.Local)
+				}
+				if v := xmlAttrValue(t, "version"); !strings.HasPrefix(v, "2.") {
+					return nil, fmt.Errorf("unsupported XLIFF version %q", v)
+				}
+				root = false
+				continue
+			}
+			switch t.Name.Local {
+			case "unit":
+				unit = &xliffUnit{id: xmlAttrValue(t, "id")}
+			case "segment", "ignorable":
+				source, target, hasTarget = nil, nil, false
+			case "source":
+				if unit != nil {
+					collect, depth = &source, 0
+				}
+			case "target":
+				if unit != nil {
+					collect, depth, hasTarget = &target, 0, true
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "unit":
+				if unit != nil {
+					units = append(units, *unit)
+				}
+				unit = nil
+			case "segment", "ignorable":
+				if unit == nil {
+					continue
+				}
+				if hasTarget {
+					unit.target = append(unit.target, target...)
+					unit.done = unit.done || t.Name.Local == "segment"
+				} else {
+					unit.target = append(unit.target, source...)
+				}
+			}
+		}
+	}
+	if root {
+		return nil, errors.New("not an XLIFF document")
+	}
+	return units, nil
+}
+
+func xmlAttrValue(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// xliffChildren rebuilds the children of doc[i], the block n, from the
+// inline content of a target.
+func xliffChildren(n *Node, i int, toks []xml.Token) ([]Span, []error) {
+	b := newSpanBuilder("xliff", n, i, func(k int, ph bool) string {
+		if ph {
+			return fmt.Sprintf(`<ph id="%d">`, k+1)
+		}
+		return fmt.Sprintf(`<pc id="%d">`, k+1)
+	})
+	for _, tok := range toks {
+		switch t := tok.(type) {
+		case xml.CharData:
+			b.write(string(t))
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "pc", "ph":
+				id := xmlAttrValue(t, "id")
+				k, err := strconv.Atoi(id)
+				switch {
+				case err != nil:
+					b.mismatch(nodePath(i), "unknown <%s id=%q>", t.Name.Local, id)
+					b.open(-1)
+				case t.Name.Local == "pc":
+					b.open(k - 1)
+				default:
+					b.placeholder(k - 1)
+					b.open(-1)
+				}
+			case "cp":
+				if v, err := strconv.ParseUint(xmlAttrValue(t, "hex"), 16, 32); err == nil {
+					b.write(string(rune(v)))
+				}
+				b.open(-1)
+			case "mrk", "sm", "em":
+				// Annotations added by translation tools; keep the content.
+				b.open(-1)
+			default:
+				b.mismatch(nodePath(i), "unsupported inline element <%s>", t.Name.Local)
+				b.open(-1)
+			}
+		case xml.EndElement:
+			b.close()
+		}
+	}
+	return b.finish()
+}
@@ -0,0 +1,187 @@
+package portabletext
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func xliffTestDoc() Document {
+	b := NewBlock("normal").
+		AddMarkDef("l1", "link", map[string]any{"href": "https://example.com"}).
+		AddSpan("Read ").
+		AddSpan("the docs", "strong", "l1").
+		AddSpan(" & more")
+	b.Key = "b1"
+	b.Children = append(b.Children, Span{Type: "image", Raw: map[string]any{"_key": "img", "alt": "icon"}})
+	for j := range b.Children {
+		if b.Children[j].Type == "span" {
+			b.Children[j].Raw["_key"] = "s" + string(rune('1'+j))
+		}
+	}
+
+	h := NewBlock("h1").AddSpan("Title")
+	h.Key = "h1"
+	img := NewNode("image")
+	img.Key = "i1"
+	return Document{*h, *img, *b}
+}
+
+// translate adds a target after each unit's source, by unit id.
+func translate(x string, targets map[string]string) string {
+	for id, t := range targets {
+		unit := `<unit id="` + id + `">`
+		i := strings.Index(x, unit)
+		j := i + strings.Index(x[i:], "</source>") + len("</source>")
+		x = x[:j] + "<target>" + t + "</target>" + x[j:]
+	}
+	return x
+}
+
+func TestExportXLIFF(t *testing.T) {
+	out, err := ExportXLIFF(xliffTestDoc(), "en", "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := string(out)
+	for _, want := range []string{
+		`<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="2.0" srcLang="en" trgLang="de">`,
+		`<unit id="h1">`,
+		`<source xml:space="preserve">Title</source>`,
+		`<unit id="b1">`,
+		`<source xml:space="preserve">Read <pc id="1" type="fmt" subType="xlf:b"><pc id="2" type="link">the docs</pc></pc> &amp; more<ph id="3" type="image"/></source>`,
+	} {
+		if !strings.Contains(x, want) {
+			t.Errorf("ExportXLIFF() missing %s\n%s", want, x)
+		}
+	}
+	if strings.Contains(x, `"i1"`) {
+		t.Error("custom node exported")
+	}
+}
+
+func TestExportXLIFFMissingKey(t *testing.T) {
+	_, err := ExportXLIFF(Document{*NewBlock("normal").AddSpan("x")}, "en", "")
+	var pe *Error
+	if !errors.Is(err, ErrMissingKey) || !errors.As(err, &pe) || pe.Path != "[0]" {
+		t.Errorf("ExportXLIFF() error = %v, want ErrMissingKey at [0]", err)
+	}
+}
+
+func TestXLIFFOverlappingMarks(t *testing.T) {
+	b := NewBlock("normal").AddSpan("a", "strong").AddSpan("b", "strong", "em").AddSpan("c", "em")
+	b.Key = "k"
+	src := xliffSource(b)
+	want := `<pc id="1" type="fmt" subType="xlf:b">a<pc id="2" type="fmt" subType="xlf:i">b</pc></pc><pc id="3" type="fmt" subType="xlf:i">c</pc>`
+	if src != want {
+		t.Fatalf("xliffSource() = %s, want %s", src, want)
+	}
+
+	x, _ := ExportXLIFF(Document{*b}, "en", "fr")
+	target := `<pc id="1">A<pc id="2">B</pc></pc><pc id="3">C</pc>`
+	got, err := ImportXLIFF(Document{*b}, strings.NewReader(translate(string(x), map[string]string{"k": target})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var marks []string
+	for _, s := range got[0].Children {
+		marks = append(marks, *s.Text+":"+strings.Join(s.Marks, "+"))
+	}
+	if !slices.Equal(marks, []string{"A:strong", "B:strong+em", "C:em"}) {
+		t.Errorf("imported spans = %v", marks)
+	}
+}
+
+func TestImportXLIFF(t *testing.T) {
+	doc := xliffTestDoc()
+	x, err := ExportXLIFF(doc, "en", "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	translated := translate(string(x), map[string]string{
+		"b1": `<ph id="3"/>Lies <pc id="1"><pc id="2">die <mrk id="m1" type="term">Doku</mrk></pc></pc> &amp; mehr`,
+	})
+
+	got, err := ImportXLIFF(doc, strings.NewReader(translated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].GetText() != "Title" {
+		t.Errorf("untranslated unit changed: %q", got[0].GetText())
+	}
+	b := got[2]
+	if b.GetText() != "Lies die Doku & mehr" {
+		t.Errorf("GetText() = %q", b.GetText())
+	}
+	if len(b.Children) != 4 || b.Children[0].Type != "image" || b.Children[0].Raw["alt"] != "icon" {
+		t.Fatalf("children = %+v", b.Children)
+	}
+	if s := b.Children[2]; !slices.Equal(s.Marks, []string{"strong", "l1"}) || s.Raw["_key"] != "s2" {
+		t.Errorf("marked span = %+v", s)
+	}
+	if s := b.Children[1]; len(s.Marks) != 0 || s.Raw["_key"] != "s1" {
+		t.Errorf("plain span = %+v", s)
+	}
+	if len(b.MarkDefs) != 1 || b.MarkDefs[0].Key != "l1" {
+		t.Errorf("markDefs = %+v", b.MarkDefs)
+	}
+	if doc[2].GetText() != "Read the docs & more" {
+		t.Error("ImportXLIFF modified its input")
+	}
+}
+
+func TestImportXLIFFErrors(t *testing.T) {
+	doc := xliffTestDoc()
+	x, _ := ExportXLIFF(doc, "en", "de")
+
+	tests := []struct {
+		name   string
+		xliff  string
+		paths  []string
+		substr string
+	}{
+		{"lost pc", translate(string(x), map[string]string{"b1": `Lies <pc id="1">die Doku</pc><ph id="3"/>`}),
+			[]string{"[2].children[1]"}, `<pc id="2"> for mark "l1" is missing`},
+		{"lost ph", translate(string(x), map[string]string{"b1": `<pc id="1"><pc id="2">x</pc></pc>`}),
+			[]string{"[2].children[3]"}, `<ph id="3"> for the image object is missing`},
+		{"unknown", translate(string(x), map[string]string{"b1": `<pc id="1"><pc id="2">x</pc></pc><ph id="3"/><ph id="9"/>`}),
+			[]string{"[2]"}, `unknown <ph id="9">`},
+		{"duplicate", translate(string(x), map[string]string{"b1": `<pc id="1"><pc id="2">x</pc></pc><ph id="3"/><ph id="3"/>`}),
+			[]string{"[2]"}, `appears more than once`},
+		{"several", translate(string(x), map[string]string{"b1": `x`}),
+			[]string{"[2].children[1]", "[2].children[1]", "[2].children[3]"}, "missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ImportXLIFF(doc, strings.NewReader(tt.xliff))
+			if got != nil || !errors.Is(err, ErrPlaceholderMismatch) || !strings.Contains(err.Error(), tt.substr) {
+				t.Fatalf("ImportXLIFF() error = %v, want %q", err, tt.substr)
+			}
+			var paths []string
+			for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+				var pe *Error
+				if errors.As(e, &pe) {
+					paths = append(paths, pe.Path)
+				}
+			}
+			if !slices.Equal(paths, tt.paths) {
+				t.Errorf("paths = %v, want %v", paths, tt.paths)
+			}
+		})
+	}
+}
+
+func TestImportXLIFFInvalid(t *testing.T) {
+	doc := xliffTestDoc()
+	for _, in := range []string{
+		`<html/>`,
+		`<xliff version="1.2"/>`,
+		`<xliff version="2.0"><file><unit id="nope"><segment><source>x</source><target>y</target></segment></unit></file></xliff>`,
+		`<xliff version="2.0"><file><unit`,
+	} {
+		if _, err := ImportXLIFF(doc, strings.NewReader(in)); err == nil {
+			t.Errorf("ImportXLIFF(%q) succeeded", in)
+		}
+	}
+}